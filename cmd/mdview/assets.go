@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/kyaoi/mdview/internal/assets"
+)
+
+// runAssets handles "mdview assets list" and "mdview assets copy [-f]
+// [<name>...]", which show the bundled files and copy them next to the
+// settings file for customisation.
+func runAssets(args []string) error {
+	usage := fmt.Errorf("使い方: mdview assets list | copy [-f] [<名前>...]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "list":
+		list, err := assets.List()
+		if err != nil {
+			return err
+		}
+		for _, asset := range list {
+			mark := ""
+			if asset.Customized {
+				mark = " (カスタマイズ済み)"
+			}
+			fmt.Println(asset.Name + mark)
+		}
+		if dir, err := assets.UserDir(); err == nil {
+			fmt.Printf("\nカスタマイズ用のディレクトリ: %s\n", dir)
+		}
+		return nil
+	case "copy":
+		flags := flag.NewFlagSet("assets copy", flag.ContinueOnError)
+		force := flags.Bool("f", false, "既存のファイルを上書きします")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		written, err := assets.Copy(flags.Args(), *force)
+		for _, path := range written {
+			fmt.Println(path)
+		}
+		return err
+	}
+	return usage
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kyaoi/mdview/internal/audit"
+	"github.com/kyaoi/mdview/internal/check"
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// runAudit handles "mdview audit [options] <path>...". Like check it never
+// opens the viewer and returns one of the check.Exit* codes.
+func runAudit(args []string, opts ui.Options) int {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	format := flags.String("format", "text", "出力形式 (text: ファイル:行: 規則: 内容, json: 配列)")
+	quiet := flags.Bool("q", false, "何も出力せず終了コードだけで結果を返します")
+	width := flags.Int("width", 80, "この幅でレンダリングし、はみ出す行を報告します (0 で検査しない)")
+	slow := flags.Duration("slow", 500*time.Millisecond, "これより時間のかかったレンダリングを報告します")
+	maxSize := flags.Int64("max-size", 1<<20, "これより大きいファイル (バイト) を報告します (0 で検査しない)")
+	top := flags.Int("top", 5, "時間のかかったファイルを上位いくつまで表示するか (text 形式のみ)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mdview audit [options] <file-or-directory>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return check.ExitError
+	}
+	if flags.NArg() == 0 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return check.ExitError
+	}
+
+	var files []string
+	for _, arg := range flags.Args() {
+		found, err := collectMarkdownFiles(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return check.ExitError
+		}
+		files = append(files, found...)
+	}
+
+	timeout := opts.RenderTimeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	report, err := audit.Files(files, audit.Options{
+		Width:   *width,
+		Slow:    *slow,
+		Timeout: timeout,
+		MaxSize: *maxSize,
+		View:    opts,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return check.ExitError
+	}
+	if !*quiet {
+		if err := writeProblems(os.Stdout, *format, report.Problems); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return check.ExitError
+		}
+		if *format == "text" {
+			writeAuditSummary(report, *top)
+		}
+	}
+	if len(report.Problems) > 0 {
+		return check.ExitProblems
+	}
+	return check.ExitOK
+}
+
+// writeAuditSummary prints the render times to stderr so that stdout stays
+// limited to the problems.
+func writeAuditSummary(report audit.Report, top int) {
+	fmt.Fprintf(os.Stderr, "%d ファイルを %s でレンダリングしました (問題 %d 件)\n",
+		len(report.Timings), report.Total.Round(time.Millisecond), len(report.Problems))
+	for _, t := range report.Timings[:min(top, len(report.Timings))] {
+		fmt.Fprintf(os.Stderr, "  %8s  %s\n", t.Duration.Round(time.Millisecond), t.File)
+	}
+}
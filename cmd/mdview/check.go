@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kyaoi/mdview/internal/check"
+	"github.com/kyaoi/mdview/internal/ignore"
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/tree"
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// runCheck handles "mdview check [options] <path>...". It never opens the
+// viewer and returns one of the check.Exit* codes so pipelines can rely on it.
+func runCheck(args []string, opts ui.Options) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	format := flags.String("format", "text", "出力形式 (text: ファイル:行: 規則: 内容, json: 配列)")
+	quiet := flags.Bool("q", false, "何も出力せず終了コードだけで結果を返します")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mdview check [options] <file-or-directory>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return check.ExitError
+	}
+	if flags.NArg() == 0 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return check.ExitError
+	}
+
+	var files []string
+	for _, arg := range flags.Args() {
+		found, err := collectMarkdownFiles(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return check.ExitError
+		}
+		files = append(files, found...)
+	}
+
+	checker := check.New(check.Options{Slug: opts.Slug, AssetsBase: opts.AssetsBase})
+	problems, err := checker.Files(files)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return check.ExitError
+	}
+	if !*quiet {
+		if err := writeProblems(os.Stdout, *format, problems); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return check.ExitError
+		}
+	}
+	if len(problems) > 0 {
+		return check.ExitProblems
+	}
+	return check.ExitOK
+}
+
+func writeProblems(w io.Writer, format string, problems []check.Problem) error {
+	if format == "json" {
+		if problems == nil {
+			problems = []check.Problem{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(problems)
+	}
+	for _, p := range problems {
+		if _, err := fmt.Fprintf(w, "%s:%d: %s: %s\n", p.File, p.Line, p.Rule, p.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectMarkdownFiles expands a path into the markdown files below it,
+// skipping the same entries as the tree view.
+func collectMarkdownFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+	var files []string
+	matcher := ignore.New(root)
+	err = tree.Walk(root, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			if path != root && tree.Skip(matcher, path, d) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if markdown.IsFile(d.Name()) && !tree.Skip(matcher, path, d) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/kyaoi/mdview/internal/app"
+	"github.com/kyaoi/mdview/internal/daemon"
+	"github.com/kyaoi/mdview/internal/safemode"
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// daemonStartTimeout bounds how long "daemon start" waits for the first scan
+// of the vaults to finish.
+const daemonStartTimeout = 5 * time.Minute
+
+// runDaemon handles "mdview daemon serve|start <dir>...", "mdview daemon stop"
+// and "mdview daemon status".
+func runDaemon(args []string) error {
+	usage := fmt.Errorf("使い方: mdview daemon serve|start <ディレクトリ>... | stop | status")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "serve":
+		return daemon.Serve(args[1:])
+	case "start":
+		if len(args) < 2 {
+			return usage
+		}
+		return startDaemon(args[1:])
+	case "stop":
+		return daemon.Stop()
+	case "status":
+		roots, err := daemon.Status()
+		if err != nil {
+			return err
+		}
+		for _, root := range roots {
+			fmt.Println(root)
+		}
+		return nil
+	}
+	return usage
+}
+
+// startDaemon runs "mdview daemon serve" detached from the terminal and waits
+// until it answers.
+func startDaemon(dirs []string) error {
+	if _, err := daemon.Status(); err == nil {
+		return errors.New("デーモンは既に起動しています")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	roots := make([]string, len(dirs))
+	for i, dir := range dirs {
+		if roots[i], err = filepath.Abs(dir); err != nil {
+			return err
+		}
+	}
+	// The daemon scans with the same flags, e.g. --no-ignore, as this
+	// invocation.
+	flags := os.Args[1 : len(os.Args)-flag.NArg()]
+	args := append(slices.Clone(flags), "daemon", "serve")
+	cmd, err := safemode.Command(self, append(args, roots...)...)
+	if err != nil {
+		return err
+	}
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	deadline := time.After(daemonStartTimeout)
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("終了しました")
+			}
+			return fmt.Errorf("デーモンを起動できませんでした: %w", err)
+		case <-deadline:
+			return errors.New("デーモンの起動がタイムアウトしました")
+		case <-time.After(100 * time.Millisecond):
+		}
+		if _, err := daemon.Status(); err == nil {
+			fmt.Printf("デーモンを起動しました (pid %d)\n", cmd.Process.Pid)
+			return nil
+		}
+	}
+}
+
+// runOpen handles "mdview open <path>". It attaches to the daemon's index of
+// the vault containing path and falls back to a normal start when no daemon
+// manages it.
+func runOpen(args []string, opts ui.Options) error {
+	if len(args) != 1 {
+		return fmt.Errorf("使い方: mdview open <ファイルまたはディレクトリ>")
+	}
+	target, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	root, entries, err := daemon.Index(target)
+	if errors.Is(err, daemon.ErrNotRunning) || errors.Is(err, daemon.ErrNotIndexed) {
+		return app.Run([]string{target}, opts)
+	}
+	if err != nil {
+		return err
+	}
+	return app.RunIndexed(root, entries, target, opts)
+}
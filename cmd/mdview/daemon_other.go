@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// detach leaves cmd as it is where there are no sessions to leave; the
+// daemon then ends with the console it was started from.
+func detach(cmd *exec.Cmd) {}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/check"
+	"github.com/kyaoi/mdview/internal/linkgraph"
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+// graphNode and graphEdge are the JSON form of the note graph.
+type graphNode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Count is the number of lines in From linking to To.
+	Count int `json:"count"`
+}
+
+// runGraph handles "mdview graph [options] <dir>", printing the links
+// between the documents below dir as Graphviz DOT or JSON. Like check it
+// returns one of the check.Exit* codes.
+func runGraph(args []string) int {
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	format := flags.String("format", "dot", "出力形式 (dot: Graphviz, json: nodes / edges)")
+	orphans := flags.Bool("orphans", true, "リンクのない文書もノードとして出力します")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mdview graph [options] <directory>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return check.ExitError
+	}
+	if flags.NArg() != 1 || (*format != "dot" && *format != "json") {
+		flags.Usage()
+		return check.ExitError
+	}
+	root := flags.Arg(0)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		if err == nil {
+			err = fmt.Errorf("%s はディレクトリではありません", root)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return check.ExitError
+	}
+	files, err := tree.MarkdownFiles(root)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return check.ExitError
+	}
+	nodes, edges := noteGraph(linkgraph.New(root, files), *orphans)
+	write := writeGraphJSON
+	if *format == "dot" {
+		write = writeGraphDOT
+	}
+	if err := write(os.Stdout, nodes, edges); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return check.ExitError
+	}
+	return check.ExitOK
+}
+
+// noteGraph folds the links of g into one edge per pair of documents. With
+// orphans false, documents without links either way are left out.
+func noteGraph(g *linkgraph.Graph, orphans bool) ([]graphNode, []graphEdge) {
+	edges := []graphEdge{}
+	index := make(map[[2]string]int)
+	linked := make(map[string]bool)
+	for _, e := range g.Edges() {
+		key := [2]string{e.From, e.To}
+		if i, ok := index[key]; ok {
+			edges[i].Count++
+			continue
+		}
+		index[key] = len(edges)
+		edges = append(edges, graphEdge{From: e.From, To: e.To, Count: 1})
+		linked[e.From], linked[e.To] = true, true
+	}
+	nodes := []graphNode{}
+	for _, rel := range g.Files() {
+		if !orphans && !linked[rel] {
+			continue
+		}
+		base := path.Base(rel)
+		nodes = append(nodes, graphNode{ID: rel, Label: strings.TrimSuffix(base, path.Ext(base))})
+	}
+	return nodes, edges
+}
+
+func writeGraphJSON(w io.Writer, nodes []graphNode, edges []graphEdge) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Nodes []graphNode `json:"nodes"`
+		Edges []graphEdge `json:"edges"`
+	}{nodes, edges})
+}
+
+// writeGraphDOT writes a digraph whose node ids are the document paths.
+// Pairs linked more than once get a heavier edge.
+func writeGraphDOT(w io.Writer, nodes []graphNode, edges []graphEdge) error {
+	var b strings.Builder
+	b.WriteString("digraph notes {\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "  %s [label=%s];\n", strconv.Quote(n.ID), strconv.Quote(n.Label))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s -> %s", strconv.Quote(e.From), strconv.Quote(e.To))
+		if e.Count > 1 {
+			fmt.Fprintf(&b, " [weight=%d, penwidth=%d]", e.Count, min(e.Count, 5))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/app"
+	"github.com/kyaoi/mdview/internal/importer"
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// runImport handles "mdview import <export> [<output>]": it converts a Notion
+// or HTML export into a markdown tree and opens the result.
+func runImport(args []string, opts ui.Options) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("使い方: mdview import <エクスポートのディレクトリまたは .zip> [出力先]")
+	}
+	src := filepath.Clean(args[0])
+	dst := strings.TrimSuffix(src, filepath.Ext(src)) + "-md"
+	if len(args) == 2 {
+		dst = filepath.Clean(args[1])
+	}
+	res, err := importer.Import(src, dst)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d ページを Markdown に変換し、%d ファイルをコピーしました: %s\n", res.Pages, res.Copied, dst)
+	return app.Run([]string{dst}, opts)
+}
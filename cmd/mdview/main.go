@@ -13,16 +13,95 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/adrg/frontmatter"
 	"github.com/kyaoi/mdview/internal/app"
+	"github.com/kyaoi/mdview/internal/assets"
+	"github.com/kyaoi/mdview/internal/config"
+	"github.com/kyaoi/mdview/internal/ignore"
+	"github.com/kyaoi/mdview/internal/indexcache"
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/remote"
+	"github.com/kyaoi/mdview/internal/safemode"
+	"github.com/kyaoi/mdview/internal/tree"
+	"github.com/kyaoi/mdview/internal/ui"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	var tagMode bool
+	var readOnly bool
+	var noIgnore bool
+	var skipDirs string
+	var hidden bool
+	var followSymlinks bool
+	var maxDepth int
+	var largeFileMB int
+	var extensions string
+	var allFiles bool
+	var textFiles string
+	var opts ui.Options
+	var script app.Script
+	var scriptSize string
+	var slug string
+	var treePosition string
+	var icons string
+	var treeSort string
+	var wrap string
+	var theme string
+	var debugFile string
 	flag.BoolVar(&tagMode, "t", false, "フロントマターの tags を表示して選択します")
+	flag.BoolVar(&opts.Typography, "typography", cfg.Typography, "引用符・ダッシュ・三点リーダーを約物に置き換えて表示します")
+	flag.StringVar(&opts.AssetsBase, "assets-base", cfg.AssetsBase, "相対パスの画像・リンクを解決する基準ディレクトリ (例: Hugo の static/)")
+	flag.BoolVar(&readOnly, "readonly", false, "ディスクへの書き込みと外部コマンドの実行を一切行いません")
+	flag.IntVar(&opts.ZenWidth, "zen-width", orDefault(cfg.ZenWidth, 80), "集中 (Zen) モードで本文を表示する最大幅")
+	flag.BoolVar(&opts.SourceLines, "source-lines", cfg.SourceLines, ":N / NG の行番号を Markdown ソースの行として解釈します")
+	flag.BoolVar(&opts.LineNumbers, "line-numbers", cfg.LineNumbers, "本文の各行に対応する Markdown ソースの行番号を表示します")
+	flag.StringVar(&opts.Rev, "rev", "", "ファイルを作業ツリーではなく指定した git のリビジョン (HEAD~3 など) の内容で表示します")
+	flag.IntVar(&opts.Line, "line", 0, "ファイルを開いたときに表示する Markdown ソースの行番号 (file.md:120 の形でも指定できます)")
+	flag.BoolVar(&opts.FootnotePreview, "footnote-preview", cfg.FootnotePreview, "表示中の脚注参照の本文をステータスバーに表示します")
+	flag.DurationVar(&opts.ReloadDebounce, "reload-debounce", orDefault(time.Duration(cfg.ReloadDebounce), 100*time.Millisecond), "ファイルの変更を検知してから再読み込みするまで待つ時間 (この間の変更はまとめて 1 回で反映します)")
+	flag.BoolVar(&opts.ConfirmReload, "confirm-reload", cfg.ConfirmReload, "ファイルが変更されても自動で再読み込みせず、ステータスバーに表示して r を待ちます (:set autoreload で切り替え)")
+	flag.IntVar(&largeFileMB, "large-file-mb", orDefault(cfg.LargeFileMB, 5), "これより大きなファイル (MiB) を開くときは、そのまま・テキストとして・先頭だけのどれで表示するかを確認します (0 で確認しない)")
+	flag.DurationVar(&opts.RenderTimeout, "render-timeout", orDefault(time.Duration(cfg.RenderTimeout), 3*time.Second), "これを超えるレンダリングは打ち切り、Markdown ソースを表示します")
+	flag.StringVar(&slug, "slug", orDefault(cfg.Slug, "github"), "見出しアンカーの形式 (github, gitlab, hugo)")
+	flag.StringVar(&theme, "theme", orDefault(cfg.Theme, assets.DefaultTheme), "配色テーマの名前 (mdview assets list を参照) または glamour のスタイル JSON のパス")
+	flag.StringVar(&wrap, "wrap", orDefault(cfg.Wrap, "auto"), "本文の折り返し方式 (auto: 文書の言語で判定, cjk: 任意の文字間, latin: 空白のみ)")
+	flag.IntVar(&opts.TreeWidth, "tree-width", cfg.TreeWidth, "ツリーペインの幅 (0 で最長のエントリに合わせる)")
+	flag.StringVar(&treePosition, "tree-position", orDefault(cfg.TreePosition, "left"), "ツリーペインの位置 (left, right)")
+	flag.StringVar(&icons, "icons", orDefault(cfg.Icons, "ascii"), "ツリーの装飾 (none: 単色, ascii: 種類別の色, nerd: 色と Nerd Font アイコン)")
+	flag.BoolVar(&opts.TreeDetails, "tree-details", cfg.TreeDetails, "ツリーの各エントリに更新日時とサイズを右寄せで表示します")
+	flag.BoolVar(&noIgnore, "no-ignore", cfg.NoIgnore, ".gitignore・.mdviewignore に一致するファイルもツリーや検索の対象にします")
+	flag.BoolVar(&hidden, "hidden", cfg.Hidden, ".github/ のような . で始まるディレクトリやファイルもツリーやタグ検索の対象にします")
+	flag.BoolVar(&followSymlinks, "follow-symlinks", cfg.FollowSymlinks, "シンボリックリンク先のディレクトリもたどってツリーやタグ検索の対象にします (循環するリンクは除外)")
+	flag.IntVar(&maxDepth, "max-depth", cfg.MaxDepth, "ディレクトリを走査する深さの上限 (0 で無制限)。それより深い階層はツリーで展開したときに読み込みます")
+	flag.StringVar(&extensions, "extensions", strings.Join(cfg.Extensions, ","), "Markdown として扱う拡張子 (カンマ区切り。既定は .md,.markdown,.mdx。例: .md,.mkd,.mdown,.qmd)")
+	flag.BoolVar(&allFiles, "all-files", cfg.AllFiles, "Markdown 以外のファイルもツリーに表示し、シンタックスハイライト付きで表示します")
+	flag.StringVar(&textFiles, "text-files", strings.Join(cfg.TextFiles, ","), "ツリーに表示し、折り返したプレーンテキストとして表示するファイル (カンマ区切り。. で始まるものは拡張子、それ以外はファイル名。既定は .txt,.text,LICENSE,LICENCE,COPYING,NOTICE,AUTHORS,README,CHANGELOG)")
+	flag.StringVar(&skipDirs, "skip-dirs", strings.Join(cfg.SkipDirs, ","), "読み込まないディレクトリ名をカンマ区切りで追加します (.git, node_modules などは常に除外、glob 可)")
+	flag.StringVar(&treeSort, "tree-sort", orDefault(cfg.TreeSort, "name"), "ツリーの並び順 (name, mtime, size)")
+	flag.StringVar(&opts.ScratchFile, "scratch-file", orDefault(cfg.ScratchFile, defaultScratchFile()), "スクラッチ欄 (s キー) のメモを保存するファイル")
+	flag.StringVar(&debugFile, "debug", "", "レンダリング・ディレクトリの読み込み・キー処理にかかった時間とファイル監視のイベントを指定ファイルに追記します")
+	flag.StringVar(&opts.SearchHistory, "search-history", orDefault(cfg.SearchHistory, defaultSearchHistory()), "検索語の履歴を保存するファイル (空にするとセッション中だけ記憶します)")
+	flag.BoolVar(&opts.NoRipgrep, "no-ripgrep", cfg.NoRipgrep, "rg (ripgrep) があっても使わず、Ctrl+g の全文検索で自前でファイルを読みます")
+	flag.BoolVar(&opts.NoMouse, "no-mouse", false, "マウス操作を無効にし、端末でのテキスト選択を優先します")
+	flag.StringVar(&script.Path, "script", "", "起動後に指定ファイルのキー操作を順に再生します")
+	flag.DurationVar(&script.Delay, "script-delay", 150*time.Millisecond, "--script で再生するキーの間隔")
+	flag.BoolVar(&script.Headless, "headless", false, "--script を端末なしで再生し、最終画面を標準出力に書き出します")
+	flag.StringVar(&scriptSize, "size", "80x24", "--headless 時の画面サイズ (幅x高さ)")
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <path-to-markdown-or-directory>\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <path-to-markdown-or-directory> | <markdown-file>...\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] check [-q] [--format text|json] <path>...\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] import <export-dir-or-zip> [<output-dir>]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] audit [audit-options] <file-or-directory>...\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s assets list | copy [-f] [<name>...]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s daemon serve|start <directory>... | stop | status\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] open <path-to-markdown-or-directory>\n", filepath.Base(os.Args[0]))
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -32,41 +111,198 @@ func main() {
 		os.Exit(1)
 	}
 
-	target := filepath.Clean(flag.Arg(0))
+	if readOnly {
+		safemode.Enable()
+	}
+	if noIgnore {
+		ignore.Disable()
+	}
+	ignore.AddSkipDirs(strings.Split(skipDirs, ",")...)
+	ignore.SetShowHidden(hidden)
+	tree.SetFollowSymlinks(followSymlinks)
+	tree.SetMaxDepth(maxDepth)
+	opts.LargeFileSize = int64(largeFileMB) << 20
+	// An empty list keeps the defaults.
+	markdown.SetExtensions(strings.Split(extensions, ","))
+	tree.SetAllFiles(allFiles)
+	markdown.SetTextFiles(strings.Split(textFiles, ","))
+	if flag.Arg(0) == "assets" {
+		if err := runAssets(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "graph" {
+		os.Exit(runGraph(flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "daemon" {
+		if err := runDaemon(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if opts.Style, err = assets.Theme(theme); err != nil {
+		log.Fatal(err)
+	}
+	if opts.Slug, err = markdown.ParseSlugStyle(slug); err != nil {
+		log.Fatal(err)
+	}
+	if opts.Icons, err = ui.ParseIconStyle(icons); err != nil {
+		log.Fatal(err)
+	}
+	if opts.TreeSort, err = tree.ParseSortMode(treeSort); err != nil {
+		log.Fatal(err)
+	}
+	if opts.Wrap, err = ui.ParseWrapMode(wrap); err != nil {
+		log.Fatal(err)
+	}
+	switch treePosition {
+	case "left":
+	case "right":
+		opts.TreeRight = true
+	default:
+		log.Fatalf("--tree-position には left か right を指定してください: %s", treePosition)
+	}
+	if debugFile != "" {
+		f, err := safemode.OpenFile(debugFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		ui.SetDebugLog(f)
+	}
+
+	if opts.AssetsBase != "" {
+		base, err := resolveAssetsBase(opts.AssetsBase)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.AssetsBase = base
+	}
+	if flag.Arg(0) == "check" {
+		os.Exit(runCheck(flag.Args()[1:], opts))
+	}
+	if flag.Arg(0) == "audit" {
+		os.Exit(runAudit(flag.Args()[1:], opts))
+	}
+	if flag.Arg(0) == "import" {
+		if err := runImport(flag.Args()[1:], opts); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "open" {
+		if err := runOpen(flag.Args()[1:], opts); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	targets := make([]string, flag.NArg())
+	for i, arg := range flag.Args() {
+		targets[i] = arg
+		if !remote.IsRemote(arg) {
+			targets[i] = filepath.Clean(arg)
+		}
+	}
 	if tagMode {
-		if err := runTagSelection(target); err != nil {
+		if err := runTagSelection(targets, opts); err != nil {
 			log.Fatal(err)
 		}
 		return
 	}
 
-	if err := app.Run(target); err != nil {
+	if script.Path != "" {
+		width, height, err := parseSize(scriptSize)
+		if err != nil {
+			log.Fatal(err)
+		}
+		script.Width, script.Height = width, height
+		if err := app.RunScript(targets, opts, script); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := app.Run(targets, opts); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func runTagSelection(path string) error {
-	info, err := os.Stat(path)
+// orDefault returns value unless it is the zero value, in which case fallback
+// is used. It lets unset config entries keep the built-in flag defaults.
+func orDefault[T comparable](value, fallback T) T {
+	var zero T
+	if value == zero {
+		return fallback
+	}
+	return value
+}
+
+// defaultScratchFile keeps the scratch notes next to the settings file.
+func defaultScratchFile() string {
+	path, err := config.Path()
 	if err != nil {
-		return err
+		return ""
 	}
+	return filepath.Join(filepath.Dir(path), "scratch.md")
+}
 
-	var index tagIndex
-	if info.IsDir() {
-		index, err = buildDirectoryTagIndex(path)
-	} else {
-		index, err = buildFileTagIndex(path)
+// defaultSearchHistory keeps the search history in the user cache directory.
+func defaultSearchHistory() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mdview", "search_history")
+}
+
+func parseSize(value string) (int, int, error) {
+	w, h, ok := strings.Cut(strings.ToLower(value), "x")
+	if ok {
+		width, errW := strconv.Atoi(w)
+		height, errH := strconv.Atoi(h)
+		if errW == nil && errH == nil && width > 0 && height > 0 {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("--size は 幅x高さ の形式で指定してください: %s", value)
+}
+
+func resolveAssetsBase(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
 	}
+	info, err := os.Stat(abs)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("--assets-base にはディレクトリを指定してください: %s", dir)
 	}
+	return abs, nil
+}
+
+func runTagSelection(paths []string, opts ui.Options) error {
+	indexes := make([]tagIndex, 0, len(paths))
+	for _, path := range paths {
+		index, err := buildTagIndex(path)
+		if err != nil {
+			return err
+		}
+		indexes = append(indexes, index)
+	}
+	index := mergeTagIndexes(indexes)
 	if index.isEmpty() {
 		fmt.Println("指定されたパスからフロントマターの tags は見つかりませんでした。")
 		return nil
 	}
 
-	printTagMenu(index)
-	selection, confirmed, err := promptTagSelection(len(index.tags))
+	recent := loadRecentTags()
+	choices := printTagMenu(index, recent.forRoot(index.historyKey(), index))
+	selection, confirmed, err := promptTagSelection(len(choices))
 	if err != nil {
 		return err
 	}
@@ -75,8 +311,9 @@ func runTagSelection(path string) error {
 		return nil
 	}
 
-	tag := index.tags[selection]
-	return launchFilteredView(index, tag)
+	tag := choices[selection]
+	_ = recent.remember(index.historyKey(), tag)
+	return launchFilteredView(index, tag, opts)
 }
 
 func readFrontMatterTags(path string) ([]string, error) {
@@ -180,6 +417,19 @@ type tagIndex struct {
 	displayRoot string
 	tags        []string
 	filesByTag  map[string][]string
+	// roots lists the merged directories when several were given to -t.
+	roots []app.TagRoot
+	// absRoots are the directories the index was built from.
+	absRoots []string
+}
+
+// historyKey identifies the index in the recent tag history, so a merged set
+// of roots keeps its own history.
+func (ti tagIndex) historyKey() string {
+	if len(ti.absRoots) > 1 {
+		return strings.Join(ti.absRoots, string(os.PathListSeparator))
+	}
+	return ti.rootDir
 }
 
 func (ti *tagIndex) add(tag, file string) {
@@ -215,15 +465,27 @@ func (ti tagIndex) isEmpty() bool {
 	return len(ti.tags) == 0
 }
 
-func printTagMenu(index tagIndex) {
+// printTagMenu lists recently used tags first, followed by every detected tag,
+// and returns the choices in the order they were numbered.
+func printTagMenu(index tagIndex, recent []string) []string {
+	choices := make([]string, 0, len(recent)+len(index.tags))
+	if len(recent) > 0 {
+		fmt.Println("最近使ったタグ:")
+		for _, tag := range recent {
+			choices = append(choices, tag)
+			fmt.Printf("  %d) %s (%d件)\n", len(choices), tag, len(index.filesByTag[tag]))
+		}
+	}
 	fmt.Println("検出されたタグ:")
-	for i, tag := range index.tags {
-		fmt.Printf("  %d) %s (%d件)\n", i+1, tag, len(index.filesByTag[tag]))
+	for _, tag := range index.tags {
+		choices = append(choices, tag)
+		fmt.Printf("  %d) %s (%d件)\n", len(choices), tag, len(index.filesByTag[tag]))
 	}
 	fmt.Println("  0) キャンセル")
+	return choices
 }
 
-func launchFilteredView(index tagIndex, tag string) error {
+func launchFilteredView(index tagIndex, tag string, opts ui.Options) error {
 	files := index.filesByTag[tag]
 	if len(files) == 0 {
 		fmt.Printf("タグ \"%s\" に一致するファイルがありません。\n", tag)
@@ -240,7 +502,72 @@ func launchFilteredView(index tagIndex, tag string) error {
 		}
 	}
 	fmt.Printf("タグ \"%s\" を含む %d 件のファイルだけを表示します。\n", tag, len(files))
-	return app.RunTagFiltered(index.rootDir, displayRoot, files, tag)
+	return app.RunTagFiltered(index.rootDir, displayRoot, index.roots, files, tag, opts)
+}
+
+func buildTagIndex(path string) (tagIndex, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return tagIndex{}, err
+	}
+	if info.IsDir() {
+		return buildDirectoryTagIndex(path)
+	}
+	return buildFileTagIndex(path)
+}
+
+// mergeTagIndexes combines the indexes of several roots. Files are re-keyed
+// relative to the roots' common ancestor and grouped under a node per root,
+// labelled with the root's name.
+func mergeTagIndexes(indexes []tagIndex) tagIndex {
+	if len(indexes) == 1 {
+		index := indexes[0]
+		index.absRoots = []string{index.rootDir}
+		return index
+	}
+	var merged tagIndex
+	for _, index := range indexes {
+		if index.rootDir != "" {
+			merged.absRoots = append(merged.absRoots, index.rootDir)
+		}
+	}
+	merged.rootDir = app.CommonAncestor(merged.absRoots)
+	labels := make(map[string]int)
+	for _, index := range indexes {
+		labels[index.displayRoot]++
+	}
+	var names []string
+	for _, index := range indexes {
+		if index.rootDir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(merged.rootDir, index.rootDir)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		label := index.displayRoot
+		if labels[label] > 1 {
+			label = rel
+		}
+		names = append(names, label)
+		merged.roots = append(merged.roots, app.TagRoot{Label: label, Path: rel})
+		for tag, files := range index.filesByTag {
+			for _, file := range files {
+				merged.add(tag, joinSlash(rel, file))
+			}
+		}
+	}
+	merged.displayRoot = strings.Join(names, " + ")
+	merged.finalize()
+	return merged
+}
+
+func joinSlash(base, rel string) string {
+	if base == "" || base == "." {
+		return rel
+	}
+	return base + "/" + rel
 }
 
 func buildFileTagIndex(path string) (tagIndex, error) {
@@ -283,30 +610,30 @@ func buildDirectoryTagIndex(root string) (tagIndex, error) {
 	if index.displayRoot == "" {
 		index.displayRoot = absRoot
 	}
-	err = filepath.WalkDir(absRoot, func(path string, d fs.DirEntry, walkErr error) error {
+	matcher := ignore.New(absRoot)
+	// Only the files changed since the last run are read again.
+	cache := indexcache.Open(absRoot)
+	err = tree.Walk(absRoot, func(path string, d fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
 		if d.IsDir() {
-			if shouldSkipDir(d.Name()) && path != absRoot {
+			if path != absRoot && tree.Skip(matcher, path, d) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
-		if !isMarkdown(d.Name()) {
-			return nil
-		}
-		tags, err := readFrontMatterTags(path)
-		if err != nil {
-			return err
-		}
-		if len(tags) == 0 {
+		if !markdown.IsFile(d.Name()) || tree.Skip(matcher, path, d) {
 			return nil
 		}
 		relPath, err := filepath.Rel(absRoot, path)
 		if err != nil {
 			relPath = path
 		}
+		tags, err := cache.Tags(filepath.ToSlash(relPath), readFrontMatterTags)
+		if err != nil {
+			return err
+		}
 		for _, tag := range tags {
 			index.add(tag, filepath.ToSlash(relPath))
 		}
@@ -315,20 +642,7 @@ func buildDirectoryTagIndex(root string) (tagIndex, error) {
 	if err != nil {
 		return tagIndex{}, err
 	}
+	_ = cache.Save()
 	index.finalize()
 	return index, nil
 }
-
-func shouldSkipDir(name string) bool {
-	switch strings.ToLower(name) {
-	case ".git", "node_modules", ".hg", ".svn", ".idea", ".vscode":
-		return true
-	default:
-		return false
-	}
-}
-
-func isMarkdown(name string) bool {
-	lower := strings.ToLower(name)
-	return strings.HasSuffix(lower, ".md") || strings.HasSuffix(lower, ".mdx")
-}
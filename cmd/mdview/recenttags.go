@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// recentTagLimit is how many recently filtered tags are remembered per root.
+const recentTagLimit = 5
+
+// recentTags maps an absolute root directory to its most recently used tags,
+// newest first.
+type recentTags map[string][]string
+
+func recentTagsPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mdview", "recent_tags.json"), nil
+}
+
+func loadRecentTags() recentTags {
+	path, err := recentTagsPath()
+	if err != nil {
+		return recentTags{}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return recentTags{}
+	}
+	recent := recentTags{}
+	if err := json.Unmarshal(data, &recent); err != nil {
+		return recentTags{}
+	}
+	return recent
+}
+
+// forRoot returns the remembered tags for root that still exist in index.
+func (r recentTags) forRoot(root string, index tagIndex) []string {
+	var tags []string
+	for _, tag := range r[root] {
+		if len(index.filesByTag[tag]) > 0 {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// remember moves tag to the front of root's list and persists the result. The
+// history is a convenience, so failures (including read-only mode) are ignored
+// by callers.
+func (r recentTags) remember(root, tag string) error {
+	list := []string{tag}
+	for _, existing := range r[root] {
+		if existing != tag && len(list) < recentTagLimit {
+			list = append(list, existing)
+		}
+	}
+	r[root] = list
+
+	path, err := recentTagsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := safemode.MkdirAll(filepath.Dir(path), 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return safemode.WriteFile(path, data, 0o644)
+}
@@ -6,17 +6,27 @@ import (
 	"github.com/kyaoi/mdview/internal/ui"
 )
 
-// Run executes the Bubble Tea program for the markdown viewer.
-func Run(target string) error {
-	state, err := LoadInitialState(target)
+// Run executes the Bubble Tea program for the markdown viewer. Several
+// targets open a tree holding just those files.
+func Run(targets []string, opts ui.Options) error {
+	state, err := loadState(targets, opts.Rev)
 	if err != nil {
 		return err
 	}
+	state.Options = opts
 	return runProgram(state)
 }
 
 func runProgram(state ui.State) error {
-	program := tea.NewProgram(ui.NewModel(state), tea.WithAltScreen())
+	program := tea.NewProgram(ui.NewModel(state), programOptions(state.Options)...)
 	_, err := program.Run()
 	return err
 }
+
+func programOptions(opts ui.Options) []tea.ProgramOption {
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if !opts.NoMouse {
+		options = append(options, tea.WithMouseCellMotion())
+	}
+	return options
+}
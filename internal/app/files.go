@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// LoadFilesState prepares the UI state for several file arguments: a tree
+// holding just those files, below their common ancestor, with the first one
+// displayed. Like a single argument, each may carry a "#section" or ":line"
+// suffix, of which only the first file's is used.
+func LoadFilesState(targets []string) (ui.State, error) {
+	state, err := LoadInitialState(targets[0])
+	if err != nil {
+		return ui.State{}, err
+	}
+	if state.ActiveAbsPath == "" {
+		return ui.State{}, fmt.Errorf("複数のパスを指定する場合はファイルだけを指定してください: %s", targets[0])
+	}
+
+	files := make([]string, 0, len(targets))
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, target := range targets {
+		path, _ := splitLine(target)
+		path, _ = splitAnchor(path)
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return ui.State{}, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return ui.State{}, err
+		}
+		if info.IsDir() {
+			return ui.State{}, fmt.Errorf("複数のパスを指定する場合はファイルだけを指定してください: %s", target)
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		files = append(files, abs)
+		dirs = append(dirs, filepath.Dir(abs))
+	}
+
+	rootDir := CommonAncestor(dirs)
+	relPaths := make([]string, len(files))
+	for i, file := range files {
+		rel, err := filepath.Rel(rootDir, file)
+		if err != nil {
+			return ui.State{}, err
+		}
+		relPaths[i] = filepath.ToSlash(rel)
+	}
+	displayRoot := filepath.Base(rootDir)
+
+	state.TreeVisible = true
+	state.TreeRoot = buildFilteredTree(rootDir, displayRoot, nil, relPaths)
+	state.TreeSelectionPath = relPaths[0]
+	state.RootDir = rootDir
+	state.DisplayRoot = displayRoot
+	return state, nil
+}
+
+// CommonAncestor returns the deepest directory containing every dir.
+func CommonAncestor(dirs []string) string {
+	if len(dirs) == 0 {
+		return ""
+	}
+	ancestor := dirs[0]
+	for _, dir := range dirs[1:] {
+		for {
+			rel, err := filepath.Rel(ancestor, dir)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				break
+			}
+			parent := filepath.Dir(ancestor)
+			if parent == ancestor {
+				break
+			}
+			ancestor = parent
+		}
+	}
+	return ancestor
+}
+
+// loadState prepares the UI state for the path arguments, showing the first
+// file as of rev when one is given.
+func loadState(targets []string, rev string) (ui.State, error) {
+	var state ui.State
+	var err error
+	if len(targets) > 1 {
+		state, err = LoadFilesState(targets)
+	} else {
+		state, err = LoadInitialState(targets[0])
+	}
+	if err != nil || rev == "" {
+		return state, err
+	}
+	return state, showRevision(&state, rev)
+}
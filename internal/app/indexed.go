@@ -0,0 +1,52 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/tree"
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// RunIndexed launches the viewer on target, a path inside root, with the tree
+// built from entries the daemon already scanned instead of reading the disk.
+func RunIndexed(root string, entries []tree.Entry, target string, opts ui.Options) error {
+	rootName := filepath.Base(root)
+	state := ui.State{
+		HeaderPath:  rootName + "/",
+		TreeVisible: true,
+		TreeRoot:    tree.NewRoot(rootName, tree.NewIndexLoader(root, entries)),
+		RootDir:     root,
+		DisplayRoot: rootName,
+		FocusTree:   true,
+		Options:     opts,
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel != "." {
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		state.TreeSelectionPath = rel
+		if !info.IsDir() {
+			data, err := os.ReadFile(target)
+			if err != nil {
+				return err
+			}
+			content, err := markdown.Convert(target, data)
+			if err != nil {
+				return err
+			}
+			state.RawContent = content
+			state.HeaderPath = rootName + "/" + rel
+			state.ActiveAbsPath = target
+			state.FocusTree = false
+		}
+	}
+	return runProgram(state)
+}
@@ -2,15 +2,25 @@ package app
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/remote"
 	"github.com/kyaoi/mdview/internal/tree"
 	"github.com/kyaoi/mdview/internal/ui"
 )
 
 // LoadInitialState analyses the target path and prepares the UI state.
 func LoadInitialState(target string) (ui.State, error) {
+	if remote.IsRemote(target) {
+		return loadRemoteState(target)
+	}
+	target, line := splitLine(target)
+	target, anchor := splitAnchor(target)
 	info, err := os.Stat(target)
 	if err != nil {
 		return ui.State{}, err
@@ -66,6 +76,10 @@ func LoadInitialState(target string) (ui.State, error) {
 	if err != nil {
 		return ui.State{}, err
 	}
+	content, err := markdown.Convert(absTarget, data)
+	if err != nil {
+		return ui.State{}, err
+	}
 
 	displayPath := absTarget
 	if wd, err := os.Getwd(); err == nil {
@@ -75,8 +89,64 @@ func LoadInitialState(target string) (ui.State, error) {
 	}
 
 	return ui.State{
-		RawContent:    string(data),
+		RawContent:    content,
 		HeaderPath:    filepath.ToSlash(displayPath),
 		ActiveAbsPath: absTarget,
+		Anchor:        anchor,
+		Line:          line,
+	}, nil
+}
+
+// splitAnchor separates a "#section" suffix from a path argument, unless
+// the argument names an existing file as written.
+func splitAnchor(target string) (string, string) {
+	if _, err := os.Stat(target); err == nil {
+		return target, ""
+	}
+	i := strings.LastIndex(target, "#")
+	if i <= 0 {
+		return target, ""
+	}
+	anchor := target[i+1:]
+	if unescaped, err := url.PathUnescape(anchor); err == nil {
+		anchor = unescaped
+	}
+	return target[:i], anchor
+}
+
+// splitLine separates a ":120" line number suffix from a path argument,
+// unless the argument names an existing file as written.
+func splitLine(target string) (string, int) {
+	if _, err := os.Stat(target); err == nil {
+		return target, 0
+	}
+	i := strings.LastIndex(target, ":")
+	if i <= 0 {
+		return target, 0
+	}
+	line, err := strconv.Atoi(target[i+1:])
+	if err != nil || line <= 0 {
+		return target, 0
+	}
+	return target[:i], line
+}
+
+// loadRemoteState downloads the document target names. A "#section"
+// fragment names the heading to scroll to, as for files.
+func loadRemoteState(target string) (ui.State, error) {
+	target, anchor, _ := strings.Cut(target, "#")
+	doc, err := remote.Fetch(target)
+	if err != nil {
+		return ui.State{}, err
+	}
+	content, err := doc.Markdown()
+	if err != nil {
+		return ui.State{}, err
+	}
+	return ui.State{
+		RawContent: content,
+		HeaderPath: target,
+		Anchor:     anchor,
+		Remote:     &doc,
 	}, nil
 }
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/kyaoi/mdview/internal/git"
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// showRevision replaces the file shown by state with its contents as of the
+// git revision rev.
+func showRevision(state *ui.State, rev string) error {
+	if state.ActiveAbsPath == "" {
+		return fmt.Errorf("--rev にはファイルを指定してください")
+	}
+	short, err := git.ShortRev(state.ActiveAbsPath, rev)
+	if err != nil {
+		return err
+	}
+	data, err := git.Show(state.ActiveAbsPath, rev)
+	if err != nil {
+		return err
+	}
+	content, err := markdown.Convert(state.ActiveAbsPath, data)
+	if err != nil {
+		return err
+	}
+	state.RawContent = content
+	state.Rev = rev
+	state.RevLabel = rev
+	if short != rev {
+		state.RevLabel += " (" + short + ")"
+	}
+	return nil
+}
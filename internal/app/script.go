@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// Script describes a key replay requested with --script.
+type Script struct {
+	Path string
+	// Delay is the pause between keys when replaying into the live program.
+	Delay time.Duration
+	// Headless replays without a terminal and prints the final screen, which
+	// makes bug reports reproducible.
+	Headless bool
+	Width    int
+	Height   int
+}
+
+// RunScript opens targets like Run and replays the keys from script.Path.
+func RunScript(targets []string, opts ui.Options, script Script) error {
+	data, err := os.ReadFile(script.Path)
+	if err != nil {
+		return err
+	}
+	msgs, err := ui.ParseScript(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", script.Path, err)
+	}
+
+	if script.Headless {
+		opts.NoWatch = true
+		opts.Sync = true
+	}
+	state, err := loadState(targets, opts.Rev)
+	if err != nil {
+		return err
+	}
+	state.Options = opts
+	model := ui.NewModel(state)
+
+	if script.Headless {
+		model.Drive(tea.WindowSizeMsg{Width: script.Width, Height: script.Height})
+		model.Drive(msgs...)
+		fmt.Println(model.View())
+		return nil
+	}
+
+	program := tea.NewProgram(model, programOptions(opts)...)
+	go func() {
+		for _, msg := range msgs {
+			time.Sleep(script.Delay)
+			program.Send(msg)
+		}
+	}()
+	_, err = program.Run()
+	return err
+}
@@ -2,21 +2,32 @@ package app
 
 import (
 	"fmt"
-	"sort"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/kyaoi/mdview/internal/tree"
 	"github.com/kyaoi/mdview/internal/ui"
 )
 
+// TagRoot is one of several directories merged into a tag filter. Its files
+// are grouped under a node named Label.
+type TagRoot struct {
+	Label string
+	// Path is the directory relative to the filter's root, using forward
+	// slashes.
+	Path string
+}
+
 // RunTagFiltered launches the viewer with a tree composed only of the provided
 // relative paths. The paths must be expressed using forward slashes and be
-// relative to rootDir.
-func RunTagFiltered(rootDir, displayRoot string, relPaths []string, tag string) error {
+// relative to rootDir. When roots is non-empty each path is shown below the
+// root that contains it.
+func RunTagFiltered(rootDir, displayRoot string, roots []TagRoot, relPaths []string, tag string, opts ui.Options) error {
 	if len(relPaths) == 0 {
 		return fmt.Errorf("タグ %q に一致するファイルがありません", tag)
 	}
-	root := buildFilteredTree(displayRoot, relPaths)
+	root := buildFilteredTree(rootDir, displayRoot, roots, relPaths)
 	state := ui.State{
 		RawContent:        fmt.Sprintf("タグ \"%s\" を含むファイルを選択してください。", tag),
 		HeaderPath:        fmt.Sprintf("%s/ (tag: %s)", displayRoot, tag),
@@ -26,32 +37,49 @@ func RunTagFiltered(rootDir, displayRoot string, relPaths []string, tag string)
 		RootDir:           rootDir,
 		DisplayRoot:       displayRoot,
 		FocusTree:         true,
+		Options:           opts,
 	}
 	return runProgram(state)
 }
 
-func buildFilteredTree(displayRoot string, relPaths []string) *tree.Node {
+func buildFilteredTree(rootDir, displayRoot string, roots []TagRoot, relPaths []string) *tree.Node {
 	root := &tree.Node{
 		Name:  displayRoot,
 		Path:  "",
 		IsDir: true,
 		Open:  true,
 	}
+	var rootNodes []*tree.Node
+	for _, r := range roots {
+		node := &tree.Node{Name: r.Label, Path: r.Path, IsDir: true, Open: true, Parent: root}
+		statNode(rootDir, node)
+		root.Children = append(root.Children, node)
+		rootNodes = append(rootNodes, node)
+	}
 	for _, rel := range relPaths {
 		trimmed := strings.Trim(rel, "/")
 		if trimmed == "" {
 			continue
 		}
-		insertPath(root, trimmed)
+		parent := root
+		for _, node := range rootNodes {
+			if strings.HasPrefix(trimmed, node.Path+"/") {
+				parent = node
+				break
+			}
+		}
+		insertPath(rootDir, parent, trimmed)
 	}
-	sortTree(root)
+	root.Sort(tree.SortName)
 	return root
 }
 
-func insertPath(root *tree.Node, rel string) {
-	parts := strings.Split(rel, "/")
-	current := root
-	parentPath := ""
+// insertPath adds the file at rel, which is relative to the tree root, below
+// parent, creating the intermediate directories.
+func insertPath(rootDir string, parent *tree.Node, rel string) {
+	parentPath := parent.Path
+	parts := strings.Split(strings.TrimPrefix(rel, prefixOf(parentPath)), "/")
+	current := parent
 	for i, part := range parts {
 		isLast := i == len(parts)-1
 		childPath := joinPath(parentPath, part)
@@ -63,6 +91,7 @@ func insertPath(root *tree.Node, rel string) {
 				IsDir: !isLast,
 			}
 			child.Parent = current
+			statNode(rootDir, child)
 			current.Children = append(current.Children, child)
 		}
 		current = child
@@ -75,24 +104,18 @@ func insertPath(root *tree.Node, rel string) {
 	}
 }
 
-func sortTree(node *tree.Node) {
-	if node == nil || len(node.Children) == 0 {
-		return
+// statNode records the metadata of the file backing node, if it exists.
+func statNode(rootDir string, node *tree.Node) {
+	if info, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(node.Path))); err == nil {
+		node.SetInfo(info)
 	}
-	sort.Slice(node.Children, func(i, j int) bool {
-		ci, cj := node.Children[i], node.Children[j]
-		switch {
-		case ci.IsDir == cj.IsDir:
-			return strings.ToLower(ci.Name) < strings.ToLower(cj.Name)
-		case ci.IsDir:
-			return true
-		default:
-			return false
-		}
-	})
-	for _, child := range node.Children {
-		sortTree(child)
+}
+
+func prefixOf(dir string) string {
+	if dir == "" {
+		return ""
 	}
+	return dir + "/"
 }
 
 func joinPath(base, part string) string {
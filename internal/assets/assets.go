@@ -0,0 +1,138 @@
+// Package assets bundles the files mdview needs at run time, currently the
+// glamour themes, into the binary. Users customise an asset by copying it
+// next to the settings file, where it takes precedence over the built-in
+// copy.
+package assets
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/config"
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+//go:embed themes
+var files embed.FS
+
+// DefaultTheme is the theme used when none is configured.
+const DefaultTheme = "tokyo-night"
+
+// Asset is one bundled file.
+type Asset struct {
+	// Name identifies the asset, e.g. "themes/dark.json".
+	Name string
+	// Customized reports whether the user directory holds its own copy.
+	Customized bool
+}
+
+// UserDir returns the directory holding customised assets: the directory of
+// the settings file.
+func UserDir() (string, error) {
+	path, err := config.Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(path), nil
+}
+
+// List returns every bundled asset in name order.
+func List() ([]Asset, error) {
+	userDir, _ := UserDir()
+	var assets []Asset
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		asset := Asset{Name: name}
+		if userDir != "" {
+			if _, err := os.Stat(filepath.Join(userDir, filepath.FromSlash(name))); err == nil {
+				asset.Customized = true
+			}
+		}
+		assets = append(assets, asset)
+		return nil
+	})
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Name < assets[j].Name })
+	return assets, err
+}
+
+// Read returns the user's copy of the named asset if there is one and the
+// bundled copy otherwise.
+func Read(name string) ([]byte, error) {
+	if userDir, err := UserDir(); err == nil {
+		data, err := os.ReadFile(filepath.Join(userDir, filepath.FromSlash(name)))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	data, err := files.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("組み込みのファイルではありません: %s", name)
+	}
+	return data, err
+}
+
+// Theme returns the glamour style for name, which is either a path to a JSON
+// style file or the name of a theme under themes/.
+func Theme(name string) ([]byte, error) {
+	if name == "" {
+		name = DefaultTheme
+	}
+	if strings.HasSuffix(name, ".json") || strings.ContainsAny(name, "/"+string(filepath.Separator)) {
+		return os.ReadFile(name)
+	}
+	data, err := Read(path.Join("themes", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("不明なテーマ %q (mdview assets list で一覧を確認できます)", name)
+	}
+	return data, nil
+}
+
+// Copy writes the bundled copies of the named assets, or of every asset when
+// names is empty, into the user directory so they can be edited. Existing
+// files are left alone unless force is set. It returns the paths written.
+func Copy(names []string, force bool) ([]string, error) {
+	userDir, err := UserDir()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		all, err := List()
+		if err != nil {
+			return nil, err
+		}
+		for _, asset := range all {
+			names = append(names, asset.Name)
+		}
+	}
+	var written []string
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return written, fmt.Errorf("組み込みのファイルではありません: %s", name)
+		}
+		dst := filepath.Join(userDir, filepath.FromSlash(name))
+		if _, err := os.Stat(dst); err == nil && !force {
+			return written, fmt.Errorf("%s は既に存在します (上書きするには -f を指定してください)", dst)
+		}
+		if err := safemode.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return written, err
+		}
+		if err := safemode.WriteFile(dst, data, 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, dst)
+	}
+	return written, nil
+}
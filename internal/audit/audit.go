@@ -0,0 +1,176 @@
+// Package audit renders every document of a vault the way the viewer does and
+// reports the ones that would be painful to read: documents that fail to
+// render, render slowly, or produce lines wider than the screen.
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/check"
+	"github.com/kyaoi/mdview/internal/ui"
+)
+
+// Rule names used in check.Problem.Rule.
+const (
+	RuleRender  = "render"
+	RuleTimeout = "timeout"
+	RuleSlow    = "slow"
+	RuleWidth   = "width"
+	RuleSize    = "size"
+)
+
+// Options holds the thresholds a document is measured against.
+type Options struct {
+	// Width is the wrap width documents are rendered at.
+	Width int
+	// Slow flags renders taking longer than this.
+	Slow time.Duration
+	// Timeout abandons renders taking longer than this.
+	Timeout time.Duration
+	// MaxSize flags files larger than this many bytes; zero disables it.
+	MaxSize int64
+	// View carries the rendering options of the viewer.
+	View ui.Options
+}
+
+// Timing records how long a single document took to render.
+type Timing struct {
+	File     string
+	Duration time.Duration
+}
+
+// Report is the outcome of an audit.
+type Report struct {
+	Problems []check.Problem
+	// Timings lists every rendered file, slowest first.
+	Timings []Timing
+	Total   time.Duration
+}
+
+// Files renders each file in turn and collects the problems found.
+func Files(files []string, opts Options) (Report, error) {
+	var report Report
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return report, err
+		}
+		if opts.MaxSize > 0 && int64(len(data)) > opts.MaxSize {
+			report.Problems = append(report.Problems, check.Problem{
+				File: file, Line: 1, Rule: RuleSize,
+				Message: fmt.Sprintf("ファイルサイズ %d バイトが上限 %d バイトを超えています", len(data), opts.MaxSize),
+			})
+		}
+		start := time.Now()
+		rendered, err := renderWithTimeout(string(data), opts)
+		elapsed := time.Since(start)
+		report.Total += elapsed
+		report.Timings = append(report.Timings, Timing{File: file, Duration: elapsed})
+		switch {
+		case errors.Is(err, errTimeout):
+			report.Problems = append(report.Problems, check.Problem{
+				File: file, Line: 1, Rule: RuleTimeout,
+				Message: fmt.Sprintf("レンダリングが %s 以内に終わりませんでした", opts.Timeout),
+			})
+			continue
+		case err != nil:
+			report.Problems = append(report.Problems, check.Problem{
+				File: file, Line: 1, Rule: RuleRender,
+				Message: err.Error(),
+			})
+			continue
+		}
+		if opts.Slow > 0 && elapsed > opts.Slow {
+			report.Problems = append(report.Problems, check.Problem{
+				File: file, Line: 1, Rule: RuleSlow,
+				Message: fmt.Sprintf("レンダリングに %s かかりました (基準 %s)", elapsed.Round(time.Millisecond), opts.Slow),
+			})
+		}
+		if p, ok := widthProblem(file, string(data), rendered, opts.Width); ok {
+			report.Problems = append(report.Problems, p)
+		}
+	}
+	sort.SliceStable(report.Timings, func(i, j int) bool {
+		return report.Timings[i].Duration > report.Timings[j].Duration
+	})
+	return report, nil
+}
+
+var errTimeout = errors.New("render timed out")
+
+// renderWithTimeout renders src, giving up after opts.Timeout. The abandoned
+// render keeps running in the background, which is acceptable for a one-shot
+// command.
+func renderWithTimeout(src string, opts Options) (string, error) {
+	if opts.Timeout <= 0 {
+		return ui.Render(src, opts.Width, opts.View)
+	}
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := ui.Render(src, opts.Width, opts.View)
+		done <- result{out: out, err: err}
+	}()
+	select {
+	case res := <-done:
+		return res.out, res.err
+	case <-time.After(opts.Timeout):
+		return "", errTimeout
+	}
+}
+
+// widthProblem reports the rendered lines wider than width, which the viewer
+// can only show by scrolling horizontally. The problem points at the source
+// line of the widest one when it can be found.
+func widthProblem(file, src, rendered string, width int) (check.Problem, bool) {
+	if width <= 0 {
+		return check.Problem{}, false
+	}
+	count, widest, widestText := 0, 0, ""
+	for _, line := range strings.Split(ansi.Strip(rendered), "\n") {
+		line = strings.TrimRight(line, " ")
+		w := ansi.StringWidth(line)
+		if w <= width {
+			continue
+		}
+		count++
+		if w > widest {
+			widest, widestText = w, strings.TrimSpace(line)
+		}
+	}
+	if count == 0 {
+		return check.Problem{}, false
+	}
+	return check.Problem{
+		File: file, Line: sourceLine(src, widestText), Rule: RuleWidth,
+		Message: fmt.Sprintf("%d 行が表示幅 %d を超えています (最大 %d 桁)", count, width, widest),
+	}, true
+}
+
+// sourceLine guesses the 1-based source line that produced text by looking
+// for a line sharing its beginning, falling back to the first line.
+func sourceLine(src, text string) int {
+	probe := []rune(text)
+	if len(probe) > 24 {
+		probe = probe[:24]
+	}
+	if len(probe) == 0 {
+		return 1
+	}
+	for i, line := range strings.Split(src, "\n") {
+		if strings.Contains(line, string(probe)) {
+			return i + 1
+		}
+	}
+	return 1
+}
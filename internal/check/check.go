@@ -0,0 +1,204 @@
+// Package check validates markdown documents for automated pipelines: broken
+// relative links, missing heading anchors and unparsable front matter.
+package check
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v2"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// Exit codes shared by the non-interactive subcommands.
+const (
+	// ExitOK means every document passed.
+	ExitOK = 0
+	// ExitProblems means at least one problem was reported.
+	ExitProblems = 1
+	// ExitError means the check itself could not run, e.g. bad arguments or
+	// unreadable paths.
+	ExitError = 2
+)
+
+// Rule names used in Problem.Rule.
+const (
+	RuleFrontMatter = "frontmatter"
+	RuleLink        = "link"
+	RuleAnchor      = "anchor"
+)
+
+// Problem is a single finding, reported against a 1-based source line.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Options tunes how links are resolved.
+type Options struct {
+	// Slug is the anchor style used to resolve "#fragment" links.
+	Slug markdown.SlugStyle
+	// AssetsBase, when set, resolves root-relative links such as "/img/a.png".
+	AssetsBase string
+}
+
+// Checker runs the checks and caches the anchors of every document it reads.
+type Checker struct {
+	opts    Options
+	anchors map[string]map[string]bool
+}
+
+// New returns a checker using opts.
+func New(opts Options) *Checker {
+	return &Checker{opts: opts, anchors: make(map[string]map[string]bool)}
+}
+
+// Files checks every file and returns the problems sorted by file and line.
+func (c *Checker) Files(files []string) ([]Problem, error) {
+	var problems []Problem
+	for _, file := range files {
+		found, err := c.File(file)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// File checks a single markdown document.
+func (c *Checker) File(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	src := string(data)
+	var problems []Problem
+	front, body := markdown.SplitFrontMatter(src)
+	if front != "" {
+		var meta map[string]interface{}
+		if err := yaml.Unmarshal([]byte(front), &meta); err != nil {
+			problems = append(problems, Problem{File: path, Line: 1, Rule: RuleFrontMatter, Message: err.Error()})
+		}
+	}
+
+	source := []byte(body)
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest string
+		switch node := n.(type) {
+		case *ast.Link:
+			dest = string(node.Destination)
+		case *ast.Image:
+			dest = string(node.Destination)
+		default:
+			return ast.WalkContinue, nil
+		}
+		if msg, rule := c.checkLink(path, dest); msg != "" {
+			problems = append(problems, Problem{File: path, Line: lineOf(n, source), Rule: rule, Message: msg})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return problems, err
+}
+
+// checkLink returns a message and rule when dest, found in the document at
+// from, does not resolve.
+func (c *Checker) checkLink(from, dest string) (string, string) {
+	if dest == "" || isExternal(dest) {
+		return "", ""
+	}
+	target, fragment, _ := strings.Cut(dest, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	file := from
+	if target != "" {
+		switch {
+		case strings.HasPrefix(target, "/") && c.opts.AssetsBase != "":
+			file = filepath.Join(c.opts.AssetsBase, filepath.FromSlash(target))
+		case strings.HasPrefix(target, "/"):
+			return "", ""
+		default:
+			file = filepath.Join(filepath.Dir(from), filepath.FromSlash(target))
+		}
+		if _, err := os.Stat(file); err != nil {
+			return "リンク先が存在しません: " + dest, RuleLink
+		}
+	}
+	if fragment == "" || !markdown.IsFile(file) {
+		return "", ""
+	}
+	anchors, err := c.anchorsOf(file)
+	if err != nil || anchors[strings.ToLower(fragment)] {
+		return "", ""
+	}
+	return "見出しアンカーが存在しません: " + dest, RuleAnchor
+}
+
+func (c *Checker) anchorsOf(file string) (map[string]bool, error) {
+	if anchors, ok := c.anchors[file]; ok {
+		return anchors, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	anchors := make(map[string]bool)
+	slugger := markdown.NewSlugger(c.opts.Slug)
+	for _, h := range markdown.Headings(string(data)) {
+		anchors[slugger.Slug(h.Text)] = true
+	}
+	c.anchors[file] = anchors
+	return anchors, nil
+}
+
+// lineOf finds the 1-based line of an inline node through the nearest
+// position goldmark records: its own text segments or its block's lines.
+func lineOf(n ast.Node, source []byte) int {
+	offset := -1
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := child.(*ast.Text); ok && entering {
+			offset = t.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	for p := n; offset < 0 && p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			offset = p.Lines().At(0).Start
+		}
+	}
+	if offset < 0 {
+		return 1
+	}
+	return strings.Count(string(source[:offset]), "\n") + 1
+}
+
+func isExternal(dest string) bool {
+	if strings.HasPrefix(dest, "//") {
+		return true
+	}
+	scheme, _, ok := strings.Cut(dest, ":")
+	return ok && !strings.ContainsAny(scheme, "/#?")
+}
@@ -0,0 +1,156 @@
+// Package config loads the optional user settings file. Every setting has a
+// command-line flag counterpart; values from the file become the flag defaults,
+// so flags always win.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// EnvPath names the environment variable that overrides the settings path.
+const EnvPath = "MDVIEW_CONFIG"
+
+// Config mirrors <UserConfigDir>/mdview/config.yaml.
+type Config struct {
+	Typography      bool     `yaml:"typography,omitempty"`
+	AssetsBase      string   `yaml:"assets_base,omitempty"`
+	ZenWidth        int      `yaml:"zen_width,omitempty"`
+	SourceLines     bool     `yaml:"source_lines,omitempty"`
+	LineNumbers     bool     `yaml:"line_numbers,omitempty"`
+	FootnotePreview bool     `yaml:"footnote_preview,omitempty"`
+	RenderTimeout   Duration `yaml:"render_timeout,omitempty"`
+	ReloadDebounce  Duration `yaml:"reload_debounce,omitempty"`
+	ConfirmReload   bool     `yaml:"confirm_reload,omitempty"`
+	LargeFileMB     int      `yaml:"large_file_mb,omitempty"`
+	Slug            string   `yaml:"slug,omitempty"`
+	TreeWidth       int      `yaml:"tree_width,omitempty"`
+	TreePosition    string   `yaml:"tree_position,omitempty"`
+	Icons           string   `yaml:"icons,omitempty"`
+	TreeDetails     bool     `yaml:"tree_details,omitempty"`
+	TreeSort        string   `yaml:"tree_sort,omitempty"`
+	NoIgnore        bool     `yaml:"no_ignore,omitempty"`
+	Hidden          bool     `yaml:"hidden,omitempty"`
+	FollowSymlinks  bool     `yaml:"follow_symlinks,omitempty"`
+	MaxDepth        int      `yaml:"max_depth,omitempty"`
+	Extensions      []string `yaml:"extensions,omitempty"`
+	AllFiles        bool     `yaml:"all_files,omitempty"`
+	TextFiles       []string `yaml:"text_files,omitempty"`
+	SkipDirs        []string `yaml:"skip_dirs,omitempty"`
+	Wrap            string   `yaml:"wrap,omitempty"`
+	Theme           string   `yaml:"theme,omitempty"`
+	ScratchFile     string   `yaml:"scratch_file,omitempty"`
+	SearchHistory   string   `yaml:"search_history,omitempty"`
+	NoRipgrep       bool     `yaml:"no_ripgrep,omitempty"`
+}
+
+// Duration accepts Go duration strings such as "500ms" in YAML.
+type Duration time.Duration
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Path returns the settings file location.
+func Path() (string, error) {
+	if path := os.Getenv(EnvPath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mdview", "config.yaml"), nil
+}
+
+// Load reads the settings file. A missing file is not an error and yields the
+// zero Config.
+func Load() (Config, error) {
+	var cfg Config
+	path, err := Path()
+	if err != nil {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Set writes value under the top-level key in the settings file, creating the
+// file if needed. Only that line changes, so the rest of the file, comments
+// included, stays as the user wrote it.
+func Set(key string, value interface{}) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	encoded, err := yaml.Marshal(map[string]interface{}{key: value})
+	if err != nil {
+		return err
+	}
+	data = setLine(data, key, strings.TrimSuffix(string(encoded), "\n"))
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := safemode.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return safemode.WriteFile(path, data, 0o644)
+}
+
+// setLine replaces the line setting key at the top level of data with line,
+// keeping a comment at its end, or appends line when key is not set.
+func setLine(data []byte, key, line string) []byte {
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `[ \t]*:.*?([ \t]+#.*)?$`)
+	loc := re.FindSubmatchIndex(data)
+	if loc == nil {
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		return append(data, line+"\n"...)
+	}
+	if loc[2] >= 0 {
+		line += string(data[loc[2]:loc[3]])
+	}
+	return slices.Concat(data[:loc[0]], []byte(line), data[loc[1]:])
+}
@@ -0,0 +1,303 @@
+// Package daemon keeps the index of large vaults in a long-running process,
+// so that viewers started with "mdview open" attach to it instead of scanning
+// the disk on every start. Clients talk to it over a unix socket with one
+// JSON request and response per connection.
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+// Errors returned to clients that should fall back to scanning themselves.
+var (
+	ErrNotRunning = errors.New("デーモンが起動していません")
+	ErrNotIndexed = errors.New("デーモンはこの場所を管理していません")
+)
+
+const (
+	dialTimeout = 200 * time.Millisecond
+	// rescanDelay lets a burst of changes, such as a checkout, settle
+	// before the vault is scanned again.
+	rescanDelay = time.Second
+)
+
+type request struct {
+	Op   string `json:"op"`
+	Path string `json:"path,omitempty"`
+}
+
+type response struct {
+	Error   string       `json:"error,omitempty"`
+	Root    string       `json:"root,omitempty"`
+	Roots   []string     `json:"roots,omitempty"`
+	Entries []tree.Entry `json:"entries,omitempty"`
+}
+
+// SocketPath returns where the daemon listens.
+func SocketPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mdview", "daemon.sock"), nil
+}
+
+// vault is one indexed root. Files being written only update their entry;
+// other changes mark the index stale and schedule a rescan, which a request
+// arriving earlier performs itself.
+type vault struct {
+	root    string
+	entries []tree.Entry
+	stale   bool
+	rescan  *time.Timer
+}
+
+// Server holds the indexes and the watches on them.
+type Server struct {
+	mu       sync.Mutex
+	vaults   []*vault
+	watcher  *fsnotify.Watcher
+	listener net.Listener
+}
+
+// Serve indexes roots and answers clients until a stop request arrives.
+func Serve(roots []string) error {
+	if len(roots) == 0 {
+		return errors.New("管理するディレクトリを指定してください")
+	}
+	if _, err := call(request{Op: "status"}); err == nil {
+		return errors.New("デーモンは既に起動しています")
+	}
+	path, err := SocketPath()
+	if err != nil {
+		return err
+	}
+	if err := safemode.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// A socket left behind by a daemon that did not shut down cleanly.
+	if err := safemode.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	s := &Server{watcher: watcher}
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		v := &vault{root: abs, stale: true}
+		if err := s.scan(v); err != nil {
+			return err
+		}
+		s.vaults = append(s.vaults, v)
+	}
+
+	listener, err := safemode.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	defer safemode.Remove(path)
+	go s.watch()
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// scan rebuilds the index of v if it is stale and watches its directories.
+// The caller holds s.mu or has not published v yet.
+func (s *Server) scan(v *vault) error {
+	if !v.stale {
+		return nil
+	}
+	entries, err := tree.Scan(v.root)
+	if err != nil {
+		return err
+	}
+	v.entries = entries
+	v.stale = false
+	_ = s.watcher.Add(v.root)
+	for _, entry := range entries {
+		if entry.IsDir {
+			// Adding an existing watch is a no-op.
+			_ = s.watcher.Add(filepath.Join(v.root, filepath.FromSlash(entry.Path)))
+		}
+	}
+	return nil
+}
+
+func (s *Server) watch() {
+	for {
+		select {
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			s.mu.Lock()
+			if v := s.vaultFor(event.Name); v != nil {
+				s.changed(v, event)
+			}
+			s.mu.Unlock()
+		case _, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// changed records event in v. The caller holds s.mu.
+func (s *Server) changed(v *vault, event fsnotify.Event) {
+	if event.Op&fsnotify.Write != 0 && event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+		rel, err := filepath.Rel(v.root, event.Name)
+		if err != nil {
+			return
+		}
+		rel = filepath.ToSlash(rel)
+		for i := range v.entries {
+			if v.entries[i].Path == rel && !v.entries[i].IsDir {
+				if info, err := os.Stat(event.Name); err == nil {
+					v.entries[i].ModTime, v.entries[i].Size = info.ModTime(), info.Size()
+				}
+				return
+			}
+		}
+		return
+	}
+	v.stale = true
+	if v.rescan != nil {
+		v.rescan.Stop()
+	}
+	v.rescan = time.AfterFunc(rescanDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_ = s.scan(v)
+	})
+}
+
+// vaultFor returns the innermost vault containing path.
+func (s *Server) vaultFor(path string) *vault {
+	var best *vault
+	for _, v := range s.vaults {
+		if path == v.root || strings.HasPrefix(path, v.root+string(filepath.Separator)) {
+			if best == nil || len(v.root) > len(best.root) {
+				best = v
+			}
+		}
+	}
+	return best
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	resp := s.respond(req)
+	_ = json.NewEncoder(conn).Encode(resp)
+	if req.Op == "stop" {
+		s.listener.Close()
+	}
+}
+
+func (s *Server) respond(req request) response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch req.Op {
+	case "status", "stop":
+		var roots []string
+		for _, v := range s.vaults {
+			roots = append(roots, v.root)
+		}
+		return response{Roots: roots}
+	case "index":
+		v := s.vaultFor(filepath.Clean(req.Path))
+		if v == nil {
+			return response{Error: ErrNotIndexed.Error()}
+		}
+		if err := s.scan(v); err != nil {
+			return response{Error: err.Error()}
+		}
+		return response{Root: v.root, Entries: v.entries}
+	}
+	return response{Error: fmt.Sprintf("不明な要求: %s", req.Op)}
+}
+
+// call sends req to the running daemon.
+func call(req request) (response, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return response{}, err
+	}
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return response{}, ErrNotRunning
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return response{}, err
+	}
+	if resp.Error == ErrNotIndexed.Error() {
+		return resp, ErrNotIndexed
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// Index asks the daemon for the vault containing path, an absolute path. It
+// returns ErrNotRunning or ErrNotIndexed when the caller should scan itself.
+func Index(path string) (root string, entries []tree.Entry, err error) {
+	resp, err := call(request{Op: "index", Path: path})
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.Root, resp.Entries, nil
+}
+
+// Status returns the roots the running daemon manages.
+func Status() ([]string, error) {
+	resp, err := call(request{Op: "status"})
+	return resp.Roots, err
+}
+
+// Stop asks the running daemon to exit.
+func Stop() error {
+	_, err := call(request{Op: "stop"})
+	return err
+}
@@ -0,0 +1,96 @@
+// Package fuzzy ranks candidates against a typed pattern the way file finders
+// do: the pattern's characters must appear in order, and matches that are
+// contiguous or start at word boundaries score higher.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	scoreMatch       = 16
+	bonusConsecutive = 24
+	bonusBoundary    = 20
+	bonusBasename    = 8
+	penaltyGap       = 1
+)
+
+// Score reports how well pattern matches candidate, case-insensitively. ok is
+// false when the pattern is not a subsequence of the candidate.
+func Score(pattern, candidate string) (score int, ok bool) {
+	pattern = strings.ToLower(pattern)
+	if pattern == "" {
+		return 0, true
+	}
+	lower := strings.ToLower(candidate)
+	base := strings.LastIndexByte(lower, '/') + 1
+
+	want, size := utf8.DecodeRuneInString(pattern)
+	prevMatched := false
+	prev := '/'
+	for i, r := range lower {
+		if r != want {
+			if prevMatched {
+				score -= penaltyGap
+			}
+			prevMatched = false
+			prev = r
+			continue
+		}
+		score += scoreMatch
+		if prevMatched {
+			score += bonusConsecutive
+		}
+		if isBoundary(prev) {
+			score += bonusBoundary
+		}
+		if i >= base {
+			score += bonusBasename
+		}
+		prevMatched = true
+		prev = r
+		pattern = pattern[size:]
+		if pattern == "" {
+			return score, true
+		}
+		want, size = utf8.DecodeRuneInString(pattern)
+	}
+	return 0, false
+}
+
+func isBoundary(r rune) bool {
+	return r == '/' || r == '-' || r == '_' || r == '.' || unicode.IsSpace(r)
+}
+
+// Rank returns the candidates matching pattern, best first. Ties prefer the
+// shorter candidate, then alphabetical order.
+func Rank(pattern string, candidates []string) []string {
+	type scored struct {
+		text  string
+		score int
+	}
+	var matches []scored
+	for _, c := range candidates {
+		if score, ok := Score(pattern, c); ok {
+			matches = append(matches, scored{text: c, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.text) != len(b.text) {
+			return len(a.text) < len(b.text)
+		}
+		return a.text < b.text
+	})
+	ranked := make([]string, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.text
+	}
+	return ranked
+}
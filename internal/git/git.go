@@ -0,0 +1,121 @@
+// Package git reads documents from the git history of the repository they
+// belong to. It runs the git command through safemode, so none of it is
+// available with --readonly.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// run executes git in dir and returns its standard output. Failures carry
+// git's own message.
+func run(dir string, args ...string) ([]byte, error) {
+	cmd, err := safemode.Command("git", append([]string{"-C", dir}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, errors.New("git コマンドが見つかりません")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], firstLine(msg))
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// Show returns the contents of the file at path as of rev.
+func Show(path, rev string) ([]byte, error) {
+	dir, name := filepath.Split(path)
+	// "rev:./name" is relative to the -C directory rather than the
+	// repository root.
+	return run(dir, "show", rev+":./"+name)
+}
+
+// ShowAt is Show for a file that was at oldPath, relative to the repository
+// root as Commit.Path is, in rev. An empty oldPath means path itself.
+func ShowAt(path, rev, oldPath string) ([]byte, error) {
+	if oldPath == "" {
+		return Show(path, rev)
+	}
+	// Without "./" the path after the colon is relative to the repository
+	// root.
+	return run(filepath.Dir(path), "show", rev+":"+oldPath)
+}
+
+// ShortRev returns the abbreviated commit hash rev refers to in the
+// repository holding path.
+func ShortRev(path, rev string) (string, error) {
+	out, err := run(filepath.Dir(path), "rev-parse", "--short", "--verify", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Commit is an entry of a file's history.
+type Commit struct {
+	Hash    string
+	Date    string
+	Subject string
+	// Path is the file's path from the repository root in this commit,
+	// which differs from the current one for commits before a rename.
+	Path string
+}
+
+// Log lists the commits that touched the file at path, newest first,
+// following it across renames.
+func Log(path string) ([]Commit, error) {
+	dir, name := filepath.Split(path)
+	// Each entry starts with \x01 and is followed by the file's name in that
+	// commit, so that older versions can be read from where they were.
+	out, err := run(dir, "log", "--follow", "--name-only", "--format=%x01%h%x00%as%x00%s", "--", name)
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	for _, entry := range strings.Split(string(out), "\x01") {
+		lines := strings.Split(strings.TrimSpace(entry), "\n")
+		fields := strings.SplitN(lines[0], "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commit := Commit{Hash: fields[0], Date: fields[1], Subject: fields[2]}
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				commit.Path = unquotePath(line)
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// unquotePath undoes the C-style quoting git applies to paths with unusual
+// characters, which Go's string literal syntax covers.
+func unquotePath(path string) string {
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,121 @@
+// Package grep searches the text of the documents of a directory.
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Match is a line of a document containing the query.
+type Match struct {
+	// File is the document, slash-separated and relative to the root.
+	File string
+	// Line is the 0-based line number.
+	Line int
+	// Text is the line with surrounding whitespace removed.
+	Text string
+}
+
+// Options narrow down a search.
+type Options struct {
+	// CaseSensitive tells upper from lower case.
+	CaseSensitive bool
+	// Limit stops the search after this many matches; zero means no limit.
+	Limit int
+	// Ripgrep searches with rg when it is on PATH and external commands
+	// are allowed, falling back to reading the files here.
+	Ripgrep bool
+}
+
+// Search returns the lines of files, given relative to root, that contain
+// query, in the order of files. It gives up with ctx.Err() once ctx is done,
+// so a search overtaken by a newer query stops early. Unreadable files are
+// skipped.
+func Search(ctx context.Context, root string, files []string, query string, opts Options) ([]Match, error) {
+	if query == "" {
+		return nil, nil
+	}
+	if opts.Ripgrep {
+		matches, err := searchRipgrep(ctx, root, files, query, opts)
+		if err == nil || ctx.Err() != nil {
+			return matches, err
+		}
+	}
+	needle := []byte(query)
+	if !opts.CaseSensitive {
+		needle = bytes.ToLower(needle)
+	}
+	var matches []Match
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			continue
+		}
+		matches = appendMatches(matches, file, data, needle, opts)
+		if opts.Limit > 0 && len(matches) >= opts.Limit {
+			return matches[:opts.Limit], nil
+		}
+	}
+	return matches, nil
+}
+
+// appendMatches appends the lines of data containing needle.
+func appendMatches(matches []Match, file string, data, needle []byte, opts Options) []Match {
+	haystack := data
+	if !opts.CaseSensitive {
+		haystack = bytes.ToLower(data)
+		if len(haystack) != len(data) {
+			// Lower casing changed byte lengths, so line offsets no
+			// longer line up; compare line by line instead.
+			return appendLineMatches(matches, file, data, needle)
+		}
+	}
+	if !bytes.Contains(haystack, needle) {
+		return matches
+	}
+	line, start := 0, 0
+	for start <= len(data) {
+		end := bytes.IndexByte(data[start:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += start
+		}
+		if bytes.Contains(haystack[start:end], needle) {
+			matches = append(matches, Match{File: file, Line: line, Text: lineText(data[start:end])})
+		}
+		line++
+		start = end + 1
+	}
+	return matches
+}
+
+// appendLineMatches is appendMatches for text whose lower case form differs
+// in length, matching each line on its own.
+func appendLineMatches(matches []Match, file string, data, needle []byte) []Match {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 0; scanner.Scan(); line++ {
+		if bytes.Contains(bytes.ToLower(scanner.Bytes()), needle) {
+			matches = append(matches, Match{File: file, Line: line, Text: lineText(scanner.Bytes())})
+		}
+	}
+	return matches
+}
+
+// lineText tidies a matched line for display.
+func lineText(line []byte) string {
+	text := strings.TrimSpace(string(line))
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "�")
+	}
+	return strings.ReplaceAll(text, "\t", " ")
+}
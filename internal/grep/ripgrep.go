@@ -0,0 +1,132 @@
+package grep
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// rgMessage is the part of a line of `rg --json` output a search needs.
+type rgMessage struct {
+	Type string `json:"type"`
+	Data struct {
+		Path struct {
+			Text string `json:"text"`
+		} `json:"path"`
+		Lines struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+		LineNumber int `json:"line_number"`
+	} `json:"data"`
+}
+
+// rgArgBytes bounds the paths given to one run of rg, well below the
+// command line limits of every platform.
+const rgArgBytes = 24 << 10
+
+// searchRipgrep is Search done by ripgrep, which reads files in parallel
+// and is much faster on huge vaults. Its own ignore rules differ from ours,
+// so rather than walking the root it is given files, as many at a time as
+// fit on a command line, and matches are kept in the order of files.
+func searchRipgrep(ctx context.Context, root string, files []string, query string, opts Options) ([]Match, error) {
+	rg, err := exec.LookPath("rg")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"--json", "--fixed-strings", "--no-config", "--no-messages"}
+	if opts.CaseSensitive {
+		args = append(args, "--case-sensitive")
+	} else {
+		args = append(args, "--ignore-case")
+	}
+	args = append(args, "-e", query, "--")
+
+	var matches []Match
+	for len(files) > 0 {
+		n, size := 0, 0
+		for n < len(files) && (n == 0 || size+len(files[n]) < rgArgBytes) {
+			size += len(files[n]) + 1
+			n++
+		}
+		found, err := runRipgrep(ctx, rg, root, args, files[:n])
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+		files = files[n:]
+		// Later runs only find matches in later files.
+		if opts.Limit > 0 && len(matches) >= opts.Limit {
+			return matches[:opts.Limit], nil
+		}
+	}
+	return matches, nil
+}
+
+// runRipgrep searches files with rg, returning the matches in the order of
+// files.
+func runRipgrep(parent context.Context, rg, root string, args, files []string) ([]Match, error) {
+	order := make(map[string]int, len(files))
+	args = slices.Clip(args)
+	for i, file := range files {
+		order[file] = i
+		args = append(args, filepath.FromSlash(file))
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	cmd, err := safemode.CommandContext(ctx, rg, args...)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Dir = root
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var matches []Match
+	decoder := json.NewDecoder(stdout)
+	for {
+		var msg rgMessage
+		if err := decoder.Decode(&msg); err != nil {
+			break
+		}
+		if msg.Type != "match" {
+			continue
+		}
+		file := filepath.ToSlash(msg.Data.Path.Text)
+		if _, ok := order[file]; !ok {
+			continue
+		}
+		text := strings.TrimRight(msg.Data.Lines.Text, "\r\n")
+		matches = append(matches, Match{File: file, Line: msg.Data.LineNumber - 1, Text: lineText([]byte(text))})
+	}
+	waitErr := cmd.Wait()
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	var exitErr *exec.ExitError
+	// Exit status 1 means nothing matched, and 2 with matches that some
+	// files could not be read.
+	if waitErr != nil && len(matches) == 0 && !(errors.As(waitErr, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, waitErr
+	}
+	// rg finishes files in no particular order.
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.File != b.File {
+			return order[a.File] < order[b.File]
+		}
+		return a.Line < b.Line
+	})
+	return matches, nil
+}
@@ -0,0 +1,336 @@
+// Package ignore decides which files the tree and the vault scanners leave
+// out: directories such as .git that never hold documents, and whatever the
+// .gitignore and .mdviewignore files of the browsed directories exclude.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Files lists the ignore files read in every directory, later files taking
+// precedence over earlier ones.
+var Files = []string{".gitignore", ".mdviewignore"}
+
+var (
+	disabled   atomic.Bool
+	showHidden atomic.Bool
+
+	skipMu   sync.RWMutex
+	skipDirs = []string{".git", "node_modules", ".hg", ".svn", ".idea", ".vscode"}
+)
+
+// Disable turns every Matcher created afterwards into one that ignores
+// nothing, for --no-ignore. Skipped directories are still skipped.
+func Disable() {
+	disabled.Store(true)
+}
+
+// Disabled reports whether ignore files are being disregarded.
+func Disabled() bool {
+	return disabled.Load()
+}
+
+// SetShowHidden chooses whether entries whose names start with a dot, such
+// as .github/ or .notes/, are listed. They are left out by default.
+func SetShowHidden(show bool) {
+	showHidden.Store(show)
+}
+
+// ShowHidden reports whether hidden entries are listed.
+func ShowHidden() bool {
+	return showHidden.Load()
+}
+
+// Hidden reports whether an entry called name is left out for being hidden.
+func Hidden(name string) bool {
+	return !ShowHidden() && strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// AddSkipDirs extends the directory names that are never descended into.
+// Names may be glob patterns and are matched case-insensitively.
+func AddSkipDirs(patterns ...string) {
+	skipMu.Lock()
+	defer skipMu.Unlock()
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			skipDirs = append(skipDirs, strings.ToLower(pattern))
+		}
+	}
+}
+
+// SkipDir reports whether a directory called name is never descended into.
+func SkipDir(name string) bool {
+	name = strings.ToLower(name)
+	skipMu.RLock()
+	defer skipMu.RUnlock()
+	for _, pattern := range skipDirs {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Matcher reports whether paths are excluded by ignore files. They are read
+// from the top of the enclosing git repository, or the browsed root outside
+// of one, down to each path, deeper files taking precedence as in git. A nil
+// Matcher ignores nothing.
+type Matcher struct {
+	top      string
+	patterns map[string][]pattern
+	dirs     map[string]bool
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// New returns the matcher for paths below root, or nil when ignore files are
+// disabled.
+func New(root string) *Matcher {
+	if Disabled() {
+		return nil
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	m := &Matcher{
+		top:      repositoryTop(abs),
+		patterns: make(map[string][]pattern),
+		dirs:     make(map[string]bool),
+	}
+	m.patterns[m.top] = append(readPatterns(filepath.Join(m.top, ".git", "info", "exclude")), m.readDir(m.top)...)
+	return m
+}
+
+// repositoryTop returns the closest directory at or above dir holding a .git
+// entry, or dir itself when there is none.
+func repositoryTop(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Lstat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// Ignored reports whether path, an absolute path, is ignored itself or lies
+// in an ignored directory.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	if !filepath.IsAbs(path) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return false
+		}
+		path = abs
+	}
+	rel, err := filepath.Rel(m.top, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	if parent := filepath.Dir(path); parent != m.top && m.dirIgnored(parent) {
+		return true
+	}
+	if isDir {
+		return m.dirIgnored(path)
+	}
+	return m.match(path, false)
+}
+
+func (m *Matcher) dirIgnored(dir string) bool {
+	if dir == m.top {
+		return false
+	}
+	if ignored, ok := m.dirs[dir]; ok {
+		return ignored
+	}
+	ignored := m.dirIgnored(filepath.Dir(dir)) || m.match(dir, true)
+	m.dirs[dir] = ignored
+	return ignored
+}
+
+// match applies the patterns of every directory from the top down to the
+// parent of path; the last pattern matching wins.
+func (m *Matcher) match(path string, isDir bool) bool {
+	var chain []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		chain = append(chain, dir)
+		if dir == m.top || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	ignored := false
+	for i := len(chain) - 1; i >= 0; i-- {
+		dir := chain[i]
+		patterns, ok := m.patterns[dir]
+		if !ok {
+			patterns = m.readDir(dir)
+			m.patterns[dir] = patterns
+		}
+		if len(patterns) == 0 {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, p := range patterns {
+			if p.dirOnly && !isDir {
+				continue
+			}
+			if p.re.MatchString(rel) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
+
+func (m *Matcher) readDir(dir string) []pattern {
+	var patterns []pattern
+	for _, name := range Files {
+		patterns = append(patterns, readPatterns(filepath.Join(dir, name))...)
+	}
+	return patterns
+}
+
+// readPatterns parses an ignore file; a missing or unreadable file has no
+// patterns.
+func readPatterns(path string) []pattern {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	var patterns []pattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := parsePattern(scanner.Text()); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// parsePattern compiles one line of an ignore file.
+func parsePattern(line string) (pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+	// A slash anywhere but at the end ties the pattern to the directory of
+	// the ignore file; otherwise it matches a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := globRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return pattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// globRegexp translates a slash-separated glob, where "**" as a whole
+// segment spans directories.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	segments := strings.Split(glob, "/")
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if segment == "**" {
+			if last {
+				b.WriteString(".*")
+			} else {
+				b.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+		b.WriteString(segmentRegexp(segment))
+		if !last {
+			b.WriteByte('/')
+		}
+	}
+	return b.String()
+}
+
+func segmentRegexp(segment string) string {
+	var b strings.Builder
+	runes := []rune(segment)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(runes[i])))
+			}
+		case '[':
+			end := i + 1
+			if end < len(runes) && (runes[end] == '!' || runes[end] == '^') {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,368 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// converter renders an HTML document as markdown. It covers the structures
+// found in Notion exports and simple static pages; anything else degrades to
+// its text content.
+type converter struct {
+	link func(string) string
+}
+
+func htmlToMarkdown(data []byte, link func(string) string) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	c := &converter{link: link}
+	body := c.container(doc)
+	if find(doc, atom.H1) == nil {
+		if title := find(doc, atom.Title); title != nil {
+			if text := strings.TrimSpace(textContent(title)); text != "" {
+				body = "# " + text + "\n\n" + body
+			}
+		}
+	}
+	return strings.TrimSpace(body) + "\n", nil
+}
+
+// container renders the children of n as a sequence of blocks. Runs of inline
+// content between block elements become paragraphs.
+func (c *converter) container(n *html.Node) string {
+	return c.blocks(n, "\n\n")
+}
+
+func (c *converter) blocks(n *html.Node, sep string) string {
+	var parts []string
+	var inline strings.Builder
+	flush := func() {
+		if text := trimLines(inline.String()); text != "" {
+			parts = append(parts, text)
+		}
+		inline.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if !isBlock(child) {
+			inline.WriteString(c.inline(child))
+			continue
+		}
+		flush()
+		if block := c.block(child); block != "" {
+			parts = append(parts, block)
+		}
+	}
+	flush()
+	return strings.Join(parts, sep)
+}
+
+func (c *converter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.Head, atom.Style, atom.Script, atom.Title, atom.Meta, atom.Link:
+		return ""
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.ReplaceAll(trimLines(c.inlineChildren(n)), "\\\n", " ")
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", int(n.Data[1]-'0')) + " " + text
+	case atom.P, atom.Summary, atom.Figcaption:
+		text := trimLines(c.inlineChildren(n))
+		if n.DataAtom == atom.Summary && text != "" {
+			return "**" + text + "**"
+		}
+		return text
+	case atom.Pre:
+		return codeBlock(n)
+	case atom.Blockquote:
+		return prefixLines(c.container(n), "> ", "> ")
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Hr:
+		return "---"
+	case atom.Table:
+		return c.table(n)
+	}
+	return c.container(n)
+}
+
+func (c *converter) list(n *html.Node) string {
+	var items []string
+	index := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+		if checked, ok := checkbox(li); ok {
+			if checked {
+				marker += "[x] "
+			} else {
+				marker += "[ ] "
+			}
+		}
+		// Items without paragraphs stay tight so nested lists do not turn
+		// the whole list loose.
+		sep := "\n"
+		if find(li, atom.P) != nil {
+			sep = "\n\n"
+		}
+		body := c.blocks(li, sep)
+		items = append(items, prefixLines(body, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// checkbox recognises Notion to-do items, which mark their state with a
+// "checkbox-on" / "checkbox-off" element, as well as plain checkbox inputs.
+func checkbox(li *html.Node) (checked, ok bool) {
+	var found, on bool
+	walk(li, func(n *html.Node) {
+		if found || n.Type != html.ElementNode {
+			return
+		}
+		class := attr(n, "class")
+		switch {
+		case strings.Contains(class, "checkbox-on"):
+			found, on = true, true
+		case strings.Contains(class, "checkbox-off"):
+			found = true
+		case n.DataAtom == atom.Input && attr(n, "type") == "checkbox":
+			found = true
+			_, on = attrOK(n, "checked")
+		}
+	})
+	return on, found
+}
+
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	walk(n, func(tr *html.Node) {
+		if tr.DataAtom != atom.Tr {
+			return
+		}
+		var cells []string
+		for cell := tr.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				text := strings.ReplaceAll(trimLines(c.inlineChildren(cell)), "\\\n", "<br>")
+				cells = append(cells, strings.ReplaceAll(text, "|", "\\|"))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var out []string
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		out = append(out, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			out = append(out, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (c *converter) inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.inline(child))
+	}
+	return b.String()
+}
+
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return collapseSpace(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		return "\\\n"
+	case atom.Strong, atom.B:
+		return wrap(c.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrap(c.inlineChildren(n), "*")
+	case atom.S, atom.Del, atom.Strike:
+		return wrap(c.inlineChildren(n), "~~")
+	case atom.Code:
+		return codeSpan(textContent(n))
+	case atom.A:
+		text := strings.TrimSpace(c.inlineChildren(n))
+		href := c.link(attr(n, "href"))
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + href + ")"
+	case atom.Img:
+		return "![" + attr(n, "alt") + "](" + c.link(attr(n, "src")) + ")"
+	case atom.Input, atom.Style, atom.Script:
+		return ""
+	}
+	return c.inlineChildren(n)
+}
+
+// collapseSpace reduces whitespace runs to single spaces as browsers do,
+// keeping one space at either edge so adjacent inline nodes stay separated.
+func collapseSpace(text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		if text == "" {
+			return ""
+		}
+		return " "
+	}
+	out := strings.Join(words, " ")
+	if first := text[0]; first == ' ' || first == '\t' || first == '\n' || first == '\r' {
+		out = " " + out
+	}
+	if last := text[len(text)-1]; last == ' ' || last == '\t' || last == '\n' || last == '\r' {
+		out += " "
+	}
+	return out
+}
+
+func codeBlock(pre *html.Node) string {
+	lang := ""
+	if code := find(pre, atom.Code); code != nil {
+		for _, class := range strings.Fields(attr(code, "class")) {
+			if l, ok := strings.CutPrefix(class, "language-"); ok {
+				lang = strings.ToLower(l)
+			}
+		}
+	}
+	text := strings.TrimRight(textContent(pre), "\n")
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+func codeSpan(text string) string {
+	ticks := "`"
+	for strings.Contains(text, ticks) {
+		ticks += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return ticks + " " + text + " " + ticks
+	}
+	return ticks + text + ticks
+}
+
+// wrap surrounds text with an emphasis marker, keeping surrounding spaces
+// outside so the result still parses as emphasis.
+func wrap(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+func trimLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func prefixLines(text, first, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+			continue
+		}
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isBlock(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.Html, atom.Head, atom.Body, atom.Style, atom.Script, atom.Title, atom.Meta, atom.Link,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.P, atom.Pre, atom.Blockquote,
+		atom.Ul, atom.Ol, atom.Li, atom.Hr, atom.Table, atom.Div, atom.Section, atom.Article,
+		atom.Header, atom.Footer, atom.Main, atom.Nav, atom.Aside, atom.Figure, atom.Figcaption,
+		atom.Details, atom.Summary:
+		return true
+	}
+	return false
+}
+
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, fn)
+	}
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(node *html.Node) {
+		if found == nil && node.Type == html.ElementNode && node.DataAtom == a {
+			found = node
+		}
+	})
+	return found
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(node *html.Node) {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+		}
+	})
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	value, _ := attrOK(n, key)
+	return value
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
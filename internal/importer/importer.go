@@ -0,0 +1,232 @@
+// Package importer turns exported notes (Notion exports or plain HTML sites)
+// into a markdown tree that mdview can browse. HTML pages are converted to
+// markdown, every other file is copied, and Notion's page IDs are dropped from
+// names with the links of HTML and markdown pages rewritten to match.
+package importer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// Result summarises an import.
+type Result struct {
+	Pages  int
+	Copied int
+}
+
+// notionID matches the " 0123…cdef" suffix Notion appends to page names.
+var notionID = regexp.MustCompile(`^(.*?) [0-9a-f]{32}(\.[^.]*)?$`)
+
+// Import converts the export at src, a directory or a .zip archive, into dst.
+// dst must not exist yet so nothing is ever overwritten.
+func Import(src, dst string) (Result, error) {
+	if _, err := os.Stat(dst); err == nil {
+		return Result{}, fmt.Errorf("出力先が既に存在します: %s", dst)
+	}
+	fsys, closeFS, err := openExport(src)
+	if err != nil {
+		return Result{}, err
+	}
+	defer closeFS()
+
+	imp := &importer{fsys: fsys, dst: dst, paths: make(map[string]string)}
+	if err := imp.plan(); err != nil {
+		return Result{}, err
+	}
+	if err := safemode.MkdirAll(dst, 0o755); err != nil {
+		return Result{}, err
+	}
+	return imp.run()
+}
+
+func openExport(src string) (fs.FS, func(), error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(src), func() {}, nil
+	}
+	if strings.EqualFold(filepath.Ext(src), ".zip") {
+		archive, err := zip.OpenReader(src)
+		if err != nil {
+			return nil, nil, err
+		}
+		return archive, func() { archive.Close() }, nil
+	}
+	return nil, nil, fmt.Errorf("ディレクトリまたは .zip を指定してください: %s", src)
+}
+
+type importer struct {
+	fsys  fs.FS
+	dst   string
+	files []string
+	// paths maps slash-separated export paths to their output paths.
+	paths map[string]string
+}
+
+// plan decides the output name of every file and directory up front so links
+// between pages can be rewritten regardless of conversion order.
+func (imp *importer) plan() error {
+	used := make(map[string]bool)
+	return fs.WalkDir(imp.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			imp.paths[p] = "."
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || d.Name() == "__MACOSX" {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		dir := imp.paths[path.Dir(p)]
+		out := path.Join(dir, outputName(cleanName(d.Name()), d.IsDir()))
+		if used[out] {
+			// Two pages with the same title: keep the original name, and
+			// number it should that be taken too.
+			name := outputName(d.Name(), d.IsDir())
+			out = path.Join(dir, name)
+			ext := ""
+			if !d.IsDir() {
+				ext = path.Ext(name)
+			}
+			for n := 2; used[out]; n++ {
+				out = path.Join(dir, fmt.Sprintf("%s %d%s", strings.TrimSuffix(name, ext), n, ext))
+			}
+		}
+		used[out] = true
+		imp.paths[p] = out
+		if !d.IsDir() {
+			imp.files = append(imp.files, p)
+		}
+		return nil
+	})
+}
+
+// outputName returns the name an exported file is written under, with HTML
+// pages becoming markdown.
+func outputName(name string, dir bool) string {
+	if !dir && isHTML(name) {
+		return strings.TrimSuffix(name, path.Ext(name)) + ".md"
+	}
+	return name
+}
+
+func (imp *importer) run() (Result, error) {
+	var res Result
+	for _, p := range imp.files {
+		data, err := fs.ReadFile(imp.fsys, p)
+		if err != nil {
+			return res, err
+		}
+		if isHTML(p) {
+			md, err := htmlToMarkdown(data, func(href string) string { return imp.rewriteLink(p, href) })
+			if err != nil {
+				return res, fmt.Errorf("%s: %w", p, err)
+			}
+			data = []byte(md)
+			res.Pages++
+		} else if markdown.IsFile(p) {
+			// Markdown pages are kept as they are, but their links still
+			// name the targets with the page IDs dropped above.
+			data = []byte(markdown.RewriteDestinations(string(data), func(dest string, _ bool) string {
+				return imp.rewriteMarkdownLink(p, dest)
+			}))
+			res.Copied++
+		} else {
+			res.Copied++
+		}
+		out := filepath.Join(imp.dst, filepath.FromSlash(imp.paths[p]))
+		if err := safemode.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+			return res, err
+		}
+		if err := safemode.WriteFile(out, data, 0o644); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// rewriteLink points a relative link in the page at from to the imported
+// name of its target. External links and unknown targets are left alone.
+func (imp *importer) rewriteLink(from, href string) string {
+	link, ok := imp.importedLink(from, href)
+	if !ok {
+		return href
+	}
+	if strings.ContainsAny(link, " ()<>") {
+		return "<" + link + ">"
+	}
+	return link
+}
+
+// linkEscaper escapes what cannot stand in a markdown link destination
+// written without angle brackets, the way Notion writes them.
+var linkEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+
+// rewriteMarkdownLink is rewriteLink for a destination already sitting in
+// markdown, which may or may not be wrapped in angle brackets.
+func (imp *importer) rewriteMarkdownLink(from, dest string) string {
+	link, ok := imp.importedLink(from, dest)
+	if !ok {
+		return dest
+	}
+	return linkEscaper.Replace(link)
+}
+
+// importedLink returns href relative to the imported page at from, or false
+// when href is not a link to another file of the export.
+func (imp *importer) importedLink(from, href string) (string, bool) {
+	if href == "" || strings.HasPrefix(href, "#") || strings.Contains(href, ":") {
+		return "", false
+	}
+	target, fragment, _ := strings.Cut(href, "#")
+	unescaped, err := url.PathUnescape(target)
+	if err != nil {
+		return "", false
+	}
+	out, ok := imp.paths[path.Join(path.Dir(from), unescaped)]
+	if !ok {
+		return "", false
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(imp.paths[from])), filepath.FromSlash(out))
+	if err != nil {
+		return "", false
+	}
+	link := filepath.ToSlash(rel)
+	if fragment != "" {
+		link += "#" + fragment
+	}
+	return link, true
+}
+
+// cleanName drops the page ID Notion appends to exported names.
+func cleanName(name string) string {
+	if m := notionID.FindStringSubmatch(name); m != nil && m[1] != "" {
+		return m[1] + m[2]
+	}
+	return name
+}
+
+func isHTML(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+// Package indexcache keeps what was read from the documents below a root,
+// their front matter tags and aliases and their links, in a file under the
+// user's cache directory. Later runs read again only the documents whose
+// modification time or size changed since.
+package indexcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// version is bumped whenever what is recorded changes, so that files
+// written by older releases are ignored.
+const version = 1
+
+// Link is a link of a document as written, with the trimmed line holding it.
+type Link struct {
+	Target  string `json:"target"`
+	Wiki    bool   `json:"wiki,omitempty"`
+	Line    int    `json:"line"`
+	Context string `json:"context,omitempty"`
+}
+
+// doc is what is known about a document as of ModTime and Size. Each part
+// is read the first time it is asked for.
+type doc struct {
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size"`
+
+	Tags     []string `json:"tags,omitempty"`
+	HasTags  bool     `json:"has_tags,omitempty"`
+	Aliases  []string `json:"aliases,omitempty"`
+	HasAlias bool     `json:"has_aliases,omitempty"`
+	Links    []Link   `json:"links,omitempty"`
+	HasLinks bool     `json:"has_links,omitempty"`
+}
+
+type file struct {
+	Version int             `json:"version"`
+	Root    string          `json:"root"`
+	Docs    map[string]*doc `json:"docs"`
+}
+
+// Cache is the index of one root. It is not safe for concurrent use.
+type Cache struct {
+	root  string
+	path  string
+	docs  map[string]*doc
+	dirty bool
+}
+
+// Open loads the index of root, starting empty when there is none yet or
+// it cannot be read.
+func Open(root string) *Cache {
+	c := &Cache{root: root, docs: make(map[string]*doc)}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return c
+	}
+	sum := sha256.Sum256([]byte(root))
+	c.path = filepath.Join(dir, "mdview", "index", hex.EncodeToString(sum[:8])+".json")
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	var f file
+	if json.Unmarshal(data, &f) == nil && f.Version == version && f.Root == root && f.Docs != nil {
+		c.docs = f.Docs
+	}
+	return c
+}
+
+// entry returns the record of rel, emptied when the file changed since it
+// was made, or nil when the file cannot be read.
+func (c *Cache) entry(rel string) *doc {
+	info, err := os.Stat(filepath.Join(c.root, filepath.FromSlash(rel)))
+	if err != nil {
+		delete(c.docs, rel)
+		return nil
+	}
+	d := c.docs[rel]
+	if d == nil || !d.ModTime.Equal(info.ModTime()) || d.Size != info.Size() {
+		d = &doc{ModTime: info.ModTime(), Size: info.Size()}
+		c.docs[rel] = d
+		c.dirty = true
+	}
+	return d
+}
+
+// Tags returns the tags of rel, calling read with its absolute path unless
+// they are known. Errors are returned without being recorded.
+func (c *Cache) Tags(rel string, read func(path string) ([]string, error)) ([]string, error) {
+	d := c.entry(rel)
+	if d != nil && d.HasTags {
+		return d.Tags, nil
+	}
+	tags, err := read(filepath.Join(c.root, filepath.FromSlash(rel)))
+	if err != nil || d == nil {
+		return tags, err
+	}
+	d.Tags, d.HasTags, c.dirty = tags, true, true
+	return tags, nil
+}
+
+// Aliases returns the aliases of rel like Tags.
+func (c *Cache) Aliases(rel string, read func(path string) ([]string, error)) ([]string, error) {
+	d := c.entry(rel)
+	if d != nil && d.HasAlias {
+		return d.Aliases, nil
+	}
+	aliases, err := read(filepath.Join(c.root, filepath.FromSlash(rel)))
+	if err != nil || d == nil {
+		return aliases, err
+	}
+	d.Aliases, d.HasAlias, c.dirty = aliases, true, true
+	return aliases, nil
+}
+
+// Links returns the links of rel like Tags.
+func (c *Cache) Links(rel string, read func(path string) ([]Link, error)) ([]Link, error) {
+	d := c.entry(rel)
+	if d != nil && d.HasLinks {
+		return d.Links, nil
+	}
+	links, err := read(filepath.Join(c.root, filepath.FromSlash(rel)))
+	if err != nil || d == nil {
+		return links, err
+	}
+	d.Links, d.HasLinks, c.dirty = links, true, true
+	return links, nil
+}
+
+// Save writes the index back when anything changed, dropping the records
+// of files that no longer exist. The index is only a shortcut, so callers
+// may ignore the error, as they must with --readonly.
+func (c *Cache) Save() error {
+	if c.path == "" {
+		return nil
+	}
+	for rel := range c.docs {
+		if _, err := os.Stat(filepath.Join(c.root, filepath.FromSlash(rel))); errors.Is(err, fs.ErrNotExist) {
+			delete(c.docs, rel)
+			c.dirty = true
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(file{Version: version, Root: c.root, Docs: c.docs})
+	if err != nil {
+		return err
+	}
+	if err := safemode.MkdirAll(filepath.Dir(c.path), 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	// Write and rename so that a concurrent run never reads half a file.
+	tmp := c.path + ".tmp"
+	if err := safemode.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := safemode.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,206 @@
+// Package linediff compares two versions of a document line by line.
+package linediff
+
+// Kind says how a line of an edit script relates to the two versions.
+type Kind int
+
+const (
+	// Equal lines are in both versions.
+	Equal Kind = iota
+	// Insert lines are only in the new version.
+	Insert
+	// Delete lines are only in the old version.
+	Delete
+)
+
+// Line is one line of an edit script.
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// Diff returns a shortest edit script turning old into new, with the deleted
+// lines of each change before the inserted ones. It uses Myers' algorithm,
+// which is fast for the small edits documents usually see and needs memory
+// linear in their length.
+func Diff(old, new []string) []Line {
+	// The common prefix and suffix need no search.
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+
+	script := make([]Line, 0, max(len(old), len(new)))
+	for _, text := range old[:prefix] {
+		script = append(script, Line{Equal, text})
+	}
+	script = append(script, myers(old[prefix:len(old)-suffix], new[prefix:len(new)-suffix])...)
+	for _, text := range old[len(old)-suffix:] {
+		script = append(script, Line{Equal, text})
+	}
+	return script
+}
+
+// Stats counts the inserted and deleted lines of script.
+func Stats(script []Line) (inserted, deleted int) {
+	for _, line := range script {
+		switch line.Kind {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+	return inserted, deleted
+}
+
+func myers(a, b []string) []Line {
+	script := make([]Line, 0, max(len(a), len(b)))
+	return groupChanges(diffRange(script, a, b))
+}
+
+// diffRange appends a shortest edit script turning a into b. It splits the
+// problem at the middle snake of an optimal path (Myers' linear space
+// refinement), so memory stays linear in the length of the documents
+// however much of them changed.
+func diffRange(script []Line, a, b []string) []Line {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		script = append(script, Line{Equal, a[0]})
+		a, b = a[1:], b[1:]
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	tail := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, text := range b {
+			script = append(script, Line{Insert, text})
+		}
+	case len(b) == 0:
+		for _, text := range a {
+			script = append(script, Line{Delete, text})
+		}
+	default:
+		// With the ends trimmed at least two edits are needed, so both
+		// halves are smaller than a and b.
+		x, y, u, v := middleSnake(a, b)
+		script = diffRange(script, a[:x], b[:y])
+		for _, text := range a[x:u] {
+			script = append(script, Line{Equal, text})
+		}
+		script = diffRange(script, a[u:], b[v:])
+	}
+	for _, text := range tail {
+		script = append(script, Line{Equal, text})
+	}
+	return script
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the snake in the
+// middle of a shortest path from the start of a and b to their ends,
+// searching from both ends at once until the paths overlap.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	// forward[k+offset] is the furthest x reached on diagonal k = x-y from
+	// the start, backward[k+offset] the furthest distance from the ends on
+	// diagonal k of the reversed documents; -1 marks diagonals not reached.
+	offset := limit + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	// furthest returns where a path of d edits ends on diagonal k, going
+	// right from diagonal k-1 or down from k+1, or -1 when neither stays
+	// inside the n by m grid.
+	furthest := func(v []int, d, k int) int {
+		if d == 0 {
+			return 0
+		}
+		x := -1
+		if down := v[k+1+offset]; down >= 0 && down-k <= m {
+			x = down
+		}
+		if right := v[k-1+offset]; right >= 0 && right+1 <= n && right+1 > x {
+			x = right + 1
+		}
+		return x
+	}
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			x0 := furthest(forward, d, k)
+			if x0 < 0 {
+				continue
+			}
+			x := x0
+			for x < n && x-k < m && a[x] == b[x-k] {
+				x++
+			}
+			forward[k+offset] = x
+			// The backward diagonal through this one is delta-k.
+			if back := delta - k; odd && back >= -(d-1) && back <= d-1 {
+				if r := backward[back+offset]; r >= 0 && x+r >= n {
+					return x0, x0 - k, x, x - k
+				}
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			x0 := furthest(backward, d, k)
+			if x0 < 0 {
+				continue
+			}
+			x := x0
+			for x < n && x-k < m && a[n-1-x] == b[m-1-x+k] {
+				x++
+			}
+			backward[k+offset] = x
+			if front := delta - k; !odd && front >= -d && front <= d {
+				if f := forward[front+offset]; f >= 0 && f+x >= n {
+					return n - x, m - x + k, n - x0, m - x0 + k
+				}
+			}
+		}
+	}
+	// Unreachable: the paths meet after at most limit rounds each.
+	return 0, 0, n, m
+}
+
+// groupChanges moves the deletions of each run of changes before its
+// insertions, so a changed paragraph reads as the old text then the new.
+func groupChanges(script []Line) []Line {
+	out := make([]Line, 0, len(script))
+	for i := 0; i < len(script); {
+		if script[i].Kind == Equal {
+			out = append(out, script[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(script) && script[j].Kind != Equal {
+			j++
+		}
+		for _, line := range script[i:j] {
+			if line.Kind == Delete {
+				out = append(out, line)
+			}
+		}
+		for _, line := range script[i:j] {
+			if line.Kind == Insert {
+				out = append(out, line)
+			}
+		}
+		i = j
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+// Package linkgraph resolves the links between the documents of a vault,
+// markdown links and Obsidian wikilinks alike, for the backlinks pane and
+// "mdview graph".
+package linkgraph
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/indexcache"
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// Graph knows the documents below a vault root and the names wikilinks may
+// use for them.
+type Graph struct {
+	root  string
+	files []string
+	names map[string]string
+	edges []Edge
+	cache *indexcache.Cache
+}
+
+// Edge is a link from one document of the vault to another.
+type Edge struct {
+	// From and To are the linking and linked documents relative to the
+	// vault root.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Line is the zero-based source line of the link in From.
+	Line int `json:"line"`
+	// Context is that line, trimmed.
+	Context string `json:"context"`
+}
+
+// New returns the graph of files, slash-separated paths relative to root as
+// listed by tree.MarkdownFiles. Documents are read lazily, and only when
+// they changed since the index cache last recorded them.
+func New(root string, files []string) *Graph {
+	return &Graph{root: root, files: files, cache: indexcache.Open(root)}
+}
+
+// Files returns the documents of the graph.
+func (g *Graph) Files() []string {
+	return g.files
+}
+
+// targets maps the lowercased names a wikilink may use for a document, its
+// path and file name with or without extension and its front matter
+// aliases, to its path.
+func (g *Graph) targets() map[string]string {
+	if g.names != nil {
+		return g.names
+	}
+	g.names = make(map[string]string)
+	add := func(name, rel string) {
+		name = strings.ToLower(name)
+		if _, taken := g.names[name]; !taken {
+			g.names[name] = rel
+		}
+	}
+	// Paths win over file names, which win over aliases.
+	for _, rel := range g.files {
+		add(rel, rel)
+		add(strings.TrimSuffix(rel, path.Ext(rel)), rel)
+	}
+	for _, rel := range g.files {
+		base := path.Base(rel)
+		add(base, rel)
+		add(strings.TrimSuffix(base, path.Ext(base)), rel)
+	}
+	for _, rel := range g.files {
+		if !markdown.IsFile(rel) {
+			continue
+		}
+		aliases, err := g.cache.Aliases(rel, readAliases)
+		if err != nil {
+			continue
+		}
+		for _, alias := range aliases {
+			add(alias, rel)
+		}
+	}
+	_ = g.cache.Save()
+	return g.names
+}
+
+// Resolve finds the document a wikilink target names, trying a path
+// relative to dir, a directory relative to the vault root, before the names
+// known to the vault.
+func (g *Graph) Resolve(dir, target string) (string, bool) {
+	target = strings.TrimPrefix(filepath.ToSlash(target), "/")
+	if dir != "." && dir != "" {
+		if rel, ok := g.targets()[strings.ToLower(path.Join(dir, target))]; ok {
+			return rel, true
+		}
+	}
+	rel, ok := g.targets()[strings.ToLower(target)]
+	return rel, ok
+}
+
+// Edges lists the links between documents of the vault in file order,
+// leaving out links to URLs, to missing documents and to the linking
+// document itself. A line linking to the same document twice gives one edge.
+func (g *Graph) Edges() []Edge {
+	if g.edges != nil {
+		return g.edges
+	}
+	g.edges = []Edge{}
+	known := make(map[string]bool, len(g.files))
+	for _, rel := range g.files {
+		known[rel] = true
+	}
+	for _, from := range g.files {
+		if markdown.IsText(from) {
+			continue
+		}
+		links, err := g.cache.Links(from, readLinks)
+		if err != nil {
+			continue
+		}
+		dir := path.Dir(from)
+		seen := make(map[Edge]bool)
+		for _, link := range links {
+			target := ""
+			switch {
+			case link.Wiki && link.Target != "":
+				target, _ = g.Resolve(dir, link.Target)
+			case link.Wiki, link.Target == "", markdown.IsURL(link.Target):
+				continue
+			case strings.HasPrefix(link.Target, "/"):
+				target = path.Clean(strings.TrimPrefix(link.Target, "/"))
+			default:
+				target = path.Join(dir, link.Target)
+			}
+			key := Edge{From: from, To: target, Line: link.Line}
+			if !known[target] || target == from || seen[key] {
+				continue
+			}
+			seen[key] = true
+			key.Context = link.Context
+			g.edges = append(g.edges, key)
+		}
+	}
+	_ = g.cache.Save()
+	return g.edges
+}
+
+// readAliases reads the front matter aliases of the document at absPath.
+func readAliases(absPath string) ([]string, error) {
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return markdown.Aliases(string(data)), nil
+}
+
+// readLinks reads the links of the document at absPath with the lines
+// holding them.
+func readLinks(absPath string) ([]indexcache.Link, error) {
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := markdown.Convert(absPath, data)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(content, "\n")
+	var links []indexcache.Link
+	for _, link := range markdown.Links(content) {
+		links = append(links, indexcache.Link{
+			Target:  link.Target,
+			Wiki:    link.Wiki,
+			Line:    link.Line,
+			Context: strings.TrimSpace(lines[link.Line]),
+		})
+	}
+	return links, nil
+}
@@ -0,0 +1,576 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AsciiDoc converts the commonly used subset of AsciiDoc to markdown:
+// section titles, paragraphs and inline markup, lists, delimited blocks,
+// admonitions, tables, images, links and cross references. Attribute
+// references are substituted; preprocessor directives are dropped.
+func AsciiDoc(data []byte) (string, error) {
+	c := &asciidoc{attrs: map[string]string{
+		"nbsp": " ", "empty": "", "sp": " ", "plus": "+", "amp": "&",
+		"lt": "<", "gt": ">", "startsb": "[", "endsb": "]", "vbar": "|",
+	}}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	out := c.convert(strings.Split(text, "\n"), true)
+	return strings.TrimSpace(strings.Join(out, "\n")) + "\n", nil
+}
+
+var (
+	adocSection     = regexp.MustCompile(`^(={1,6})\s+(.+?)(?:\s+=+)?$`)
+	adocAttrEntry   = regexp.MustCompile(`^:(!?)([\w-]+?)(!?):(?:\s+(.*))?$`)
+	adocPreproc     = regexp.MustCompile(`^(?:ifdef|ifndef|ifeval|endif)::`)
+	adocBlockAttrs  = regexp.MustCompile(`^\[(.*)\]$`)
+	adocBlockTitle  = regexp.MustCompile(`^\.([^.\s].*)$`)
+	adocAdmonition  = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	adocUnordered   = regexp.MustCompile(`^(\*{1,5}|-)\s+(.*)$`)
+	adocOrdered     = regexp.MustCompile(`^(\.{1,5})\s+(.*)$`)
+	adocDescription = regexp.MustCompile(`^(\S.*?)(?:::|:::|::::|;;)(?:\s+(.*))?$`)
+	adocBlockMacro  = regexp.MustCompile(`^(image|include|video|toc)::([^\[]*)\[(.*)\]$`)
+	adocAttrRef     = regexp.MustCompile(`\{([\w-]+)\}`)
+	adocPassthrough = regexp.MustCompile(`(^|[^\w+])\+([^+\s](?:[^+]*?[^+\s])?)\+($|[^\w+])`)
+	adocInlineImage = regexp.MustCompile(`image:([^\s\[:][^\s\[]*)\[([^\]]*)\]`)
+	adocURLMacro    = regexp.MustCompile(`(?:link:)?((?:https?|ftp|mailto):[^\s\[]+)\[([^\]]*)\]`)
+	adocLinkMacro   = regexp.MustCompile(`(?:link|xref):([^\s\[]+)\[([^\]]*)\]`)
+	adocXref        = regexp.MustCompile(`<<([^,>]+)(?:,\s*([^>]+))?>>`)
+	adocBold        = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*?[^*\s])?)\*($|[^\w*])`)
+	adocItalic      = regexp.MustCompile(`__([^_]+?)__`)
+	adocHighlight   = regexp.MustCompile(`(^|[^\w#])#([^#\s](?:[^#]*?[^#\s])?)#($|[^\w#])`)
+)
+
+// admonitionLabels lists the admonition styles.
+var admonitionLabels = map[string]bool{
+	"NOTE": true, "TIP": true, "IMPORTANT": true, "WARNING": true, "CAUTION": true,
+}
+
+type asciidoc struct {
+	attrs map[string]string
+}
+
+// blockAttrs holds the attribute list and title given to the next block.
+type blockAttrs struct {
+	style       string
+	lang        string
+	attribution string
+	cols        int
+	title       string
+}
+
+// convert converts lines, which form a whole document when top is set and
+// the contents of a compound block otherwise.
+func (c *asciidoc) convert(lines []string, top bool) []string {
+	var out []string
+	var pending blockAttrs
+	// quoting is set while the paragraph being converted belongs to an
+	// admonition and is shown as a block quote.
+	quoting := false
+	inHeader := false
+	seenContent := !top
+	var listWidths []int
+
+	emitTitle := func() {
+		if pending.title != "" {
+			out = append(out, "**"+c.inline(pending.title)+"**", "")
+		}
+	}
+	paragraphStart := func() bool {
+		return len(out) == 0 || out[len(out)-1] == ""
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		if line == "" {
+			quoting, inHeader = false, false
+			if len(out) > 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+			continue
+		}
+		if line == "////" {
+			i = closingDelimiter(lines, i)
+			continue
+		}
+		if strings.HasPrefix(line, "//") {
+			continue
+		}
+		if m := adocAttrEntry.FindStringSubmatch(line); m != nil {
+			if m[1] == "!" || m[3] == "!" {
+				delete(c.attrs, m[2])
+			} else {
+				c.attrs[m[2]] = m[4]
+			}
+			continue
+		}
+		if inHeader || adocPreproc.MatchString(line) {
+			// Author and revision lines follow the document title.
+			continue
+		}
+		if m := adocBlockAttrs.FindStringSubmatch(line); m != nil && !strings.HasPrefix(m[1], "[") {
+			pending = parseBlockAttrs(m[1], pending.title)
+			continue
+		} else if m != nil {
+			// An anchor such as [[id]].
+			continue
+		}
+		if m := adocBlockTitle.FindStringSubmatch(line); m != nil && paragraphStart() {
+			pending.title = m[1]
+			continue
+		}
+
+		if isDelimiter(line) {
+			end := closingDelimiter(lines, i)
+			body := lines[i+1 : end]
+			i = end
+			emitTitle()
+			out = append(out, c.delimited(line, body, pending)...)
+			out = append(out, "")
+			pending = blockAttrs{}
+			listWidths = nil
+			seenContent = true
+			continue
+		}
+
+		if m := adocSection.FindStringSubmatch(line); m != nil && paragraphStart() {
+			out = append(out, strings.Repeat("#", len(m[1]))+" "+c.inline(m[2]), "")
+			if len(m[1]) == 1 && !seenContent {
+				inHeader = true
+			}
+			pending, listWidths, seenContent = blockAttrs{}, nil, true
+			continue
+		}
+		seenContent = true
+
+		if m := adocBlockMacro.FindStringSubmatch(line); m != nil {
+			emitTitle()
+			out = append(out, c.blockMacro(m[1], m[2], m[3]), "")
+			pending = blockAttrs{}
+			continue
+		}
+		switch line {
+		case "'''":
+			if !paragraphStart() {
+				// Directly under text, --- would make it a setext heading.
+				out = append(out, "")
+			}
+			out = append(out, "---", "")
+			continue
+		case "<<<", "+":
+			// Page breaks do not apply, and list continuations are implied
+			// by the markdown layout.
+			continue
+		}
+
+		if paragraphStart() {
+			switch style := pending.style; {
+			case style == "source" || style == "listing" || style == "literal":
+				end := paragraphEnd(lines, i)
+				emitTitle()
+				out = append(out, CodeBlock(strings.Join(lines[i:end], "\n"), pending.lang), "")
+				i = end - 1
+				pending = blockAttrs{}
+				continue
+			case admonitionLabels[style]:
+				emitTitle()
+				out = append(out, "> **"+style+":** "+c.inline(line))
+				quoting = true
+				pending = blockAttrs{}
+				continue
+			case line[0] == ' ' || line[0] == '\t':
+				// A literal paragraph.
+				end := paragraphEnd(lines, i)
+				emitTitle()
+				out = append(out, CodeBlock(dedent(lines[i:end]), ""), "")
+				i = end - 1
+				pending = blockAttrs{}
+				continue
+			}
+			emitTitle()
+			pending = blockAttrs{}
+		}
+
+		if quoting {
+			out = append(out, "> "+c.inline(line))
+			continue
+		}
+		if m := adocAdmonition.FindStringSubmatch(line); m != nil {
+			out = append(out, "> **"+m[1]+":** "+c.inline(m[2]))
+			quoting = true
+			continue
+		}
+		if m := adocUnordered.FindStringSubmatch(line); m != nil {
+			depth := len(m[1])
+			if m[1] == "-" {
+				depth = 1
+			}
+			out = append(out, listItem(&listWidths, depth, "- ")+c.inline(m[2]))
+			continue
+		}
+		if m := adocOrdered.FindStringSubmatch(line); m != nil {
+			out = append(out, listItem(&listWidths, len(m[1]), "1. ")+c.inline(m[2]))
+			continue
+		}
+		if m := adocDescription.FindStringSubmatch(line); m != nil {
+			item := "- **" + c.inline(m[1]) + "**"
+			if m[2] != "" {
+				item += ": " + c.inline(m[2])
+			}
+			listWidths = []int{2}
+			out = append(out, item)
+			continue
+		}
+		if strings.HasSuffix(line, " +") {
+			out = append(out, c.inline(strings.TrimSuffix(line, " +"))+"\\")
+			continue
+		}
+		out = append(out, c.inline(line))
+	}
+	return out
+}
+
+// delimited converts the body of a delimited block opened by delimiter.
+func (c *asciidoc) delimited(delimiter string, body []string, attrs blockAttrs) []string {
+	switch {
+	case strings.HasPrefix(delimiter, "```"):
+		lang := strings.TrimSpace(strings.TrimLeft(delimiter, "`"))
+		if lang == "" {
+			lang = attrs.lang
+		}
+		return []string{CodeBlock(strings.Join(body, "\n"), lang)}
+	case delimiter == "--":
+		if admonitionLabels[attrs.style] {
+			return admonitionBlock(attrs.style, c.convert(body, false))
+		}
+		return c.convert(body, false)
+	case delimiter == "|===":
+		return c.table(body, attrs.cols)
+	}
+	switch delimiter[0] {
+	case '-':
+		lang := attrs.lang
+		if lang == "" && attrs.style == "source" {
+			lang = c.attrs["source-language"]
+		}
+		return []string{CodeBlock(strings.Join(body, "\n"), lang)}
+	case '.':
+		return []string{CodeBlock(strings.Join(body, "\n"), "")}
+	case '+':
+		return body
+	case '_':
+		var inner []string
+		if attrs.style == "verse" {
+			for _, line := range body {
+				inner = append(inner, c.inline(line)+"\\")
+			}
+		} else {
+			inner = c.convert(body, false)
+		}
+		if attrs.attribution != "" {
+			inner = append(inner, "", "— "+c.inline(attrs.attribution))
+		}
+		return quote(inner)
+	case '=':
+		if admonitionLabels[attrs.style] {
+			return admonitionBlock(attrs.style, c.convert(body, false))
+		}
+		return c.convert(body, false)
+	case '*':
+		return quote(c.convert(body, false))
+	}
+	return body
+}
+
+// blockMacro converts a block macro such as image::target[attributes].
+func (c *asciidoc) blockMacro(name, target, attrs string) string {
+	target = c.substitute(target)
+	text := strings.TrimSpace(strings.SplitN(attrs, ",", 2)[0])
+	switch name {
+	case "image":
+		return "![" + strings.Trim(text, `"`) + "](" + target + ")"
+	case "include":
+		return "*include: " + target + "*"
+	case "video":
+		return "[" + target + "](" + target + ")"
+	}
+	// toc::[] is left out; the viewer has its own outline.
+	return ""
+}
+
+// table converts the body of a |=== table to a pipe table, using the first
+// row as the header.
+func (c *asciidoc) table(body []string, cols int) []string {
+	var cells []string
+	for _, line := range body {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, "|")
+		if parts[0] != "" && len(cells) > 0 {
+			// Text continuing the previous cell.
+			cells[len(cells)-1] += " " + strings.TrimSpace(parts[0])
+		}
+		if cols == 0 && len(cells) == 0 {
+			cols = len(parts) - 1
+		}
+		for _, part := range parts[1:] {
+			cells = append(cells, strings.TrimSpace(part))
+		}
+	}
+	if cols <= 0 || len(cells) == 0 {
+		return nil
+	}
+	var out []string
+	for start := 0; start < len(cells); start += cols {
+		row := make([]string, cols)
+		for j := range row {
+			if start+j < len(cells) {
+				row[j] = c.inline(cells[start+j])
+			}
+		}
+		out = append(out, "| "+strings.Join(row, " | ")+" |")
+		if start == 0 {
+			out = append(out, "|"+strings.Repeat(" --- |", cols))
+		}
+	}
+	return out
+}
+
+// inline converts the inline markup of one line. Code spans are left alone.
+func (c *asciidoc) inline(text string) string {
+	text = adocPassthrough.ReplaceAllString(text, "$1`$2`$3")
+	parts := strings.Split(text, "`")
+	for i := 0; i < len(parts); i += 2 {
+		if i == len(parts)-1 && i > 0 {
+			// An unbalanced backtick; the rest is plain text.
+			parts[i] = c.inlinePlain(parts[i])
+			break
+		}
+		parts[i] = c.inlinePlain(parts[i])
+	}
+	return strings.Join(parts, "`")
+}
+
+func (c *asciidoc) inlinePlain(text string) string {
+	text = c.substitute(text)
+	text = adocInlineImage.ReplaceAllString(text, "![$2]($1)")
+	text = adocURLMacro.ReplaceAllStringFunc(text, func(match string) string {
+		m := adocURLMacro.FindStringSubmatch(match)
+		if m[2] == "" {
+			return strings.TrimPrefix(m[1], "mailto:")
+		}
+		return "[" + m[2] + "](" + m[1] + ")"
+	})
+	text = adocLinkMacro.ReplaceAllStringFunc(text, func(match string) string {
+		m := adocLinkMacro.FindStringSubmatch(match)
+		label := m[2]
+		if label == "" {
+			label = m[1]
+		}
+		return "[" + label + "](" + m[1] + ")"
+	})
+	text = adocXref.ReplaceAllStringFunc(text, func(match string) string {
+		m := adocXref.FindStringSubmatch(match)
+		target, label := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		if label == "" {
+			label = target
+		}
+		if !strings.Contains(target, "#") && !strings.Contains(target, ".") {
+			target = "#" + target
+		}
+		return "[" + label + "](" + target + ")"
+	})
+	text = adocBold.ReplaceAllString(text, "$1**$2**$3")
+	text = adocItalic.ReplaceAllString(text, "*$1*")
+	return adocHighlight.ReplaceAllString(text, "$1$2$3")
+}
+
+// substitute replaces references to defined attributes. References to
+// undefined attributes are kept, as AsciiDoc does by default.
+func (c *asciidoc) substitute(text string) string {
+	return adocAttrRef.ReplaceAllStringFunc(text, func(ref string) string {
+		if value, ok := c.attrs[ref[1:len(ref)-1]]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// parseBlockAttrs parses the contents of a block attribute line such as
+// "source,go" or "cols=\"1,2\",options=header". The block title, which may
+// precede the attribute line, is carried over.
+func parseBlockAttrs(list, title string) blockAttrs {
+	attrs := blockAttrs{title: title}
+	positional := 0
+	for _, part := range splitAttrList(list) {
+		part = strings.TrimSpace(part)
+		if key, value, ok := strings.Cut(part, "="); ok {
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch strings.TrimSpace(key) {
+			case "cols":
+				attrs.cols = countCols(value)
+			case "language":
+				attrs.lang = value
+			}
+			continue
+		}
+		switch positional {
+		case 0:
+			// Drop the #id, .role and %option shorthands.
+			if i := strings.IndexAny(part, "#.%"); i >= 0 {
+				part = part[:i]
+			}
+			attrs.style = part
+		case 1:
+			if attrs.style == "source" {
+				attrs.lang = part
+			} else {
+				attrs.attribution = part
+			}
+		}
+		positional++
+	}
+	return attrs
+}
+
+// splitAttrList splits an attribute list at commas outside quotes.
+func splitAttrList(list string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, r := range list {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			parts = append(parts, list[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, list[start:])
+}
+
+// countCols returns the number of columns a cols attribute declares, e.g. 3
+// for "3", "1,2,1" or "3*".
+func countCols(value string) int {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	count := 0
+	for _, spec := range strings.Split(value, ",") {
+		if repeat, _, ok := strings.Cut(spec, "*"); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(repeat)); err == nil {
+				count += n
+				continue
+			}
+		}
+		count++
+	}
+	return count
+}
+
+// isDelimiter reports whether line opens a delimited block.
+func isDelimiter(line string) bool {
+	if line == "--" || line == "|===" || strings.HasPrefix(line, "```") {
+		return true
+	}
+	if len(line) < 4 || !strings.ContainsRune("-.+_=*", rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// closingDelimiter returns the index of the line closing the block opened at
+// lines[open], or len(lines) when it is never closed.
+func closingDelimiter(lines []string, open int) int {
+	delimiter := strings.TrimRight(lines[open], " \t")
+	if strings.HasPrefix(delimiter, "```") {
+		delimiter = "```"
+	}
+	for i := open + 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \t") == delimiter {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// paragraphEnd returns the index of the blank line ending the paragraph
+// starting at lines[start].
+func paragraphEnd(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// dedent removes the indentation shared by lines.
+func dedent(lines []string) string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if common < 0 || indent < common {
+			common = indent
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= common && common > 0 {
+			line = line[common:]
+		}
+		out[i] = line
+	}
+	return strings.Join(out, "\n")
+}
+
+// listItem returns the indentation and marker of a list item at depth,
+// nesting it under the markers of the enclosing items.
+func listItem(widths *[]int, depth int, marker string) string {
+	if depth-1 < len(*widths) {
+		*widths = (*widths)[:depth-1]
+	}
+	indent := 0
+	for _, width := range *widths {
+		indent += width
+	}
+	// Skipped levels are nested one marker width each.
+	for len(*widths) < depth-1 {
+		*widths = append(*widths, len(marker))
+		indent += len(marker)
+	}
+	*widths = append(*widths, len(marker))
+	return strings.Repeat(" ", indent) + marker
+}
+
+// admonitionBlock shows a compound admonition as a labelled block quote.
+func admonitionBlock(label string, body []string) []string {
+	return quote(append([]string{"**" + label + ":**", ""}, body...))
+}
+
+// quote prefixes lines as a block quote.
+func quote(lines []string) []string {
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var out []string
+	for _, line := range strings.Split(strings.Join(lines, "\n"), "\n") {
+		if line == "" {
+			out = append(out, ">")
+		} else {
+			out = append(out, "> "+line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CalloutMark precedes the icon of a callout title left by Callouts. It is
+// an invisible zero-width character, like MatchStart, so the viewer can find
+// the callout in rendered output and color it.
+const CalloutMark = "⁡"
+
+type calloutKind struct {
+	name, icon string
+}
+
+var calloutKinds = map[string]calloutKind{
+	"note":      {"note", "ℹ"},
+	"abstract":  {"abstract", "≡"},
+	"todo":      {"todo", "☐"},
+	"tip":       {"tip", "★"},
+	"important": {"important", "❖"},
+	"success":   {"success", "✔"},
+	"question":  {"question", "?"},
+	"warning":   {"warning", "⚠"},
+	"caution":   {"caution", "✖"},
+	"failure":   {"failure", "✘"},
+	"danger":    {"danger", "‼"},
+	"bug":       {"bug", "✱"},
+	"example":   {"example", "▸"},
+	"quote":     {"quote", "“"},
+}
+
+// calloutAliases maps the alternative Obsidian type names to their kinds.
+var calloutAliases = map[string]string{
+	"info": "note", "summary": "abstract", "tldr": "abstract", "hint": "tip",
+	"check": "success", "done": "success", "help": "question", "faq": "question",
+	"attention": "warning", "fail": "failure", "missing": "failure", "error": "danger",
+	"cite": "quote",
+}
+
+var calloutStart = regexp.MustCompile(`^(\s{0,3}>\s?)\[!([\w-]+)\][+-]?\s*(.*)$`)
+
+// Callouts turns GitHub alerts and Obsidian callouts, block quotes opened by
+// a line such as "> [!NOTE]" or "> [!tip]- Title", into block quotes headed
+// by an icon and a bold title. Unknown types are shown as notes.
+func Callouts(src string) string {
+	if !strings.Contains(src, "[!") {
+		return src
+	}
+	lines := strings.Split(src, "\n")
+	fence := ""
+	prevQuote := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" && indentWidth(line) < 4 {
+			fence = marker
+			continue
+		}
+		quoted := strings.HasPrefix(trimmed, ">")
+		m := calloutStart.FindStringSubmatch(line)
+		if m == nil || prevQuote {
+			prevQuote = quoted
+			continue
+		}
+		prevQuote = true
+		written := strings.ToLower(m[2])
+		kind, ok := calloutKinds[written]
+		if alias, aliased := calloutAliases[written]; aliased {
+			kind, ok = calloutKinds[alias], true
+		}
+		if !ok {
+			kind = calloutKinds["note"]
+		}
+		title := strings.TrimSpace(m[3])
+		if title == "" {
+			title = strings.ToUpper(written[:1]) + written[1:]
+		}
+		lines[i] = m[1] + CalloutMark + kind.icon + " **" + title + "**"
+		if i+1 < len(lines) && strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[i+1]), ">")) != "" {
+			// Keep the body from joining the title's paragraph; the
+			// renderer ignores hard line breaks.
+			lines[i] += "\n" + strings.TrimRight(m[1], " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CalloutKind returns the kind of callout, such as "warning", whose icon is
+// icon, or "" when icon is not a callout icon.
+func CalloutKind(icon string) string {
+	for _, kind := range calloutKinds {
+		if kind.icon == icon {
+			return kind.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package markdown
+
+import "strings"
+
+// Chunks splits src into pieces of about size bytes that render like the
+// whole when rendered one by one and joined. It cuts only before ATX
+// headings outside fenced code, which always start a new top-level block,
+// and copies the link reference definitions into every piece that lacks
+// them. A document with no place to cut comes back whole.
+func Chunks(src string, size int) []string {
+	if len(src) <= size {
+		return []string{src}
+	}
+	lines := strings.Split(src, "\n")
+	var refs []string
+	fence := ""
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			continue
+		}
+		if refDefinition.MatchString(line) {
+			refs = append(refs, line)
+		}
+	}
+
+	var chunks []string
+	start, length := 0, 0
+	fence = ""
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+		} else if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+		} else if length >= size && isChunkStart(line) {
+			chunks = append(chunks, strings.Join(lines[start:i], "\n"))
+			start, length = i, 0
+		}
+		length += len(line) + 1
+	}
+	chunks = append(chunks, strings.Join(lines[start:], "\n"))
+	if len(refs) > 0 && len(chunks) > 1 {
+		defs := "\n\n" + strings.Join(refs, "\n") + "\n"
+		for i := range chunks {
+			chunks[i] += defs
+		}
+	}
+	return chunks
+}
+
+// isChunkStart reports whether line is an ATX heading at the top level.
+func isChunkStart(line string) bool {
+	n := countRun(line, '#')
+	return n >= 1 && n <= 6 && (len(line) == n || line[n] == ' ' || line[n] == '\t')
+}
@@ -0,0 +1,70 @@
+package markdown
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a document format shown by converting it to markdown first.
+type Format struct {
+	// Name is shown in conversion errors.
+	Name string
+	// Extensions lists the lower-case file extensions of the format.
+	Extensions []string
+	// Convert turns the contents of a file into markdown.
+	Convert func(data []byte) (string, error)
+}
+
+// formats lists the converted formats. Supporting another format only takes
+// an entry here.
+var formats = []Format{
+	{Name: "Jupyter Notebook", Extensions: []string{".ipynb"}, Convert: Notebook},
+	{Name: "AsciiDoc", Extensions: []string{".adoc", ".asciidoc", ".asc"}, Convert: AsciiDoc},
+	{Name: "Org", Extensions: []string{".org"}, Convert: Org},
+	{Name: "CSV", Extensions: []string{".csv"}, Convert: CSV},
+	{Name: "TSV", Extensions: []string{".tsv", ".tab"}, Convert: TSV},
+}
+
+// formatFor returns the format of the file name, if it is converted.
+func formatFor(name string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" || IsFile(name) {
+		return Format{}, false
+	}
+	for _, format := range formats {
+		for _, candidate := range format.Extensions {
+			if ext == candidate {
+				return format, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// Converted reports whether name is in a format converted to markdown before
+// it is shown.
+func Converted(name string) bool {
+	_, ok := formatFor(name)
+	return ok
+}
+
+// Viewable reports whether name is shown as a document: a markdown file, a
+// file converted to markdown or a plain text file.
+func Viewable(name string) bool {
+	return IsFile(name) || Converted(name) || IsText(name)
+}
+
+// Convert returns the markdown shown for the file name holding data. Markdown
+// files and files in other formats are returned unchanged.
+func Convert(name string, data []byte) (string, error) {
+	format, ok := formatFor(name)
+	if !ok {
+		return string(data), nil
+	}
+	out, err := format.Convert(data)
+	if err != nil {
+		return "", fmt.Errorf("%s を変換できません: %w", format.Name, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,161 @@
+package markdown
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// CSV converts comma-separated values to a markdown table whose first record
+// is the header.
+func CSV(data []byte) (string, error) {
+	return delimitedTable(string(data), ',')
+}
+
+// TSV converts tab-separated values to a markdown table whose first record
+// is the header.
+func TSV(data []byte) (string, error) {
+	return delimitedTable(string(data), '\t')
+}
+
+func delimitedTable(text string, comma rune) (string, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(text, "\ufeff")))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return "", err
+	}
+	return Table(records), nil
+}
+
+// Table lays records out as a markdown table with padded columns, using the
+// first record as the header. Columns holding only numbers are right-aligned.
+func Table(records [][]string) string {
+	if len(records) == 0 {
+		return ""
+	}
+	cols := 0
+	for _, record := range records {
+		cols = max(cols, len(record))
+	}
+	widths := make([]int, cols)
+	numeric := make([]bool, cols)
+	for j := range numeric {
+		numeric[j] = len(records) > 1
+	}
+	cells := make([][]string, len(records))
+	for i, record := range records {
+		cells[i] = make([]string, cols)
+		for j := range cols {
+			cell := ""
+			if j < len(record) {
+				cell = tableCell(record[j])
+			}
+			cells[i][j] = cell
+			widths[j] = max(widths[j], ansi.StringWidth(cell), 3)
+			if i > 0 && cell != "" {
+				if _, err := strconv.ParseFloat(strings.ReplaceAll(cell, ",", ""), 64); err != nil {
+					numeric[j] = false
+				}
+			}
+		}
+	}
+
+	var out strings.Builder
+	for i, row := range cells {
+		out.WriteString("|")
+		for j, cell := range row {
+			pad := strings.Repeat(" ", widths[j]-ansi.StringWidth(cell))
+			if numeric[j] && i > 0 {
+				out.WriteString(" " + pad + cell + " |")
+			} else {
+				out.WriteString(" " + cell + pad + " |")
+			}
+		}
+		out.WriteString("\n")
+		if i == 0 {
+			out.WriteString("|")
+			for j, width := range widths {
+				if numeric[j] {
+					out.WriteString(" " + strings.Repeat("-", width-1) + ": |")
+				} else {
+					out.WriteString(" " + strings.Repeat("-", width) + " |")
+				}
+			}
+			out.WriteString("\n")
+		}
+	}
+	return out.String()
+}
+
+// tableCell makes a value safe to place in a table cell.
+func tableCell(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// FencedTables replaces fenced code blocks tagged csv or tsv with tables.
+// The replacement spans as many lines as the block did whenever every record
+// is on a line of its own and a blank line follows the block, so source line
+// positions stay valid.
+func FencedTables(src string) string {
+	if !strings.Contains(src, "csv") && !strings.Contains(src, "tsv") {
+		return src
+	}
+	return replaceFences(src, func(lang, body string) ([]string, bool) {
+		if lang != "csv" && lang != "tsv" {
+			return nil, false
+		}
+		comma := ','
+		if lang == "tsv" {
+			comma = '\t'
+		}
+		table, err := delimitedTable(body, comma)
+		if err != nil || table == "" {
+			return nil, false
+		}
+		return strings.Split(strings.TrimSuffix(table, "\n"), "\n"), true
+	})
+}
+
+// replaceFences passes the body of every closed fenced code block to replace
+// along with its lowercased language tag. When replace accepts the block, its
+// lines take the block's place: a blank line stands in for the opening fence
+// and keeps them from joining a preceding paragraph, and another separates
+// them from text right after the block.
+func replaceFences(src string, replace func(lang, body string) ([]string, bool)) string {
+	lines := strings.Split(src, "\n")
+	out := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		body := strings.TrimRight(lines[i], "\r")
+		trimmed := strings.TrimLeft(body, " \t")
+		marker := fenceMarker(trimmed)
+		if marker == "" || indentWidth(body) >= 4 {
+			out = append(out, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !isFenceClose(strings.TrimLeft(strings.TrimRight(lines[end], "\r"), " \t"), marker) {
+			end++
+		}
+		info := strings.ToLower(strings.TrimSpace(trimmed[len(marker):]))
+		if lang, _, _ := strings.Cut(info, " "); end < len(lines) {
+			if replacement, ok := replace(lang, strings.Join(lines[i+1:end], "\n")); ok {
+				out = append(out, "")
+				out = append(out, replacement...)
+				if end+1 < len(lines) && strings.TrimSpace(lines[end+1]) != "" {
+					out = append(out, "")
+				}
+				i = end
+				continue
+			}
+		}
+		out = append(out, lines[i:min(end+1, len(lines))]...)
+		i = end
+	}
+	return strings.Join(out, "\n")
+}
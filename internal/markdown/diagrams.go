@@ -0,0 +1,153 @@
+package markdown
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyaoi/mdview/internal/mermaid"
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// diagramTimeout bounds a run of an external diagram command.
+const diagramTimeout = 10 * time.Second
+
+var errDiagramTimeout = errors.New("diagram command timed out")
+
+// diagramRenderers draw the diagrams of fenced code blocks by language tag.
+var diagramRenderers = map[string]func(string) (string, error){
+	"mermaid":  mermaid.Render,
+	"dot":      mermaid.RenderDot,
+	"graphviz": mermaid.RenderDot,
+	"plantuml": plantUML,
+	"puml":     plantUML,
+}
+
+// externalDiagrams are the languages drawn by running a command, which can
+// take seconds.
+var externalDiagrams = map[string]bool{
+	"plantuml": true,
+	"puml":     true,
+}
+
+type diagram struct {
+	text string
+	ok   bool
+}
+
+// diagramCache holds drawn diagrams, and failures, by block so that a
+// block is drawn once however often the document is rendered. A command
+// that timed out is not cached so the block is tried again.
+var (
+	diagramMu    sync.Mutex
+	diagramCache = make(map[[sha256.Size]byte]diagram)
+)
+
+// Diagram is a fenced block left for an external command to draw.
+type Diagram struct {
+	lang, body string
+}
+
+// Draw runs the command drawing d, so that later calls to FencedDiagrams and
+// CachedDiagrams show it, and reports whether it was drawn.
+func (d Diagram) Draw() bool {
+	return drawDiagram(d.lang, d.body).ok
+}
+
+// FencedDiagrams replaces fenced code blocks tagged mermaid, dot (or
+// graphviz) and plantuml (or puml) with the diagram drawn as text. Blocks that
+// cannot be drawn keep their source.
+func FencedDiagrams(src string) string {
+	out, _ := fencedDiagrams(src, true)
+	return out
+}
+
+// CachedDiagrams is FencedDiagrams for callers that cannot wait for external
+// commands: blocks they have not drawn before keep their source and are
+// returned to be drawn with Diagram.Draw.
+func CachedDiagrams(src string) (string, []Diagram) {
+	return fencedDiagrams(src, false)
+}
+
+func fencedDiagrams(src string, external bool) (string, []Diagram) {
+	if !strings.Contains(src, "mermaid") && !strings.Contains(src, "dot") &&
+		!strings.Contains(src, "graphviz") && !strings.Contains(src, "uml") {
+		return src, nil
+	}
+	var pending []Diagram
+	out := replaceFences(src, func(lang, body string) ([]string, bool) {
+		if diagramRenderers[lang] == nil {
+			return nil, false
+		}
+		d, cached := cachedDiagram(lang, body)
+		if !cached {
+			if externalDiagrams[lang] && !external {
+				pending = append(pending, Diagram{lang: lang, body: body})
+				return nil, false
+			}
+			d = drawDiagram(lang, body)
+		}
+		if !d.ok {
+			return nil, false
+		}
+		return strings.Split(strings.TrimSuffix(CodeBlock(d.text, "text"), "\n"), "\n"), true
+	})
+	return out, pending
+}
+
+func diagramKey(lang, body string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(lang + "\x00" + body))
+}
+
+func cachedDiagram(lang, body string) (diagram, bool) {
+	diagramMu.Lock()
+	defer diagramMu.Unlock()
+	d, ok := diagramCache[diagramKey(lang, body)]
+	return d, ok
+}
+
+// drawDiagram draws a block and caches the outcome.
+func drawDiagram(lang, body string) diagram {
+	text, err := diagramRenderers[lang](body)
+	d := diagram{text: text, ok: err == nil && strings.TrimSpace(text) != ""}
+	if !errors.Is(err, errDiagramTimeout) {
+		diagramMu.Lock()
+		diagramCache[diagramKey(lang, body)] = d
+		diagramMu.Unlock()
+	}
+	return d
+}
+
+// plantUML draws a diagram with the plantuml command's Unicode text output,
+// which is available for sequence diagrams.
+func plantUML(body string) (string, error) {
+	if !strings.Contains(body, "@start") {
+		body = "@startuml\n" + body + "\n@enduml\n"
+	}
+	cmd, err := safemode.Command("plantuml", "-tutxt", "-pipe")
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	timer := time.AfterFunc(diagramTimeout, func() { cmd.Process.Kill() })
+	if err := cmd.Wait(); err != nil {
+		if !timer.Stop() {
+			return "", errDiagramTimeout
+		}
+		return "", err
+	}
+	timer.Stop()
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/linediff"
+)
+
+// DiffInsertStart/DiffInsertEnd and DiffDeleteStart/DiffDeleteEnd delimit the
+// text of lines MarkDiff reports as added or removed. Like MatchStart they
+// are invisible zero-width characters that survive rendering.
+const (
+	DiffInsertStart = "⁫"
+	DiffInsertEnd   = "⁬"
+	DiffDeleteStart = "⁭"
+	DiffDeleteEnd   = "⁮"
+)
+
+// diffLinePrefix matches the block syntax opening a line, which the markers
+// must follow for the line to keep parsing as the same block.
+var diffLinePrefix = regexp.MustCompile(`^[ \t]*(?:>[ \t]?)*[ \t]*(?:\[![A-Za-z]+\][-+]?[ \t]*|[-*+][ \t]+(?:\[[ xX]\][ \t]+)?|\d{1,9}[.)][ \t]+|#{1,6}[ \t]+|\[\^[^\]\s]+\]:[ \t]*|\|)?`)
+
+// MarkDiff joins the lines of a line diff into one document, deleted lines
+// included, with the text of every added and deleted line wrapped in the
+// diff markers. Lines without text of their own, such as blank lines, fence
+// lines, thematic breaks and reference definitions, are left unmarked.
+func MarkDiff(script []linediff.Line) string {
+	lines := make([]string, len(script))
+	for i, line := range script {
+		lines[i] = line.Text
+	}
+	start := frontMatterLines(lines)
+	fence := ""
+	for i, line := range script {
+		trimmed := strings.TrimLeft(line.Text, " \t")
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+				continue
+			}
+		} else if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			continue
+		}
+		if line.Kind == linediff.Equal || i < start || !diffMarkable(trimmed) {
+			continue
+		}
+		open, close := DiffInsertStart, DiffInsertEnd
+		if line.Kind == linediff.Delete {
+			open, close = DiffDeleteStart, DiffDeleteEnd
+		}
+		prefix := line.Text[:len(line.Text)-len(trimmed)]
+		if fence == "" {
+			prefix = diffLinePrefix.FindString(line.Text)
+		}
+		body := strings.TrimRight(line.Text[len(prefix):], " \t\r|")
+		if body == "" {
+			continue
+		}
+		lines[i] = prefix + open + body + close + line.Text[len(prefix)+len(body):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffMarkable reports whether a trimmed line holds text to mark.
+func diffMarkable(trimmed string) bool {
+	if trimmed == "" || refDefinition.MatchString(trimmed) {
+		return false
+	}
+	// Thematic breaks, setext underlines and table delimiter rows.
+	return strings.Trim(trimmed, "-=*_:| \t") != ""
+}
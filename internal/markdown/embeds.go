@@ -0,0 +1,117 @@
+package markdown
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// EmbedDepthLimit is how deeply embedded notes may embed further notes.
+const EmbedDepthLimit = 4
+
+var embedLine = regexp.MustCompile(`^\s{0,3}!\[\[([^\[\]|#]+)(?:#([^\[\]|]*))?(?:\|([^\[\]]*))?\]\]\s*$`)
+
+// embedImageExts lists the embed targets shown as images.
+var embedImageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".bmp": true,
+}
+
+// Embeds inlines the notes embedded with Obsidian's ![[note]] syntax, each on
+// a line of its own, so that a composite note reads as one document.
+// ![[note#Heading]] inlines only that heading's section, and images embedded
+// this way become markdown images. load returns the markdown of the note a
+// target names and a key identifying it, such as its path; key identifies
+// src the same way. Embeds nest up to EmbedDepthLimit deep; an embed that
+// would exceed the limit or repeat a note it is inside of is shown as a
+// wikilink with a note instead.
+func Embeds(src, key string, load func(target string) (key, content string, ok bool)) string {
+	if !strings.Contains(src, "![[") {
+		return src
+	}
+	return embed(src, load, []string{key})
+}
+
+func embed(src string, load func(string) (string, string, bool), stack []string) string {
+	lines := strings.Split(src, "\n")
+	out := make([]string, 0, len(lines))
+	fence := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+			out = append(out, line)
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" && indentWidth(line) < 4 {
+			fence = marker
+			out = append(out, line)
+			continue
+		}
+		m := embedLine.FindStringSubmatch(line)
+		if m == nil {
+			out = append(out, line)
+			continue
+		}
+		target, heading := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		if embedImageExts[strings.ToLower(path.Ext(target))] {
+			alt := strings.TrimSpace(m[3])
+			if alt == "" {
+				alt = path.Base(target)
+			}
+			out = append(out, "!["+alt+"](<"+target+">)")
+			continue
+		}
+		link := strings.TrimPrefix(strings.TrimSpace(line), "!")
+		key, content, ok := load(target)
+		switch {
+		case !ok:
+			out = append(out, link+" *(埋め込み先が見つかりません)*")
+			continue
+		case len(stack) > EmbedDepthLimit:
+			out = append(out, link+" *(埋め込みが深すぎるため省略)*")
+			continue
+		case key == "" || slices.Contains(stack, key):
+			out = append(out, link+" *(循環する埋め込みのため省略)*")
+			continue
+		}
+		_, body := SplitFrontMatter(content)
+		if heading != "" {
+			section, found := Section(body, heading)
+			if !found {
+				out = append(out, link+" *(見出しが見つかりません)*")
+				continue
+			}
+			body = section
+		}
+		body = embed(strings.Trim(body, "\n"), load, append(stack, key))
+		ensureBlank(&out)
+		out = append(out, strings.Split(body, "\n")...)
+		out = append(out, "")
+	}
+	return strings.Join(out, "\n")
+}
+
+// Section returns the part of src from the heading whose text matches
+// heading, ignoring case, up to the next heading of the same or a higher
+// level.
+func Section(src, heading string) (string, bool) {
+	headings := Headings(src)
+	for i, h := range headings {
+		if !strings.EqualFold(h.Text, heading) {
+			continue
+		}
+		lines := strings.Split(src, "\n")
+		end := len(lines)
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line
+				break
+			}
+		}
+		return strings.Join(lines[h.Line:end], "\n"), true
+	}
+	return "", false
+}
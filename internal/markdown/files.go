@@ -0,0 +1,107 @@
+package markdown
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultExtensions are the file extensions treated as markdown unless
+// configured otherwise.
+var DefaultExtensions = []string{".md", ".markdown", ".mdx"}
+
+var (
+	extMu      sync.RWMutex
+	extensions = DefaultExtensions
+)
+
+// SetExtensions replaces the extensions treated as markdown. Entries may be
+// given with or without the leading dot; an empty list keeps the defaults.
+func SetExtensions(exts []string) {
+	var normalized []string
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized = append(normalized, ext)
+	}
+	if len(normalized) == 0 {
+		normalized = DefaultExtensions
+	}
+	extMu.Lock()
+	defer extMu.Unlock()
+	extensions = normalized
+}
+
+// Extensions returns the extensions treated as markdown.
+func Extensions() []string {
+	extMu.RLock()
+	defer extMu.RUnlock()
+	return append([]string(nil), extensions...)
+}
+
+// IsFile reports whether name has one of the markdown extensions.
+func IsFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return false
+	}
+	extMu.RLock()
+	defer extMu.RUnlock()
+	for _, candidate := range extensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTextFiles are the files shown as wrapped plain text unless
+// configured otherwise: entries starting with a dot are extensions, the
+// others whole file names.
+var DefaultTextFiles = []string{".txt", ".text", "LICENSE", "LICENCE", "COPYING", "NOTICE", "AUTHORS", "README", "CHANGELOG"}
+
+var (
+	textMu    sync.RWMutex
+	textFiles = DefaultTextFiles
+)
+
+// SetTextFiles replaces the files shown as plain text. An empty list keeps
+// the defaults.
+func SetTextFiles(names []string) {
+	var normalized []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			normalized = append(normalized, name)
+		}
+	}
+	if len(normalized) == 0 {
+		normalized = DefaultTextFiles
+	}
+	textMu.Lock()
+	defer textMu.Unlock()
+	textFiles = normalized
+}
+
+// IsText reports whether name is shown as plain text, by its extension or,
+// for files such as LICENSE, its whole name. Both compare case-insensitively.
+func IsText(name string) bool {
+	base := filepath.Base(name)
+	ext := filepath.Ext(base)
+	textMu.RLock()
+	defer textMu.RUnlock()
+	for _, candidate := range textFiles {
+		if strings.HasPrefix(candidate, ".") {
+			if ext != "" && strings.EqualFold(ext, candidate) {
+				return true
+			}
+		} else if strings.EqualFold(base, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Footnote is a footnote definition, numbered in order of first reference.
+type Footnote struct {
+	Label  string
+	Number int
+	// Text is the definition as written, its lines joined by spaces.
+	Text string
+}
+
+// FootnoteSpot is a footnote reference or, with Definition set, the start of
+// a footnote's text, in the order Footnotes leaves them in the document.
+type FootnoteSpot struct {
+	Number     int
+	Definition bool
+}
+
+// FootnoteMark precedes every footnote reference and definition left by
+// Footnotes. It is an invisible zero-width character, like MatchStart, so
+// the viewer can find them in rendered output.
+const FootnoteMark = "⁠"
+
+var (
+	footnoteRefPattern = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+	footnoteDefPattern = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]*(.*)$`)
+)
+
+// Footnotes turns [^label] references into superscript numbers and gathers
+// the [^label]: definitions into a numbered list at the end of src, as
+// GitHub does. Definition lines are blanked rather than removed so the
+// result keeps the line numbering of src. References without a definition
+// and definitions never referenced are left out of the numbering.
+func Footnotes(src string) (string, []Footnote, []FootnoteSpot) {
+	if !strings.Contains(src, "[^") {
+		return src, nil, nil
+	}
+	lines := strings.Split(src, "\n")
+	defs := footnoteDefinitions(lines)
+	if len(defs) == 0 {
+		return src, nil, nil
+	}
+
+	var notes []Footnote
+	var spots []FootnoteSpot
+	numbers := make(map[string]int)
+	refs := func(seg string) string {
+		return footnoteRefPattern.ReplaceAllStringFunc(seg, func(match string) string {
+			label := strings.ToLower(match[2 : len(match)-1])
+			def, ok := defs[label]
+			if !ok {
+				return match
+			}
+			n, ok := numbers[label]
+			if !ok {
+				n = len(notes) + 1
+				numbers[label] = n
+				text := strings.Join(strings.Fields(strings.Join(def, " ")), " ")
+				notes = append(notes, Footnote{Label: label, Number: n, Text: text})
+			}
+			spots = append(spots, FootnoteSpot{Number: n})
+			return "[" + FootnoteMark + superscript(n) + "](#)"
+		})
+	}
+	body := MapProse(strings.Join(lines, "\n"), refs)
+	if len(notes) == 0 {
+		return src, nil, nil
+	}
+
+	var out strings.Builder
+	out.WriteString(strings.TrimRight(body, "\n"))
+	out.WriteString("\n\n---\n\n")
+	// Definitions may reference footnotes not numbered yet, which grows notes.
+	for i := 0; i < len(notes); i++ {
+		n := notes[i].Number
+		spots = append(spots, FootnoteSpot{Number: n, Definition: true})
+		marker := strconv.Itoa(n) + ". "
+		indent := strings.Repeat(" ", len(marker))
+		for j, line := range strings.Split(MapProse(strings.Join(defs[notes[i].Label], "\n"), refs), "\n") {
+			switch {
+			case j == 0:
+				out.WriteString(marker + FootnoteMark + line)
+			case strings.TrimSpace(line) != "":
+				out.WriteString(indent + line)
+			}
+			out.WriteString("\n")
+		}
+	}
+	return out.String(), notes, spots
+}
+
+// footnoteDefinitions removes the footnote definitions outside code from
+// lines, blanking them in place, and returns their lines by lowercased
+// label with the continuation indent stripped. The first definition of a
+// label wins.
+func footnoteDefinitions(lines []string) map[string][]string {
+	defs := make(map[string][]string)
+	start := frontMatterLines(lines)
+	fence := ""
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " \t")
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" && indentWidth(lines[i]) < 4 {
+			fence = marker
+			continue
+		}
+		m := footnoteDefPattern.FindStringSubmatch(strings.TrimRight(lines[i], "\r"))
+		if m == nil {
+			continue
+		}
+		body := []string{m[2]}
+		lines[i] = ""
+		// Indented lines continue the definition, across blank lines too;
+		// plain text right after a line of it continues it lazily.
+	continuation:
+		for i+1 < len(lines) {
+			next := strings.TrimRight(lines[i+1], "\r")
+			switch {
+			case indentWidth(next) >= 4 && strings.TrimSpace(next) != "":
+				body = append(body, strings.TrimPrefix(strings.TrimPrefix(next, "\t"), "    "))
+			case strings.TrimSpace(next) == "" && followedByIndent(lines, i+1):
+				body = append(body, "")
+			case strings.TrimSpace(next) != "" && strings.TrimSpace(body[len(body)-1]) != "" && lazyLine(next):
+				body = append(body, strings.TrimSpace(next))
+			default:
+				break continuation
+			}
+			lines[i+1] = ""
+			i++
+		}
+		label := strings.ToLower(m[1])
+		if _, taken := defs[label]; !taken {
+			defs[label] = body
+		}
+	}
+	return defs
+}
+
+// followedByIndent reports whether the blank lines from i on end in an
+// indented line, continuing a footnote definition.
+func followedByIndent(lines []string, i int) bool {
+	for ; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			return indentWidth(lines[i]) >= 4
+		}
+	}
+	return false
+}
+
+// lazyLine reports whether line may continue a paragraph without indent,
+// that is it starts no other block.
+func lazyLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if footnoteDefPattern.MatchString(line) || fenceMarker(trimmed) != "" {
+		return false
+	}
+	switch trimmed[0] {
+	case '#', '>', '-', '*', '+', '|':
+		return false
+	}
+	return true
+}
+
+// superscript writes n in superscript digits.
+func superscript(n int) string {
+	var b strings.Builder
+	for _, r := range strconv.Itoa(n) {
+		b.WriteRune(superscripts[r])
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Heading describes an ATX or setext heading found in a markdown source.
+type Heading struct {
+	Level int
+	// Text is the heading content with inline markup removed.
+	Text string
+	// Line is the zero-based source line of the heading.
+	Line int
+}
+
+var (
+	linkPattern      = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	refLinkPattern   = regexp.MustCompile(`!?\[([^\]]*)\]\[[^\]]*\]`)
+	emphasisReplacer = strings.NewReplacer("**", "", "__", "", "*", "", "`", "", "~~", "")
+)
+
+// Headings lists the headings of src in document order, skipping front matter
+// and code blocks.
+func Headings(src string) []Heading {
+	lines := strings.Split(src, "\n")
+	var headings []Heading
+	fence := ""
+	start := frontMatterLines(strings.SplitAfter(src, "\n"))
+	prevBlank := true
+	for i := start; i < len(lines); i++ {
+		body := strings.TrimRight(lines[i], "\r")
+		trimmed := strings.TrimLeft(body, " \t")
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" && indentWidth(body) < 4 {
+			fence = marker
+			continue
+		}
+		if indentWidth(body) >= 4 {
+			prevBlank = false
+			continue
+		}
+		if level, text, ok := atxHeading(trimmed); ok {
+			headings = append(headings, Heading{Level: level, Text: PlainText(text), Line: i})
+			prevBlank = false
+			continue
+		}
+		if !prevBlank && i > start && trimmed != "" {
+			if level := setextLevel(strings.TrimSpace(trimmed)); level > 0 {
+				prev := strings.TrimSpace(lines[i-1])
+				if prev != "" && !strings.HasPrefix(prev, "#") {
+					headings = append(headings, Heading{Level: level, Text: PlainText(prev), Line: i - 1})
+				}
+			}
+		}
+		prevBlank = trimmed == ""
+	}
+	return headings
+}
+
+// PlainText strips common inline markup (emphasis, code spans, links) from a
+// single line of markdown.
+func PlainText(text string) string {
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = refLinkPattern.ReplaceAllString(text, "$1")
+	text = emphasisReplacer.Replace(text)
+	return strings.TrimSpace(text)
+}
+
+func atxHeading(trimmed string) (int, string, bool) {
+	level := countRun(trimmed, '#')
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if closing := strings.TrimRight(rest, "#"); closing != rest {
+		if closing == "" || strings.HasSuffix(closing, " ") {
+			rest = strings.TrimSpace(closing)
+		}
+	}
+	return level, rest, true
+}
+
+func setextLevel(trimmed string) int {
+	switch {
+	case strings.Trim(trimmed, "=") == "":
+		return 1
+	case strings.Trim(trimmed, "-") == "" && len(trimmed) >= 2:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MatchStart and MatchEnd delimit search matches inserted by MarkMatches. Both
+// are invisible zero-width characters, so the renderer lays out text exactly
+// as if they were absent and they survive wrapping and styling untouched.
+const (
+	MatchStart = "⁣"
+	MatchEnd   = "⁤"
+)
+
+// MatchOptions narrow down what MarkMatches counts as a match.
+type MatchOptions struct {
+	// CaseSensitive tells upper from lower case.
+	CaseSensitive bool
+	// WholeWord only accepts matches not preceded or followed by a letter,
+	// digit or underscore where the query starts or ends with one.
+	WholeWord bool
+}
+
+// MarkMatches wraps every occurrence of query in src with
+// MatchStart/MatchEnd. Runs of whitespace compare equal to a single space so
+// matches may continue across soft line breaks. Unless literal is set,
+// emphasis and code span delimiters are ignored while matching, which lets a
+// query span styled text such as "**bold** word"; the markers are then placed
+// inside the delimiters so the markup keeps parsing.
+func MarkMatches(src, query string, literal bool, opts MatchOptions) string {
+	fold := unicode.ToLower
+	if opts.CaseSensitive {
+		fold = func(r rune) rune { return r }
+	}
+	needle := foldQuery(query, fold)
+	if len(needle) == 0 || src == "" {
+		return src
+	}
+
+	type projected struct {
+		r     rune
+		start int
+		end   int
+	}
+	var text []projected
+	lastSpace := false
+	for i := 0; i < len(src); {
+		if i == 0 || src[i-1] == '\n' {
+			// Fence lines act as barriers so no marker lands in an info string.
+			line := src[i:]
+			if end := strings.IndexByte(line, '\n'); end >= 0 {
+				line = line[:end]
+			}
+			if fenceMarker(strings.TrimLeft(line, " \t")) != "" {
+				text = append(text, projected{r: '\n', start: i, end: i + len(line)})
+				i += len(line)
+				lastSpace = false
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case !literal && (r == '*' || r == '`'):
+		case unicode.IsSpace(r):
+			if !lastSpace {
+				text = append(text, projected{r: ' ', start: i, end: i + size})
+			}
+			lastSpace = true
+		default:
+			text = append(text, projected{r: fold(r), start: i, end: i + size})
+			lastSpace = false
+		}
+		i += size
+	}
+
+	var out strings.Builder
+	out.Grow(len(src) + 16)
+	copied := 0
+	for i := 0; i+len(needle) <= len(text); {
+		matched := true
+		for j, r := range needle {
+			if text[i+j].r != r {
+				matched = false
+				break
+			}
+		}
+		if matched && opts.WholeWord {
+			end := i + len(needle)
+			matched = (i == 0 || !isWordRune(needle[0]) || !isWordRune(text[i-1].r)) &&
+				(end == len(text) || !isWordRune(needle[len(needle)-1]) || !isWordRune(text[end].r))
+		}
+		if !matched {
+			i++
+			continue
+		}
+		first, last := text[i], text[i+len(needle)-1]
+		out.WriteString(src[copied:first.start])
+		out.WriteString(MatchStart)
+		out.WriteString(src[first.start:last.end])
+		out.WriteString(MatchEnd)
+		copied = last.end
+		i += len(needle)
+	}
+	out.WriteString(src[copied:])
+	return out.String()
+}
+
+// isWordRune reports whether r can be part of a word for whole word matching.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func foldQuery(query string, fold func(rune) rune) []rune {
+	fields := strings.Fields(query)
+	var needle []rune
+	for i, field := range fields {
+		if i > 0 {
+			needle = append(needle, ' ')
+		}
+		for _, r := range field {
+			needle = append(needle, fold(r))
+		}
+	}
+	return needle
+}
@@ -0,0 +1,60 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Image is an image referenced by a document.
+type Image struct {
+	Alt    string
+	Target string
+	// Width and Height are zero when the dimensions are unknown.
+	Width, Height int
+}
+
+// ImageLabel starts every image placeholder, so that placeholders can be
+// found again in rendered output.
+const ImageLabel = "[画像"
+
+var imagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^\s)>]+)>?(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+
+// ImagePlaceholders replaces the images in the prose of src with a code span
+// naming the alt text, the target and, when size knows them, the
+// dimensions, for terminals that cannot show images. size may be nil. The
+// images are returned in document order.
+func ImagePlaceholders(src string, size func(target string) (width, height int, ok bool)) (string, []Image) {
+	if !strings.Contains(src, "![") {
+		return src, nil
+	}
+	var images []Image
+	out := MapProse(src, func(seg string) string {
+		return imagePattern.ReplaceAllStringFunc(seg, func(match string) string {
+			m := imagePattern.FindStringSubmatch(match)
+			img := Image{Alt: strings.TrimSpace(m[1]), Target: m[2]}
+			if size != nil {
+				if w, h, ok := size(img.Target); ok {
+					img.Width, img.Height = w, h
+				}
+			}
+			images = append(images, img)
+			return placeholder(img)
+		})
+	})
+	return out, images
+}
+
+// placeholder formats img as an inline code span.
+func placeholder(img Image) string {
+	label := ImageLabel
+	if img.Width > 0 && img.Height > 0 {
+		label += fmt.Sprintf(" %d×%d", img.Width, img.Height)
+	}
+	label += "]"
+	if img.Alt != "" {
+		label += " " + img.Alt + " —"
+	}
+	label += " " + img.Target
+	return "`" + strings.ReplaceAll(label, "`", "'") + "`"
+}
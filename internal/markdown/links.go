@@ -0,0 +1,188 @@
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Link is a link found in a document's prose.
+type Link struct {
+	// Target is the destination as written, without its #fragment, which is
+	// kept in Fragment.
+	Target, Fragment string
+	// Label is the link text as written.
+	Label string
+	// Line is the zero-based source line of the link.
+	Line int
+	// Wiki marks [[wikilinks]] and ![[embeds]].
+	Wiki bool
+}
+
+// LinkMark precedes the label of every link left by MarkLinks. It is an
+// invisible zero-width character, like MatchStart, so the viewer can find
+// the links in rendered output.
+const LinkMark = "⁪"
+
+var (
+	// inlineLink allows an image in the label, as in linked badges.
+	inlineLink = regexp.MustCompile(`(!?)\[((?:!\[[^\]]*\]\([^)]*\)|[^\]])*)\]\(\s*<?([^\s)>]*)>?(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	// refLink matches [label][ref], [label][] and [label]; only those with
+	// a definition are links.
+	refLink       = regexp.MustCompile(`(!?)\[([^\[\]^][^\[\]]*)\](?:\[([^\[\]]*)\])?`)
+	refDefinition = regexp.MustCompile(`^ {0,3}\[([^\[\]^][^\[\]]*)\]:\s*<?([^\s>]+)>?`)
+)
+
+// newLink splits a destination into a Link.
+func newLink(dest, label string, line int) Link {
+	target, fragment, _ := strings.Cut(dest, "#")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	return Link{Target: target, Fragment: fragment, Label: label, Line: line}
+}
+
+// Links lists the links of src in document order, leaving out images and
+// anything in front matter, code blocks and code spans.
+func Links(src string) []Link {
+	if !strings.Contains(src, "](") && !strings.Contains(src, "[[") {
+		return nil
+	}
+	var links []Link
+	mapProseLines(src, func(line int, seg string) string {
+		for _, m := range inlineLink.FindAllStringSubmatch(seg, -1) {
+			if m[1] == "!" || m[3] == "" {
+				continue
+			}
+			links = append(links, newLink(m[3], m[2], line))
+		}
+		for _, m := range wikiLinkPattern.FindAllStringSubmatch(seg, -1) {
+			links = append(links, Link{
+				Target: strings.TrimSpace(m[2]), Fragment: strings.TrimSpace(m[3]),
+				Label: strings.TrimSpace(m[4]), Line: line, Wiki: true,
+			})
+		}
+		return seg
+	})
+	return links
+}
+
+// MarkLinks puts LinkMark at the start of the label of every inline and
+// reference link in the prose of src and returns them in document order,
+// reference links with the destination of their definition. Images and
+// [[wikilinks]] are left alone.
+func MarkLinks(src string) (string, []Link) {
+	if !strings.Contains(src, "[") {
+		return src, nil
+	}
+	defs := referenceDefinitions(src)
+	var links []Link
+	out := mapProseLines(src, func(line int, seg string) string {
+		if refDefinition.MatchString(seg) {
+			return seg
+		}
+		var b strings.Builder
+		for seg != "" {
+			inline := inlineLink.FindStringSubmatchIndex(seg)
+			ref := refLink.FindStringSubmatchIndex(seg)
+			if ref != nil && (inline == nil || ref[0] < inline[0]) {
+				m := refLink.FindStringSubmatch(seg[ref[0]:ref[1]])
+				key := m[3]
+				if key == "" {
+					key = m[2]
+				}
+				dest, ok := defs[referenceKey(key)]
+				wiki := ref[0] > 0 && seg[ref[0]-1] == '[' || ref[1] < len(seg) && seg[ref[1]] == ']'
+				if m[1] == "!" || !ok || wiki {
+					// Not a link: step over the bracket and look again.
+					b.WriteString(seg[:ref[0]+1])
+					seg = seg[ref[0]+1:]
+					continue
+				}
+				links = append(links, newLink(dest, m[2], line))
+				b.WriteString(seg[:ref[4]] + LinkMark + seg[ref[4]:ref[1]])
+				seg = seg[ref[1]:]
+				continue
+			}
+			if inline == nil {
+				b.WriteString(seg)
+				break
+			}
+			label := inline[4]
+			if seg[inline[2]:inline[3]] != "!" && inline[6] != inline[7] {
+				links = append(links, newLink(seg[inline[6]:inline[7]], seg[inline[4]:inline[5]], line))
+				b.WriteString(seg[:label] + LinkMark + seg[label:inline[1]])
+			} else {
+				b.WriteString(seg[:inline[1]])
+			}
+			seg = seg[inline[1]:]
+		}
+		return b.String()
+	})
+	return out, links
+}
+
+// referenceDefinitions maps the normalized labels of the [label]: url
+// definitions in the prose of src to their destinations.
+func referenceDefinitions(src string) map[string]string {
+	defs := make(map[string]string)
+	if !strings.Contains(src, "]:") {
+		return defs
+	}
+	mapProseLines(src, func(_ int, seg string) string {
+		if m := refDefinition.FindStringSubmatch(seg); m != nil {
+			if key := referenceKey(m[1]); defs[key] == "" {
+				defs[key] = m[2]
+			}
+		}
+		return seg
+	})
+	return defs
+}
+
+// referenceKey normalizes a reference label the way CommonMark matches
+// them: case-insensitively with runs of whitespace collapsed.
+func referenceKey(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// IsURL reports whether a link target points outside the local file system,
+// such as https: or mailto: targets.
+func IsURL(target string) bool {
+	if strings.Contains(target, "://") {
+		return true
+	}
+	scheme, _, ok := strings.Cut(target, ":")
+	return ok && len(scheme) > 1 && !strings.ContainsAny(scheme, "/\\.")
+}
+
+// RewriteDestinations replaces the destination of every inline link, image
+// and reference definition in the prose of src with fn's result, leaving
+// [[wikilinks]] alone. Images nested in link labels, as in linked badges,
+// are rewritten too.
+func RewriteDestinations(src string, fn func(dest string, image bool) string) string {
+	if !strings.Contains(src, "](") && !strings.Contains(src, "]:") {
+		return src
+	}
+	var rewrite func(seg string) string
+	rewrite = func(seg string) string {
+		return inlineLink.ReplaceAllStringFunc(seg, func(match string) string {
+			m := inlineLink.FindStringSubmatchIndex(match)
+			label := match[m[4]:m[5]]
+			if strings.Contains(label, "](") {
+				label = rewrite(label)
+			}
+			dest := match[m[6]:m[7]]
+			if dest != "" {
+				dest = fn(dest, match[m[2]:m[3]] == "!")
+			}
+			return match[:m[4]] + label + match[m[5]:m[6]] + dest + match[m[7]:]
+		})
+	}
+	return mapProseLines(src, func(_ int, seg string) string {
+		if m := refDefinition.FindStringSubmatchIndex(seg); m != nil {
+			return seg[:m[4]] + fn(seg[m[4]:m[5]], false) + seg[m[5]:]
+		}
+		return rewrite(seg)
+	})
+}
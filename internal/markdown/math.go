@@ -0,0 +1,153 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Math converts LaTeX math to Unicode text with TeX. Display math, written
+// between $$ lines or in a fenced block tagged math, becomes a code block so
+// that its line breaks and spacing survive; inline $...$ math is converted in
+// place. A $ followed by a space or closed by one preceded by a space is not
+// math, so prices such as $5 and $10 are left alone.
+func Math(src string) string {
+	if !strings.Contains(src, "$") && !strings.Contains(src, "math") {
+		return src
+	}
+	src = replaceFences(src, func(lang, body string) ([]string, bool) {
+		if lang != "math" {
+			return nil, false
+		}
+		return mathBlock(body), true
+	})
+	return MapProse(displayMath(src), inlineMath)
+}
+
+// displayMath replaces $$ blocks outside code with code blocks. A block
+// spans the same number of lines as its source unless it sits on one line.
+func displayMath(src string) string {
+	if !strings.Contains(src, "$$") {
+		return src
+	}
+	lines := strings.Split(src, "\n")
+	out := make([]string, 0, len(lines))
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if fence != "" {
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+			out = append(out, lines[i])
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			out = append(out, lines[i])
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "$$") {
+			out = append(out, lines[i])
+			continue
+		}
+		if rest := trimmed[2:]; strings.HasSuffix(rest, "$$") {
+			if expr := strings.TrimSuffix(rest, "$$"); strings.TrimSpace(expr) != "" {
+				out = append(out, mathBlock(expr)...)
+				continue
+			}
+		}
+		end := i + 1
+		for end < len(lines) && !strings.HasSuffix(strings.TrimSpace(lines[end]), "$$") {
+			end++
+		}
+		if end == len(lines) {
+			out = append(out, lines[i])
+			continue
+		}
+		body := append([]string{trimmed[2:]}, lines[i+1:end]...)
+		body = append(body, strings.TrimSuffix(strings.TrimSpace(lines[end]), "$$"))
+		block := mathBlock(strings.Join(body, "\n"))
+		// Blank lines pad the block to the source's length, so later lines
+		// keep their positions.
+		for len(block) < end-i+1 {
+			block = append(block, "")
+		}
+		out = append(out, block...)
+		i = end
+	}
+	return strings.Join(out, "\n")
+}
+
+// mathBlock returns the lines of a code block holding expr converted.
+func mathBlock(expr string) []string {
+	return strings.Split(strings.TrimSuffix(CodeBlock(TeX(expr), "text"), "\n"), "\n")
+}
+
+// inlineMath converts the $...$ spans of a run of prose.
+func inlineMath(seg string) string {
+	if !strings.Contains(seg, "$") {
+		return seg
+	}
+	var out strings.Builder
+	for i := 0; i < len(seg); i++ {
+		switch {
+		case seg[i] == '\\' && i+1 < len(seg):
+			out.WriteString(seg[i : i+2])
+			i++
+			continue
+		case seg[i] != '$':
+			out.WriteByte(seg[i])
+			continue
+		}
+		delim := "$"
+		if strings.HasPrefix(seg[i:], "$$") {
+			delim = "$$"
+		}
+		start := i + len(delim)
+		end := closingDollar(seg, start, delim)
+		if end < 0 {
+			out.WriteString(delim)
+			i = start - 1
+			continue
+		}
+		out.WriteString(escapeProse(strings.ReplaceAll(TeX(seg[start:end]), "\n", "; ")))
+		i = end + len(delim) - 1
+	}
+	return out.String()
+}
+
+// closingDollar returns the index of the delimiter closing math that starts
+// at start, or -1. Inline math must not start or end with a space, and a
+// closing $ must not be followed by a digit.
+func closingDollar(seg string, start int, delim string) int {
+	if start >= len(seg) || seg[start] == ' ' || seg[start] == '$' {
+		return -1
+	}
+	for j := start + 1; j < len(seg); j++ {
+		switch {
+		case seg[j] == '\\':
+			j++
+		case strings.HasPrefix(seg[j:], delim):
+			if seg[j-1] == ' ' {
+				return -1
+			}
+			if next := j + len(delim); delim == "$" && next < len(seg) && (unicode.IsDigit(rune(seg[next])) || seg[next] == '$') {
+				return -1
+			}
+			return j
+		}
+	}
+	return -1
+}
+
+// escapeProse escapes the characters markdown would read as inline markup.
+func escapeProse(s string) string {
+	var out strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>|", r) {
+			out.WriteByte('\\')
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
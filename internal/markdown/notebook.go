@@ -0,0 +1,138 @@
+package markdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// notebook is the part of the Jupyter notebook format (nbformat 4) that is
+// shown.
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   notebookText     `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string                  `json:"output_type"`
+	Text       notebookText            `json:"text"`
+	Data       map[string]notebookText `json:"data"`
+	Ename      string                  `json:"ename"`
+	Evalue     string                  `json:"evalue"`
+	Traceback  []string                `json:"traceback"`
+}
+
+// notebookText is a multiline string, which notebooks store either as one
+// string or as a list of lines.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		// Binary outputs such as JSON data are not shown.
+		return nil
+	}
+	*t = notebookText(text)
+	return nil
+}
+
+// Notebook converts a Jupyter notebook to markdown: markdown cells are kept
+// verbatim, code cells become fenced blocks in the kernel language followed
+// by their text outputs, and rich outputs are noted by their type.
+func Notebook(data []byte) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", err
+	}
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.Kernelspec.Language
+	}
+	var parts []string
+	for _, cell := range nb.Cells {
+		source := strings.TrimRight(string(cell.Source), "\n")
+		switch cell.CellType {
+		case "markdown":
+			if source != "" {
+				parts = append(parts, source+"\n")
+			}
+		case "code":
+			parts = append(parts, CodeBlock(source, lang))
+			for _, output := range cell.Outputs {
+				if out := notebookOutputText(output); out != "" {
+					parts = append(parts, out)
+				}
+			}
+		default:
+			// Raw cells are passed to converters untouched; show them as text.
+			if source != "" {
+				parts = append(parts, CodeBlock(source, "text"))
+			}
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// notebookOutputText renders one output of a code cell.
+func notebookOutputText(output notebookOutput) string {
+	switch output.OutputType {
+	case "stream":
+		return outputBlock(string(output.Text))
+	case "error":
+		text := strings.Join(output.Traceback, "\n")
+		if text == "" {
+			text = output.Ename + ": " + output.Evalue
+		}
+		return outputBlock(ansi.Strip(text))
+	case "execute_result", "display_data":
+		if text, ok := output.Data["text/markdown"]; ok {
+			return strings.TrimRight(string(text), "\n") + "\n"
+		}
+		// Plots carry a text/plain placeholder such as "<Figure ...>", which
+		// says less than naming the image.
+		var images []string
+		for mime := range output.Data {
+			if strings.HasPrefix(mime, "image/") {
+				images = append(images, mime)
+			}
+		}
+		if len(images) > 0 {
+			sort.Strings(images)
+			return fmt.Sprintf("*[画像出力: %s]*\n", strings.Join(images, ", "))
+		}
+		if text, ok := output.Data["text/plain"]; ok {
+			return outputBlock(string(text))
+		}
+	}
+	return ""
+}
+
+// outputBlock shows cell output as an untagged code block.
+func outputBlock(text string) string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return ""
+	}
+	return CodeBlock(text, "text")
+}
@@ -0,0 +1,210 @@
+package markdown
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Org converts the commonly used subset of Org mode to markdown: headings,
+// paragraphs and inline markup, lists, source and example blocks, tables and
+// links. Keyword lines other than the title, comments and property drawers
+// are dropped.
+func Org(data []byte) (string, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	out := convertOrg(strings.Split(text, "\n"))
+	return strings.TrimSpace(strings.Join(out, "\n")) + "\n", nil
+}
+
+var (
+	orgHeading     = regexp.MustCompile(`^(\*+)\s+(.*?)(?:\s+:[\w@#%:]+:)?$`)
+	orgKeyword     = regexp.MustCompile(`^#\+(\w+):\s*(.*)$`)
+	orgBlockBegin  = regexp.MustCompile(`(?i)^#\+begin_(\w+)(?:\s+(\S+))?`)
+	orgListItem    = regexp.MustCompile(`^(\s*)([-+]|\s\*|\d+[.)])\s+(.*)$`)
+	orgCheckbox    = regexp.MustCompile(`^\[([ Xx-])\]\s+`)
+	orgDescription = regexp.MustCompile(`^(.*?)\s+::(?:\s+(.*))?$`)
+	orgTableRule   = regexp.MustCompile(`^\|[-+]+\|?$`)
+	orgLink        = regexp.MustCompile(`\[\[([^\]]+)\](?:\[([^\]]+)\])?\]`)
+	orgCode        = regexp.MustCompile(`(^|[\s('"{-])[=~]([^\s=~](?:[^=~]*?[^\s=~])?)[=~]($|[^\w=~])`)
+	orgBold        = regexp.MustCompile(`(^|[\s('"{-])\*([^\s*](?:[^*]*?[^\s*])?)\*($|[^\w*])`)
+	orgItalic      = regexp.MustCompile(`(^|[\s('"{-])/([^\s/](?:[^/]*?[^\s/])?)/($|[^\w/])`)
+	orgStrike      = regexp.MustCompile(`(^|[\s('"{-])\+([^\s+](?:[^+]*?[^\s+])?)\+($|[^\w+])`)
+)
+
+// orgImageExts lists the link targets shown as images.
+var orgImageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true,
+}
+
+func convertOrg(lines []string) []string {
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			if len(out) > 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+			continue
+		case trimmed == "#" || strings.HasPrefix(trimmed, "# "):
+			continue
+		case strings.EqualFold(trimmed, ":PROPERTIES:") || strings.EqualFold(trimmed, ":LOGBOOK:"):
+			for i < len(lines) && !strings.EqualFold(strings.TrimSpace(lines[i]), ":END:") {
+				i++
+			}
+			continue
+		}
+
+		if m := orgBlockBegin.FindStringSubmatch(trimmed); m != nil {
+			kind := strings.ToLower(m[1])
+			end := i + 1
+			for end < len(lines) && !strings.EqualFold(strings.TrimSpace(lines[end]), "#+end_"+kind) {
+				end++
+			}
+			body := lines[i+1 : min(end, len(lines))]
+			i = end
+			ensureBlank(&out)
+			switch kind {
+			case "src":
+				out = append(out, CodeBlock(dedent(body), m[2]))
+			case "example", "verse":
+				out = append(out, CodeBlock(dedent(body), ""))
+			case "quote":
+				out = append(out, quote(convertOrg(body))...)
+			case "comment":
+			default:
+				out = append(out, convertOrg(body)...)
+			}
+			out = append(out, "")
+			continue
+		}
+		if m := orgKeyword.FindStringSubmatch(trimmed); m != nil {
+			if strings.EqualFold(m[1], "title") {
+				out = append(out, "# "+orgInline(m[2]), "")
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#+") {
+			// Other keywords and stray block ends.
+			continue
+		}
+		if m := orgHeading.FindStringSubmatch(line); m != nil {
+			ensureBlank(&out)
+			out = append(out, strings.Repeat("#", min(len(m[1]), 6))+" "+orgInline(m[2]), "")
+			continue
+		}
+		if strings.HasPrefix(trimmed, ": ") || trimmed == ":" {
+			// Fixed-width lines.
+			var body []string
+			for ; i < len(lines); i++ {
+				fixed := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(fixed, ": ") && fixed != ":" {
+					break
+				}
+				body = append(body, strings.TrimPrefix(strings.TrimPrefix(fixed, ":"), " "))
+			}
+			i--
+			ensureBlank(&out)
+			out = append(out, CodeBlock(strings.Join(body, "\n"), ""), "")
+			continue
+		}
+		if strings.HasPrefix(trimmed, "|") {
+			var rows []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, strings.TrimSpace(lines[i]))
+			}
+			i--
+			ensureBlank(&out)
+			out = append(out, orgTable(rows)...)
+			out = append(out, "")
+			continue
+		}
+		if len(trimmed) >= 5 && strings.Count(trimmed, "-") == len(trimmed) {
+			ensureBlank(&out)
+			out = append(out, "---", "")
+			continue
+		}
+		if m := orgListItem.FindStringSubmatch(line); m != nil {
+			indent, marker, text := m[1], strings.TrimSpace(m[2]), m[3]
+			if marker == "*" {
+				// "*" only starts an item when indented, otherwise it is a
+				// heading; keep the indentation it was matched with.
+				indent += " "
+			}
+			if marker == "+" || marker == "*" {
+				marker = "-"
+			} else if strings.HasSuffix(marker, ")") {
+				marker = strings.TrimSuffix(marker, ")") + "."
+			}
+			if c := orgCheckbox.FindStringSubmatch(text); c != nil {
+				box := strings.ToLower(c[1])
+				if box == "-" {
+					box = " "
+				}
+				text = "[" + box + "] " + text[len(c[0]):]
+			} else if d := orgDescription.FindStringSubmatch(text); d != nil && marker == "-" {
+				text = "**" + orgInline(d[1]) + "**"
+				if d[2] != "" {
+					text += ": " + d[2]
+				}
+			}
+			out = append(out, indent+marker+" "+orgInline(text))
+			continue
+		}
+		out = append(out, orgInline(line))
+	}
+	return out
+}
+
+// orgTable converts table rows to a pipe table. Rule lines are dropped; the
+// first row becomes the header, as markdown tables need one.
+func orgTable(rows []string) []string {
+	var out []string
+	for _, row := range rows {
+		if orgTableRule.MatchString(row) {
+			continue
+		}
+		cells := strings.Split(strings.Trim(row, "|"), "|")
+		for j, cell := range cells {
+			cells[j] = orgInline(strings.TrimSpace(cell))
+		}
+		out = append(out, "| "+strings.Join(cells, " | ")+" |")
+		if len(out) == 1 {
+			out = append(out, "|"+strings.Repeat(" --- |", len(cells)))
+		}
+	}
+	return out
+}
+
+// orgInline converts the inline markup of one line. Code spans are left
+// alone.
+func orgInline(text string) string {
+	text = orgCode.ReplaceAllString(text, "$1`$2`$3")
+	parts := strings.Split(text, "`")
+	for i := 0; i < len(parts); i += 2 {
+		part := orgLink.ReplaceAllStringFunc(parts[i], func(match string) string {
+			m := orgLink.FindStringSubmatch(match)
+			target := strings.TrimPrefix(m[1], "file:")
+			if m[2] == "" && orgImageExts[strings.ToLower(path.Ext(target))] {
+				return "![](" + target + ")"
+			}
+			label := m[2]
+			if label == "" {
+				label = target
+			}
+			return "[" + label + "](" + target + ")"
+		})
+		part = orgBold.ReplaceAllString(part, "$1**$2**$3")
+		part = orgItalic.ReplaceAllString(part, "$1*$2*$3")
+		parts[i] = orgStrike.ReplaceAllString(part, "$1~~$2~~$3")
+	}
+	return strings.Join(parts, "`")
+}
+
+// ensureBlank separates the next block from preceding text.
+func ensureBlank(out *[]string) {
+	if len(*out) > 0 && (*out)[len(*out)-1] != "" {
+		*out = append(*out, "")
+	}
+}
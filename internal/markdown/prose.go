@@ -0,0 +1,156 @@
+package markdown
+
+import "strings"
+
+// MapProse applies fn to every run of prose in src while leaving front matter,
+// fenced code blocks, indented code blocks and inline code spans untouched.
+// Line breaks are preserved so the result keeps the same line numbering as the
+// input.
+func MapProse(src string, fn func(string) string) string {
+	return mapProseLines(src, func(_ int, seg string) string { return fn(seg) })
+}
+
+// mapProseLines is MapProse with the zero-based line of each run passed to
+// fn.
+func mapProseLines(src string, fn func(line int, seg string) string) string {
+	if src == "" {
+		return src
+	}
+	lines := strings.SplitAfter(src, "\n")
+	var out strings.Builder
+	out.Grow(len(src))
+
+	start := frontMatterLines(lines)
+	for _, line := range lines[:start] {
+		out.WriteString(line)
+	}
+
+	fence := ""
+	prevBlank := true
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		body := strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimLeft(body, " \t")
+
+		if fence != "" {
+			out.WriteString(line)
+			if isFenceClose(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" && indentWidth(body) < 4 {
+			fence = marker
+			out.WriteString(line)
+			continue
+		}
+		if prevBlank && indentWidth(body) >= 4 && trimmed != "" {
+			out.WriteString(line)
+			continue
+		}
+
+		out.WriteString(mapInline(body, func(seg string) string { return fn(i, seg) }))
+		out.WriteString(line[len(body):])
+		prevBlank = trimmed == ""
+	}
+	return out.String()
+}
+
+// mapInline applies fn to the text of a single line outside of code spans.
+func mapInline(line string, fn func(string) string) string {
+	var out strings.Builder
+	rest := line
+	for rest != "" {
+		start := strings.IndexByte(rest, '`')
+		if start < 0 {
+			out.WriteString(fn(rest))
+			break
+		}
+		out.WriteString(fn(rest[:start]))
+		ticks := countRun(rest[start:], '`')
+		delim := rest[start : start+ticks]
+		end := strings.Index(rest[start+ticks:], delim)
+		if end < 0 {
+			out.WriteString(rest[start : start+ticks])
+			rest = rest[start+ticks:]
+			continue
+		}
+		stop := start + ticks + end + ticks
+		out.WriteString(rest[start:stop])
+		rest = rest[stop:]
+	}
+	return out.String()
+}
+
+// frontMatterLines reports how many leading lines belong to a YAML front
+// matter block, or 0 when the document has none.
+func frontMatterLines(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(lines[0], "\r\n") != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		switch strings.TrimRight(lines[i], "\r\n") {
+		case "---", "...":
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// FrontMatterBlock returns the front matter of src exactly as written,
+// delimiters included, or "" when there is none.
+func FrontMatterBlock(src string) string {
+	lines := strings.SplitAfter(src, "\n")
+	return strings.Join(lines[:frontMatterLines(lines)], "")
+}
+
+// SplitFrontMatter separates the YAML front matter of src from its body. The
+// body keeps a blank line for every front matter line so positions in it
+// still match the original file.
+func SplitFrontMatter(src string) (front, body string) {
+	lines := strings.SplitAfter(src, "\n")
+	n := frontMatterLines(lines)
+	if n == 0 {
+		return "", src
+	}
+	front = strings.Join(lines[1:n-1], "")
+	body = strings.Repeat("\n", n) + strings.Join(lines[n:], "")
+	return front, body
+}
+
+func fenceMarker(trimmed string) string {
+	for _, ch := range []byte{'`', '~'} {
+		if n := countRun(trimmed, ch); n >= 3 {
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+func isFenceClose(trimmed, fence string) bool {
+	n := countRun(trimmed, fence[0])
+	return n >= len(fence) && strings.TrimSpace(trimmed[n:]) == ""
+}
+
+func countRun(s string, ch byte) int {
+	n := 0
+	for n < len(s) && s[n] == ch {
+		n++
+	}
+	return n
+}
+
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
@@ -0,0 +1,48 @@
+package markdown
+
+import "unicode"
+
+// Script is the writing system a document is predominantly written in.
+type Script int
+
+const (
+	// ScriptLatin covers space-separated scripts, which wrap between words.
+	ScriptLatin Script = iota
+	// ScriptCJK covers Chinese, Japanese and Korean text, which may wrap
+	// between any two characters.
+	ScriptCJK
+)
+
+// DetectScript reports which script dominates the prose of src. Code is
+// ignored. A CJK character carries about as much as a Latin word, so CJK
+// characters are weighed against Latin words rather than letters.
+func DetectScript(src string) Script {
+	cjk, words := 0, 0
+	MapProse(src, func(seg string) string {
+		inWord := false
+		for _, r := range seg {
+			switch {
+			case IsCJK(r):
+				cjk++
+				inWord = false
+			case unicode.IsLetter(r):
+				if !inWord {
+					words++
+				}
+				inWord = true
+			default:
+				inWord = false
+			}
+		}
+		return seg
+	})
+	if cjk > words {
+		return ScriptCJK
+	}
+	return ScriptLatin
+}
+
+// IsCJK reports whether r is a Han, kana or Hangul character.
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,95 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SlugStyle selects the heading anchor algorithm of a hosting platform.
+type SlugStyle string
+
+const (
+	// SlugGitHub follows github.com: lowercase, punctuation dropped, spaces
+	// become hyphens, repeated hyphens kept.
+	SlugGitHub SlugStyle = "github"
+	// SlugGitLab follows GitLab: like GitHub but runs of hyphens collapse.
+	SlugGitLab SlugStyle = "gitlab"
+	// SlugHugo follows Hugo's blackfriday-style anchors: every run of
+	// characters other than letters and digits becomes a single hyphen.
+	SlugHugo SlugStyle = "hugo"
+)
+
+// ParseSlugStyle validates a style name, accepting the empty string as GitHub.
+func ParseSlugStyle(name string) (SlugStyle, error) {
+	switch style := SlugStyle(strings.ToLower(strings.TrimSpace(name))); style {
+	case "":
+		return SlugGitHub, nil
+	case SlugGitHub, SlugGitLab, SlugHugo:
+		return style, nil
+	}
+	return "", fmt.Errorf("不明なスラッグ形式 %q (github, gitlab, hugo のいずれか)", name)
+}
+
+// Slugger generates unique anchors for the headings of one document.
+type Slugger struct {
+	style SlugStyle
+	seen  map[string]int
+}
+
+// NewSlugger returns a slugger for style.
+func NewSlugger(style SlugStyle) *Slugger {
+	return &Slugger{style: style, seen: make(map[string]int)}
+}
+
+// Slug returns the anchor for a heading, adding "-1", "-2", … for repeats as
+// the platforms do.
+func (s *Slugger) Slug(text string) string {
+	base := Slugify(text, s.style)
+	n, exists := s.seen[base]
+	s.seen[base] = n + 1
+	if !exists {
+		return base
+	}
+	slug := fmt.Sprintf("%s-%d", base, n)
+	s.seen[slug]++
+	return slug
+}
+
+// Slugify converts heading text into an anchor without duplicate handling.
+func Slugify(text string, style SlugStyle) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	var b strings.Builder
+	switch style {
+	case SlugHugo:
+		pendingDash := false
+		for _, r := range text {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				if pendingDash && b.Len() > 0 {
+					b.WriteByte('-')
+				}
+				pendingDash = false
+				b.WriteRune(r)
+				continue
+			}
+			pendingDash = true
+		}
+		return b.String()
+	default:
+		for _, r := range text {
+			switch {
+			case unicode.IsLetter(r), unicode.IsDigit(r), r == '_', r == '-':
+				b.WriteRune(r)
+			case r == ' ':
+				b.WriteByte('-')
+			}
+		}
+		slug := b.String()
+		if style == SlugGitLab {
+			for strings.Contains(slug, "--") {
+				slug = strings.ReplaceAll(slug, "--", "-")
+			}
+		}
+		return slug
+	}
+}
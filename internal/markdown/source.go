@@ -0,0 +1,45 @@
+package markdown
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SourceBlock wraps src in a fenced code block tagged as markdown so a renderer
+// shows the document verbatim with syntax highlighting.
+func SourceBlock(src string) string {
+	return CodeBlock(src, "markdown")
+}
+
+// CodeBlock wraps src in a fenced code block tagged with lang. The fence is
+// made longer than any backtick run inside src so embedded fences stay
+// intact.
+func CodeBlock(src, lang string) string {
+	longest := 0
+	run := 0
+	for i := 0; i < len(src); i++ {
+		if src[i] == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+			continue
+		}
+		run = 0
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	body := strings.TrimRight(src, "\n")
+	return fence + lang + "\n" + body + "\n" + fence + "\n"
+}
+
+// Language names the highlighting language of a file for a code block info
+// string: its extension, or the whole name for files such as Makefile. The
+// highlighter resolves both.
+func Language(name string) string {
+	base := filepath.Base(name)
+	lang := strings.TrimPrefix(filepath.Ext(base), ".")
+	if lang == "" {
+		lang = base
+	}
+	return strings.Join(strings.Fields(lang), "")
+}
@@ -0,0 +1,333 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TeX converts a LaTeX math expression to plain Unicode text: Greek letters
+// and symbols become their characters, scripts become superscript and
+// subscript characters where Unicode has them, and fractions and roots are
+// written inline. Line breaks (\\) in environments such as aligned are kept
+// as newlines.
+func TeX(expr string) string {
+	p := &texParser{src: []rune(expr)}
+	out := p.parse(false)
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+type texParser struct {
+	src []rune
+	pos int
+}
+
+// parse converts atoms up to the end of input or, in a group, the closing
+// brace.
+func (p *texParser) parse(group bool) string {
+	var out strings.Builder
+	for p.pos < len(p.src) {
+		if p.src[p.pos] == '}' {
+			if group {
+				p.pos++
+				return out.String()
+			}
+			p.pos++
+			continue
+		}
+		out.WriteString(p.atom())
+	}
+	return out.String()
+}
+
+// atom converts the next atom: a group, a command with its arguments, a
+// script or a single character.
+func (p *texParser) atom() string {
+	r := p.src[p.pos]
+	p.pos++
+	switch {
+	case r == '{':
+		return p.parse(true)
+	case r == '^' || r == '_':
+		return script(p.argument(), r == '^')
+	case r == '&':
+		return " "
+	case r == '~':
+		return " "
+	case r == '\\':
+		return p.command()
+	case unicode.IsSpace(r):
+		return " "
+	case r == '\'':
+		return "′"
+	}
+	return string(r)
+}
+
+// argument returns the next atom converted, skipping spaces before it.
+func (p *texParser) argument() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	return p.atom()
+}
+
+// rawArgument returns the text of the next braced group unconverted.
+func (p *texParser) rawArgument() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return ""
+	}
+	depth, start := 0, p.pos+1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1])
+			}
+		}
+	}
+	return string(p.src[start:])
+}
+
+// optional returns the converted [..] argument of a command, if present.
+func (p *texParser) optional() string {
+	if p.pos >= len(p.src) || p.src[p.pos] != '[' {
+		return ""
+	}
+	end := p.pos
+	for end < len(p.src) && p.src[end] != ']' {
+		end++
+	}
+	inner := TeX(string(p.src[p.pos+1 : end]))
+	p.pos = min(end+1, len(p.src))
+	return inner
+}
+
+func (p *texParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *texParser) command() string {
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) && p.src[p.pos] < unicode.MaxASCII {
+		p.pos++
+	}
+	if p.pos == start {
+		// A control symbol such as \, or \{.
+		p.pos++
+		if s, ok := texSymbols[string(p.src[start])]; ok {
+			return s
+		}
+		return string(p.src[start])
+	}
+	name := string(p.src[start:p.pos])
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		return fraction(p.argument(), p.argument())
+	case "binom":
+		n, k := p.argument(), p.argument()
+		return "C(" + n + ", " + k + ")"
+	case "sqrt":
+		p.skipSpace()
+		index := p.optional()
+		radicand := p.argument()
+		if runeCount(radicand) > 1 {
+			radicand = "(" + radicand + ")"
+		}
+		switch index {
+		case "":
+			return "√" + radicand
+		case "3":
+			return "∛" + radicand
+		case "4":
+			return "∜" + radicand
+		}
+		return script(index, true) + "√" + radicand
+	case "text", "textrm", "textit", "textbf", "mathrm", "mathit", "mathbf", "mathsf", "mathtt", "operatorname", "boldsymbol", "mbox":
+		if name == "text" || name == "textrm" || name == "textit" || name == "textbf" || name == "mbox" {
+			return p.rawArgument()
+		}
+		return p.argument()
+	case "mathbb":
+		var out strings.Builder
+		for _, r := range p.argument() {
+			if s, ok := doubleStruck[r]; ok {
+				out.WriteRune(s)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+		return out.String()
+	case "mathcal", "mathscr", "mathfrak":
+		return p.argument()
+	case "left", "right", "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr", "middle":
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '.' {
+			p.pos++
+		}
+		return ""
+	case "begin", "end":
+		p.rawArgument()
+		return ""
+	case "limits", "nolimits", "displaystyle", "textstyle", "scriptstyle":
+		return ""
+	}
+	if mark, ok := texAccents[name]; ok {
+		var out strings.Builder
+		for _, r := range p.argument() {
+			out.WriteRune(r)
+			out.WriteRune(mark)
+		}
+		return out.String()
+	}
+	if s, ok := texSymbols[name]; ok {
+		return s
+	}
+	// Operator names such as \sin and unknown commands are written as
+	// their names.
+	return name
+}
+
+// fraction writes num over den on one line, using a vulgar fraction for
+// numbers.
+func fraction(num, den string) string {
+	if isDigits(num) && isDigits(den) {
+		if s, ok := vulgarFractions[num+"/"+den]; ok {
+			return s
+		}
+		return script(num, true) + "⁄" + script(den, false)
+	}
+	if runeCount(num) > 1 && !isWord(num) {
+		num = "(" + num + ")"
+	}
+	if runeCount(den) > 1 && !isWord(den) {
+		den = "(" + den + ")"
+	}
+	return num + "/" + den
+}
+
+// script writes s as a superscript or subscript, falling back to ^ and _
+// notation when a character has no script form.
+func script(s string, super bool) string {
+	table, mark := subscripts, "_"
+	if super {
+		table, mark = superscripts, "^"
+	}
+	var out strings.Builder
+	for _, r := range s {
+		c, ok := table[r]
+		if !ok {
+			if runeCount(s) == 1 {
+				return mark + s
+			}
+			return mark + "(" + s + ")"
+		}
+		out.WriteRune(c)
+	}
+	return out.String()
+}
+
+func runeCount(s string) int {
+	return len([]rune(s))
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// isWord reports whether s reads as one term without parentheses.
+func isWord(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("⁰¹²³⁴⁵⁶⁷⁸⁹₀₁₂₃₄₅₆₇₈₉′.", r) {
+			return false
+		}
+	}
+	return true
+}
+
+var superscripts = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+	'+': '⁺', '-': '⁻', '−': '⁻', '=': '⁼', '(': '⁽', ')': '⁾', '′': '′', '*': '*', '∗': '*',
+	'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ', 'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ',
+	'j': 'ʲ', 'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ', 'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ',
+	't': 'ᵗ', 'u': 'ᵘ', 'v': 'ᵛ', 'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ',
+	'A': 'ᴬ', 'B': 'ᴮ', 'D': 'ᴰ', 'E': 'ᴱ', 'G': 'ᴳ', 'H': 'ᴴ', 'I': 'ᴵ', 'J': 'ᴶ', 'K': 'ᴷ',
+	'L': 'ᴸ', 'M': 'ᴹ', 'N': 'ᴺ', 'O': 'ᴼ', 'P': 'ᴾ', 'R': 'ᴿ', 'T': 'ᵀ', 'U': 'ᵁ', 'V': 'ⱽ', 'W': 'ᵂ',
+	'α': 'ᵅ', 'β': 'ᵝ', 'γ': 'ᵞ', 'δ': 'ᵟ', 'θ': 'ᶿ', 'φ': 'ᵠ', 'χ': 'ᵡ', '∞': '∞',
+}
+
+var subscripts = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+	'+': '₊', '-': '₋', '−': '₋', '=': '₌', '(': '₍', ')': '₎',
+	'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ', 'j': 'ⱼ', 'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ',
+	'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ', 't': 'ₜ', 'u': 'ᵤ', 'v': 'ᵥ', 'x': 'ₓ',
+	'β': 'ᵦ', 'γ': 'ᵧ', 'ρ': 'ᵨ', 'φ': 'ᵩ', 'χ': 'ᵪ',
+}
+
+var vulgarFractions = map[string]string{
+	"1/2": "½", "1/3": "⅓", "2/3": "⅔", "1/4": "¼", "3/4": "¾", "1/5": "⅕", "2/5": "⅖",
+	"3/5": "⅗", "4/5": "⅘", "1/6": "⅙", "5/6": "⅚", "1/8": "⅛", "3/8": "⅜", "5/8": "⅝", "7/8": "⅞",
+}
+
+var doubleStruck = map[rune]rune{
+	'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ',
+	'A': '𝔸', 'B': '𝔹', 'E': '𝔼', 'F': '𝔽', 'K': '𝕂', '1': '𝟙',
+}
+
+// texAccents maps accent commands to the combining characters placed after
+// each character of their argument.
+var texAccents = map[string]rune{
+	"hat": '̂', "widehat": '̂', "bar": '̄', "overline": '̅', "vec": '⃗',
+	"dot": '̇', "ddot": '̈', "tilde": '̃', "widetilde": '̃', "underline": '̲',
+}
+
+var texSymbols = map[string]string{
+	// Greek letters.
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "omicron": "ο", "pi": "π", "varpi": "ϖ",
+	"rho": "ρ", "varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ",
+	"phi": "ϕ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	// Operators and relations.
+	"times": "×", "cdot": "·", "pm": "±", "mp": "∓", "div": "÷", "ast": "∗", "star": "⋆",
+	"circ": "∘", "bullet": "•", "oplus": "⊕", "ominus": "⊖", "otimes": "⊗", "odot": "⊙",
+	"le": "≤", "leq": "≤", "ge": "≥", "geq": "≥", "ne": "≠", "neq": "≠", "ll": "≪", "gg": "≫",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆", "supset": "⊃",
+	"supseteq": "⊇", "cup": "∪", "cap": "∩", "setminus": "∖", "emptyset": "∅", "varnothing": "∅",
+	"forall": "∀", "exists": "∃", "nexists": "∄", "neg": "¬", "lnot": "¬", "land": "∧",
+	"wedge": "∧", "lor": "∨", "vee": "∨", "mid": "∣", "parallel": "∥", "perp": "⊥",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺",
+	"mapsto": "↦", "uparrow": "↑", "downarrow": "↓", "longrightarrow": "⟶", "longleftarrow": "⟵",
+	// Large operators.
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+	"bigcup": "⋃", "bigcap": "⋂",
+	// Miscellaneous symbols.
+	"infty": "∞", "partial": "∂", "nabla": "∇", "hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ",
+	"aleph": "ℵ", "angle": "∠", "triangle": "△", "prime": "′", "degree": "°", "dagger": "†",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"lvert": "|", "rvert": "|", "vert": "|", "lVert": "‖", "rVert": "‖", "Vert": "‖",
+	// Spacing and control symbols.
+	",": " ", ";": " ", ":": " ", ">": " ", "!": "", " ": " ", "quad": "  ", "qquad": "    ",
+	"\\": "\n", "{": "{", "}": "}", "|": "‖", "$": "$", "%": "%", "&": "&", "#": "#", "_": "_",
+	"cr": "\n", "newline": "\n",
+}
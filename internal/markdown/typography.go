@@ -0,0 +1,115 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Typography replaces straight quotes, double/triple hyphens and triple dots in
+// prose with their typographic counterparts (“ ” ‘ ’ – — …). Code, link
+// destinations, autolinks and inline HTML are left as written.
+func Typography(src string) string {
+	return MapProse(src, smartenSegment)
+}
+
+func smartenSegment(seg string) string {
+	if isRuleLike(seg) {
+		return seg
+	}
+	var out strings.Builder
+	out.Grow(len(seg))
+	prev := rune(0)
+	for i := 0; i < len(seg); {
+		r, size := utf8.DecodeRuneInString(seg[i:])
+		switch {
+		case r == '\\' && i+size < len(seg):
+			_, next := utf8.DecodeRuneInString(seg[i+size:])
+			out.WriteString(seg[i : i+size+next])
+			i += size + next
+			prev = 0
+			continue
+		case r == ']' && strings.HasPrefix(seg[i:], "]("):
+			end := strings.IndexByte(seg[i:], ')')
+			if end > 0 {
+				out.WriteString(seg[i : i+end+1])
+				i += end + 1
+				prev = ')'
+				continue
+			}
+		case r == '<' && i+1 < len(seg) && isTagStart(seg[i+1]):
+			end := strings.IndexByte(seg[i:], '>')
+			if end > 0 {
+				out.WriteString(seg[i : i+end+1])
+				i += end + 1
+				prev = '>'
+				continue
+			}
+		case r == '.' && strings.HasPrefix(seg[i:], "..."):
+			out.WriteRune('…')
+			i += 3
+			prev = '…'
+			continue
+		case r == '-' && strings.HasPrefix(seg[i:], "---"):
+			out.WriteRune('—')
+			i += 3
+			prev = '—'
+			continue
+		case r == '-' && strings.HasPrefix(seg[i:], "--"):
+			out.WriteRune('–')
+			i += 2
+			prev = '–'
+			continue
+		case r == '"':
+			if opensQuote(prev) {
+				out.WriteRune('“')
+			} else {
+				out.WriteRune('”')
+			}
+			i += size
+			prev = r
+			continue
+		case r == '\'':
+			next, _ := utf8.DecodeRuneInString(seg[i+size:])
+			if opensQuote(prev) && next != utf8.RuneError && !unicode.IsSpace(next) {
+				out.WriteRune('‘')
+			} else {
+				out.WriteRune('’')
+			}
+			i += size
+			prev = r
+			continue
+		}
+		out.WriteString(seg[i : i+size])
+		i += size
+		prev = r
+	}
+	return out.String()
+}
+
+// opensQuote reports whether a quote following prev starts a quotation.
+func opensQuote(prev rune) bool {
+	if prev == 0 || unicode.IsSpace(prev) {
+		return true
+	}
+	switch prev {
+	case '(', '[', '{', '–', '—', '“', '‘', '*', '_':
+		return true
+	}
+	return false
+}
+
+// isRuleLike reports whether seg is a thematic break, setext underline or table
+// delimiter row, all of which rely on literal hyphens.
+func isRuleLike(seg string) bool {
+	trimmed := strings.TrimSpace(seg)
+	if trimmed == "" {
+		return true
+	}
+	return strings.Trim(trimmed, "-*_=|: \t") == ""
+}
+
+func isTagStart(ch byte) bool {
+	return ch == '/' || ch == '!' || ch == '?' ||
+		(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
@@ -0,0 +1,91 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// WikiLink is an Obsidian-style [[Note#Heading|label]] link.
+type WikiLink struct {
+	// Target is the note name or path as written; Heading follows the #.
+	Target, Heading string
+	Label           string
+	// Path is the file the link resolved to, or "" when it did not resolve.
+	Path string
+}
+
+// WikiLinkMark precedes the label of every wikilink left by WikiLinks. It is
+// an invisible zero-width character, like MatchStart, so the viewer can find
+// the links in rendered output.
+const WikiLinkMark = "⁢"
+
+var wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]|#]*)(?:#([^\[\]|]*))?(?:\|([^\[\]]*))?\]\]`)
+
+// WikiLinks replaces the [[wikilinks]] in the prose of src with links that
+// show only their label. resolve, which may be nil, maps a target to the
+// file it names. The links are returned in document order. Embeds, written
+// ![[...]], that Embeds did not inline become links too.
+func WikiLinks(src string, resolve func(target string) (string, bool)) (string, []WikiLink) {
+	if !strings.Contains(src, "[[") {
+		return src, nil
+	}
+	var links []WikiLink
+	out := MapProse(src, func(seg string) string {
+		return wikiLinkPattern.ReplaceAllStringFunc(seg, func(match string) string {
+			m := wikiLinkPattern.FindStringSubmatch(match)
+			target, heading := strings.TrimSpace(m[2]), strings.TrimSpace(m[3])
+			if target == "" && heading == "" {
+				return match
+			}
+			link := WikiLink{Target: target, Heading: heading, Label: strings.TrimSpace(m[4])}
+			switch {
+			case link.Label != "":
+			case heading == "":
+				link.Label = target
+			case target == "":
+				link.Label = heading
+			default:
+				link.Label = target + " › " + heading
+			}
+			if resolve != nil && target != "" {
+				link.Path, _ = resolve(target)
+			}
+			links = append(links, link)
+			return "[" + WikiLinkMark + escapeProse(link.Label) + "](#)"
+		})
+	})
+	return out, links
+}
+
+// Aliases returns the alternative names a note declares in the aliases (or
+// alias) field of its front matter.
+func Aliases(src string) []string {
+	front, _ := SplitFrontMatter(src)
+	if front == "" {
+		return nil
+	}
+	var meta map[string]interface{}
+	if err := yaml.Unmarshal([]byte(front), &meta); err != nil {
+		return nil
+	}
+	var aliases []string
+	for _, key := range []string{"aliases", "alias"} {
+		switch v := meta[key].(type) {
+		case string:
+			for _, alias := range strings.Split(v, ",") {
+				if alias = strings.TrimSpace(alias); alias != "" {
+					aliases = append(aliases, alias)
+				}
+			}
+		case []interface{}:
+			for _, item := range v {
+				if alias, ok := item.(string); ok && strings.TrimSpace(alias) != "" {
+					aliases = append(aliases, strings.TrimSpace(alias))
+				}
+			}
+		}
+	}
+	return aliases
+}
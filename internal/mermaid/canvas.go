@@ -0,0 +1,142 @@
+package mermaid
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Directions a line leaves a cell in. Cells crossed by several lines combine
+// their directions into the matching box-drawing junction.
+const (
+	up uint8 = 1 << iota
+	down
+	left
+	right
+)
+
+var junctions = map[uint8]rune{
+	up: '│', down: '│', up | down: '│',
+	left: '─', right: '─', left | right: '─',
+	down | right: '┌', down | left: '┐', up | right: '└', up | left: '┘',
+	up | down | right: '├', up | down | left: '┤',
+	left | right | down: '┬', left | right | up: '┴',
+	up | down | left | right: '┼',
+}
+
+type cell struct {
+	r     rune
+	lines uint8
+	// covered marks the right half of a wide character.
+	covered bool
+}
+
+// canvas is a grid of terminal cells that grows as it is drawn on.
+type canvas struct {
+	rows [][]cell
+}
+
+func (c *canvas) at(x, y int) *cell {
+	if x < 0 || y < 0 {
+		return &cell{}
+	}
+	for len(c.rows) <= y {
+		c.rows = append(c.rows, nil)
+	}
+	for len(c.rows[y]) <= x {
+		c.rows[y] = append(c.rows[y], cell{})
+	}
+	return &c.rows[y][x]
+}
+
+// put places r at x, y, replacing any line there.
+func (c *canvas) put(x, y int, r rune) {
+	*c.at(x, y) = cell{r: r}
+}
+
+// text writes s starting at x, y and returns the column after it.
+func (c *canvas) text(x, y int, s string) int {
+	for _, r := range s {
+		c.put(x, y, r)
+		if width(string(r)) == 2 {
+			*c.at(x+1, y) = cell{covered: true}
+			x++
+		}
+		x++
+	}
+	return x
+}
+
+// hline draws a horizontal line between x1 and x2 inclusive. A line of a
+// single cell draws nothing, so that it does not cross a vertical one.
+func (c *canvas) hline(y, x1, x2 int) {
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	for x := x1; x <= x2; x++ {
+		cell := c.at(x, y)
+		if x > x1 {
+			cell.lines |= left
+		}
+		if x < x2 {
+			cell.lines |= right
+		}
+	}
+}
+
+// vline draws a vertical line between y1 and y2 inclusive. Like hline, a
+// single cell draws nothing.
+func (c *canvas) vline(x, y1, y2 int) {
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+	for y := y1; y <= y2; y++ {
+		cell := c.at(x, y)
+		if y > y1 {
+			cell.lines |= up
+		}
+		if y < y2 {
+			cell.lines |= down
+		}
+	}
+}
+
+// box draws a rectangle with its top left corner at x, y and label centred
+// inside. Rounded boxes get rounded corners.
+func (c *canvas) box(x, y, w int, label string, rounded bool) {
+	c.hline(y, x, x+w-1)
+	c.hline(y+2, x, x+w-1)
+	c.vline(x, y, y+2)
+	c.vline(x+w-1, y, y+2)
+	if rounded {
+		c.put(x, y, '╭')
+		c.put(x+w-1, y, '╮')
+		c.put(x, y+2, '╰')
+		c.put(x+w-1, y+2, '╯')
+	}
+	c.text(x+(w-width(label))/2, y+1, label)
+}
+
+func (c *canvas) String() string {
+	lines := make([]string, len(c.rows))
+	for y, row := range c.rows {
+		var b strings.Builder
+		for _, cell := range row {
+			switch {
+			case cell.covered:
+			case cell.r != 0:
+				b.WriteRune(cell.r)
+			case cell.lines != 0:
+				b.WriteRune(junctions[cell.lines])
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func width(s string) int {
+	return ansi.StringWidth(s)
+}
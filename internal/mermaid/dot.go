@@ -0,0 +1,197 @@
+package mermaid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	dotComment = regexp.MustCompile(`(?s)/\*.*?\*/|(?m)^\s*#.*$|//[^\n]*`)
+	dotToken   = regexp.MustCompile(`"(?:\\.|[^"\\])*"|->|--|[\w.\x{80}-\x{10FFFF}]+|[\[\]{}=;,:]`)
+	dotEscape  = strings.NewReplacer(`\"`, `"`, `\n`, " ", `\l`, " ", `\r`, " ", `\\`, `\`)
+)
+
+// dotBoxShapes lists the Graphviz node shapes drawn with square corners;
+// the rest, including the default ellipse, are drawn rounded.
+var dotBoxShapes = map[string]bool{
+	"box": true, "rect": true, "rectangle": true, "square": true, "record": true,
+	"plaintext": true, "plain": true, "none": true, "note": true, "tab": true,
+	"folder": true, "component": true, "box3d": true, "cylinder": true,
+}
+
+// RenderDot draws a Graphviz graph with the flowchart layout. Nodes, edges,
+// labels, shapes and rankdir are read; clusters are flattened and other
+// attributes are ignored.
+func RenderDot(src string) (string, error) {
+	tokens := dotToken.FindAllString(dotComment.ReplaceAllString(src, ""), -1)
+	i := 0
+	if i < len(tokens) && strings.EqualFold(tokens[i], "strict") {
+		i++
+	}
+	if i >= len(tokens) {
+		return "", ErrUnsupported
+	}
+	kind := strings.ToLower(tokens[i])
+	if kind != "graph" && kind != "digraph" {
+		return "", fmt.Errorf("%w: %s", ErrUnsupported, tokens[i])
+	}
+	for i < len(tokens) && tokens[i] != "{" {
+		i++
+	}
+	d := &dotGraph{
+		flowchart: flowchart{nodes: make(map[string]*flowNode)},
+		directed:  kind == "digraph",
+		direction: "TD",
+		rounded:   true,
+	}
+	d.parse(tokens[min(i+1, len(tokens)):])
+	return d.draw(d.direction)
+}
+
+type dotGraph struct {
+	flowchart
+	directed  bool
+	direction string
+	// rounded is the shape of new nodes, set by "node [shape=...]".
+	rounded bool
+
+	// The statement being read: its keyword ("graph", "node" or "edge") or
+	// its last node, the edges it added so far and whether an edge operator
+	// is waiting for its head.
+	keyword string
+	last    *flowNode
+	added   int
+	pending bool
+}
+
+func (d *dotGraph) parse(tokens []string) {
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch tok {
+		case "{", "}", ";", ",":
+			d.end()
+		case "->", "--":
+			d.pending = d.last != nil
+		case "[":
+			end := i + 1
+			for end < len(tokens) && tokens[end] != "]" {
+				end++
+			}
+			d.apply(dotAttributes(tokens[i+1 : end]))
+			i = end
+			d.end()
+		case "=", ":", "]":
+		default:
+			if i+2 < len(tokens) && tokens[i+1] == "=" {
+				d.apply(map[string]string{tok: dotString(tokens[i+2])})
+				i += 2
+				d.end()
+				continue
+			}
+			switch lower := strings.ToLower(tok); {
+			case lower == "subgraph":
+				if i+1 < len(tokens) && tokens[i+1] != "{" {
+					i++
+				}
+				continue
+			case lower == "graph" || lower == "node" || lower == "edge":
+				d.end()
+				d.keyword = lower
+				continue
+			}
+			d.addNode(dotString(tok))
+			if i+2 < len(tokens) && tokens[i+1] == ":" {
+				// Ports do not change the drawing.
+				i += 2
+			}
+		}
+	}
+}
+
+func (d *dotGraph) addNode(id string) {
+	fresh := d.nodes[id] == nil
+	n := d.node(id)
+	if fresh {
+		n.rounded = d.rounded
+	}
+	switch {
+	case d.pending:
+		d.edges = append(d.edges, flowEdge{from: d.last, to: n, arrowTo: d.directed})
+		d.added++
+	case d.last != nil || d.keyword != "":
+		d.end()
+	}
+	d.last, d.pending = n, false
+}
+
+// end finishes the current statement.
+func (d *dotGraph) end() {
+	d.keyword, d.last, d.added, d.pending = "", nil, 0, false
+}
+
+// apply sets attributes on what the current statement declared.
+func (d *dotGraph) apply(attrs map[string]string) {
+	switch {
+	case d.added > 0:
+		for i := len(d.edges) - d.added; i < len(d.edges); i++ {
+			e := &d.edges[i]
+			if label, ok := attrs["label"]; ok {
+				e.label = label
+			}
+			switch attrs["dir"] {
+			case "back":
+				e.arrowFrom, e.arrowTo = true, false
+			case "both":
+				e.arrowFrom, e.arrowTo = true, true
+			case "none":
+				e.arrowFrom, e.arrowTo = false, false
+			case "forward":
+				e.arrowFrom, e.arrowTo = false, true
+			}
+		}
+	case d.last != nil:
+		if label, ok := attrs["label"]; ok && label != "" {
+			d.last.label = label
+		}
+		if shape, ok := attrs["shape"]; ok {
+			d.last.rounded = !dotBoxShapes[strings.ToLower(shape)]
+		}
+	case d.keyword == "node":
+		if shape, ok := attrs["shape"]; ok {
+			d.rounded = !dotBoxShapes[strings.ToLower(shape)]
+		}
+	case d.keyword == "" || d.keyword == "graph":
+		switch strings.ToUpper(attrs["rankdir"]) {
+		case "LR":
+			d.direction = "LR"
+		case "RL":
+			d.direction = "RL"
+		case "BT":
+			d.direction = "BT"
+		case "TB":
+			d.direction = "TD"
+		}
+	}
+}
+
+// dotAttributes reads the key=value pairs of an attribute list.
+func dotAttributes(tokens []string) map[string]string {
+	attrs := make(map[string]string)
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i+1] == "=" {
+			attrs[strings.ToLower(tokens[i])] = dotString(tokens[i+2])
+			i += 2
+		}
+	}
+	return attrs
+}
+
+// dotString removes the quotes around an identifier and turns its line
+// break escapes into spaces.
+func dotString(tok string) string {
+	if len(tok) >= 2 && tok[0] == '"' && tok[len(tok)-1] == '"' {
+		tok = dotEscape.Replace(tok[1 : len(tok)-1])
+	}
+	return strings.Join(strings.Fields(tok), " ")
+}
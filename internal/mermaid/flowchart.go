@@ -0,0 +1,555 @@
+package mermaid
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type flowNode struct {
+	id      string
+	label   string
+	rounded bool
+	// dummy nodes stand in for edges crossing a rank, so that every
+	// segment joins adjacent ranks.
+	dummy bool
+	rank  int
+	x, y  int
+	w     int
+}
+
+type flowEdge struct {
+	from, to  *flowNode
+	label     string
+	arrowFrom bool
+	arrowTo   bool
+}
+
+// segment joins a node to one in the next rank.
+type segment struct {
+	a, b           *flowNode
+	arrowA, arrowB bool
+	label          string
+	// labelAtA places the label by a rather than b.
+	labelAtA bool
+}
+
+type flowchart struct {
+	nodes map[string]*flowNode
+	order []*flowNode
+	edges []flowEdge
+}
+
+// shapes maps the opening brackets of node shapes to their closing ones,
+// longest first so that "((" wins over "(".
+var shapes = []struct {
+	open, close string
+	rounded     bool
+}{
+	{"(((", ")))", true}, {"((", "))", true}, {"([", "])", true}, {"[[", "]]", false},
+	{"[(", ")]", false}, {"[/", "/]", false}, {`[\`, `\]`, false}, {"{{", "}}", true},
+	{"(", ")", true}, {"[", "]", false}, {"{", "}", true}, {">", "]", false},
+}
+
+var (
+	labeledEdge = regexp.MustCompile(`^\s*(<?)(--|==|-\.)\s*([^-=.|>\s][^|>]*?)\s*(-{2,}>|={2,}>|\.-+>|-{3,}|={3,}|\.-+)\s*`)
+	plainEdge   = regexp.MustCompile(`^\s*(<?)(-{2,}|={2,}|-\.+-)(>|x|o)?\s*(?:\|([^|]*)\|\s*)?`)
+)
+
+// skippedStatements start statements that only affect styling or grouping.
+var skippedStatements = []string{"subgraph", "end", "classDef", "class ", "style ", "click ", "linkStyle", "direction"}
+
+func renderFlowchart(direction string, lines []string) (string, error) {
+	g := &flowchart{nodes: make(map[string]*flowNode)}
+	for _, line := range lines {
+		for _, stmt := range strings.Split(line, ";") {
+			stmt = strings.TrimSpace(stmt)
+			if stmt == "" || skipped(stmt) {
+				continue
+			}
+			if err := g.parse(stmt); err != nil {
+				return "", err
+			}
+		}
+	}
+	return g.draw(direction)
+}
+
+// draw lays the graph out in the given mermaid direction and draws it.
+func (g *flowchart) draw(direction string) (string, error) {
+	if len(g.order) == 0 {
+		return "", ErrUnsupported
+	}
+	g.assignRanks()
+	reversed := direction == "BT" || direction == "RL"
+	if reversed {
+		top := 0
+		for _, n := range g.order {
+			top = max(top, n.rank)
+		}
+		for _, n := range g.order {
+			n.rank = top - n.rank
+		}
+	}
+	layers, segments := g.layers()
+	orderLayers(layers, segments)
+	var c canvas
+	if direction == "LR" || direction == "RL" {
+		drawHorizontal(&c, layers, segments)
+	} else {
+		drawVertical(&c, layers, segments)
+	}
+	return c.String(), nil
+}
+
+func skipped(stmt string) bool {
+	for _, prefix := range skippedStatements {
+		if stmt == strings.TrimSpace(prefix) || strings.HasPrefix(stmt, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse reads a chain of node groups joined by edges, such as
+// "A[Start] --> B & C -->|yes| D".
+func (g *flowchart) parse(stmt string) error {
+	rest := stmt
+	prev, rest, err := g.parseGroup(rest)
+	if err != nil {
+		return err
+	}
+	for strings.TrimSpace(rest) != "" {
+		var label, op string
+		var arrowFrom bool
+		if m := labeledEdge.FindStringSubmatch(rest); m != nil {
+			arrowFrom, label, op = m[1] == "<", m[3], m[4]
+			rest = rest[len(m[0]):]
+		} else if m := plainEdge.FindStringSubmatch(rest); m != nil && m[0] != "" {
+			arrowFrom, op, label = m[1] == "<", m[2]+m[3], m[4]
+			rest = rest[len(m[0]):]
+		} else {
+			return fmt.Errorf("mermaid: 解釈できない行: %s", stmt)
+		}
+		arrowTo := strings.HasSuffix(op, ">") || strings.HasSuffix(op, "x") || strings.HasSuffix(op, "o")
+		next, remaining, err := g.parseGroup(rest)
+		if err != nil {
+			return err
+		}
+		for _, from := range prev {
+			for _, to := range next {
+				g.edges = append(g.edges, flowEdge{from: from, to: to, label: unquote(label), arrowFrom: arrowFrom, arrowTo: arrowTo})
+			}
+		}
+		prev, rest = next, remaining
+	}
+	return nil
+}
+
+// parseGroup reads nodes joined by "&".
+func (g *flowchart) parseGroup(s string) ([]*flowNode, string, error) {
+	var group []*flowNode
+	for {
+		node, rest, err := g.parseNode(s)
+		if err != nil {
+			return nil, "", err
+		}
+		group = append(group, node)
+		trimmed := strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(trimmed, "&") {
+			return group, rest, nil
+		}
+		s = trimmed[1:]
+	}
+}
+
+// node returns the node named id, adding it on first use.
+func (g *flowchart) node(id string) *flowNode {
+	node := g.nodes[id]
+	if node == nil {
+		node = &flowNode{id: id, label: id}
+		g.nodes[id] = node
+		g.order = append(g.order, node)
+	}
+	return node
+}
+
+// parseNode reads a node reference with an optional shape and label.
+func (g *flowchart) parseNode(s string) (*flowNode, string, error) {
+	s = strings.TrimLeft(s, " \t")
+	end := 0
+	for end < len(s) {
+		// Bytes of multi-byte runes are all taken as name characters.
+		r := rune(s[end])
+		if r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			end++
+			continue
+		}
+		break
+	}
+	id := s[:end]
+	if id == "" {
+		return nil, "", fmt.Errorf("mermaid: ノード名がありません: %s", s)
+	}
+	rest := s[end:]
+	node := g.node(id)
+	for _, shape := range shapes {
+		if !strings.HasPrefix(rest, shape.open) {
+			continue
+		}
+		closing := strings.Index(rest[len(shape.open):], shape.close)
+		if closing < 0 {
+			return nil, "", fmt.Errorf("mermaid: 閉じていないノード: %s", s)
+		}
+		node.label = unquote(rest[len(shape.open) : len(shape.open)+closing])
+		node.rounded = shape.rounded
+		rest = rest[len(shape.open)+closing+len(shape.close):]
+		break
+	}
+	if strings.HasPrefix(rest, ":::") {
+		i := 3
+		for i < len(rest) && rest[i] != ' ' && rest[i] != '-' && rest[i] != '&' {
+			i++
+		}
+		rest = rest[i:]
+	}
+	return node, rest, nil
+}
+
+// assignRanks places every node one rank below its deepest predecessor,
+// ignoring the edges that close cycles.
+func (g *flowchart) assignRanks() {
+	out := make(map[*flowNode][]*flowNode)
+	for _, e := range g.edges {
+		if e.from != e.to {
+			out[e.from] = append(out[e.from], e.to)
+		}
+	}
+	// Drop back edges found by a depth-first search.
+	state := make(map[*flowNode]int)
+	forward := make(map[*flowNode][]*flowNode)
+	var visit func(n *flowNode)
+	visit = func(n *flowNode) {
+		state[n] = 1
+		for _, next := range out[n] {
+			switch state[next] {
+			case 0:
+				forward[n] = append(forward[n], next)
+				visit(next)
+			case 2:
+				forward[n] = append(forward[n], next)
+			}
+		}
+		state[n] = 2
+	}
+	for _, n := range g.order {
+		if state[n] == 0 {
+			visit(n)
+		}
+	}
+	indegree := make(map[*flowNode]int)
+	for _, targets := range forward {
+		for _, t := range targets {
+			indegree[t]++
+		}
+	}
+	var queue []*flowNode
+	for _, n := range g.order {
+		if indegree[n] == 0 {
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, t := range forward[n] {
+			t.rank = max(t.rank, n.rank+1)
+			if indegree[t]--; indegree[t] == 0 {
+				queue = append(queue, t)
+			}
+		}
+	}
+}
+
+// layers groups the nodes by rank and splits every edge into segments
+// between adjacent ranks.
+func (g *flowchart) layers() ([][]*flowNode, []segment) {
+	top := 0
+	for _, n := range g.order {
+		top = max(top, n.rank)
+	}
+	layers := make([][]*flowNode, top+1)
+	for _, n := range g.order {
+		layers[n.rank] = append(layers[n.rank], n)
+	}
+	var segments []segment
+	for _, e := range g.edges {
+		if e.from == e.to {
+			continue
+		}
+		u, v := e.from, e.to
+		arrowU, arrowV, labelAtU := e.arrowFrom, e.arrowTo, true
+		if u.rank > v.rank {
+			u, v = v, u
+			arrowU, arrowV, labelAtU = arrowV, arrowU, false
+		}
+		prev := u
+		for r := u.rank + 1; r < v.rank; r++ {
+			dummy := &flowNode{dummy: true, rank: r}
+			layers[r] = append(layers[r], dummy)
+			segments = append(segments, segment{a: prev, b: dummy})
+			prev = dummy
+		}
+		segments = append(segments, segment{a: prev, b: v})
+		// The arrowheads and the label sit at the ends of the chain; the
+		// label goes by the node the edge starts from.
+		first, last := &segments[len(segments)-(v.rank-u.rank)], &segments[len(segments)-1]
+		first.arrowA, last.arrowB = arrowU, arrowV
+		if labelAtU {
+			first.label, first.labelAtA = e.label, true
+		} else {
+			last.label = e.label
+		}
+	}
+	return layers, segments
+}
+
+// orderLayers reduces crossings by moving every node towards the average
+// position of its neighbours, sweeping down and up a few times.
+func orderLayers(layers [][]*flowNode, segments []segment) {
+	above := make(map[*flowNode][]*flowNode)
+	below := make(map[*flowNode][]*flowNode)
+	for _, s := range segments {
+		below[s.a] = append(below[s.a], s.b)
+		above[s.b] = append(above[s.b], s.a)
+	}
+	pos := make(map[*flowNode]float64)
+	number := func(layer []*flowNode) {
+		for i, n := range layer {
+			pos[n] = float64(i)
+		}
+	}
+	for _, layer := range layers {
+		number(layer)
+	}
+	sweep := func(layer []*flowNode, neighbours map[*flowNode][]*flowNode) {
+		key := make(map[*flowNode]float64)
+		for _, n := range layer {
+			key[n] = pos[n]
+			if len(neighbours[n]) > 0 {
+				sum := 0.0
+				for _, m := range neighbours[n] {
+					sum += pos[m]
+				}
+				key[n] = sum / float64(len(neighbours[n]))
+			}
+		}
+		sort.SliceStable(layer, func(i, j int) bool { return key[layer[i]] < key[layer[j]] })
+		number(layer)
+	}
+	for range 4 {
+		for r := 1; r < len(layers); r++ {
+			sweep(layers[r], above)
+		}
+		for r := len(layers) - 2; r >= 0; r-- {
+			sweep(layers[r], below)
+		}
+	}
+}
+
+const (
+	nodeGap  = 3
+	rankGap  = 3
+	boxInset = 4
+)
+
+// onBranch reports, for each segment, whether its label goes on its own
+// branch after the fork rather than by the node it leaves: other labelled
+// segments leave that node too, and their labels would write over it.
+func onBranch(segments []segment) []bool {
+	labelled := make(map[*flowNode]int)
+	for _, s := range segments {
+		if s.label != "" && s.labelAtA {
+			labelled[s.a]++
+		}
+	}
+	branch := make([]bool, len(segments))
+	for i, s := range segments {
+		branch[i] = s.label != "" && s.labelAtA && labelled[s.a] > 1
+	}
+	return branch
+}
+
+// drawVertical lays ranks out as rows, top to bottom.
+func drawVertical(c *canvas, layers [][]*flowNode, segments []segment) {
+	branch := onBranch(segments)
+	// A label on a branch is written right of the line entering its node,
+	// so the next node in the row keeps clear of it.
+	labelWidth := make(map[*flowNode]int)
+	for i, s := range segments {
+		if branch[i] {
+			labelWidth[s.b] = max(labelWidth[s.b], width(s.label))
+		}
+	}
+	gapAfter := func(n *flowNode) int {
+		return max(nodeGap, n.w/2+labelWidth[n]+2-n.w)
+	}
+	widest := 0
+	rowWidth := make([]int, len(layers))
+	for r, layer := range layers {
+		for i, n := range layer {
+			n.w = 1
+			if !n.dummy {
+				n.w = width(n.label) + boxInset
+			}
+			if i > 0 {
+				rowWidth[r] += gapAfter(layer[i-1])
+			}
+			rowWidth[r] += n.w
+		}
+		widest = max(widest, rowWidth[r])
+	}
+	for r, layer := range layers {
+		x := (widest - rowWidth[r]) / 2
+		for _, n := range layer {
+			n.x, n.y = x, r*(3+rankGap)
+			x += n.w + gapAfter(n)
+			if !n.dummy {
+				c.box(n.x, n.y, n.w, n.label, n.rounded)
+			}
+		}
+	}
+	type mark struct {
+		x, y int
+		text string
+	}
+	var marks []mark
+	for i, s := range segments {
+		xa, xb := s.a.x+s.a.w/2, s.b.x+s.b.w/2
+		g0, g1, g2 := s.a.y+3, s.a.y+4, s.a.y+5
+		start := s.a.y + 2
+		if s.arrowA {
+			start = g0
+			marks = append(marks, mark{xa, g0, "▲"})
+		}
+		c.vline(xa, start, g1)
+		c.hline(g1, xa, xb)
+		switch {
+		case s.b.dummy:
+			c.vline(xb, g1, s.b.y+2)
+		case s.arrowB:
+			c.vline(xb, g1, g2)
+			marks = append(marks, mark{xb, g2, "▼"})
+		default:
+			c.vline(xb, g1, s.b.y)
+		}
+		if s.label != "" {
+			if s.labelAtA && !branch[i] {
+				marks = append(marks, mark{xa + 2, g0, s.label})
+			} else {
+				marks = append(marks, mark{xb + 2, g2, s.label})
+			}
+		}
+	}
+	for _, m := range marks {
+		c.text(m.x, m.y, m.text)
+	}
+}
+
+// drawHorizontal lays ranks out as columns, left to right.
+func drawHorizontal(c *canvas, layers [][]*flowNode, segments []segment) {
+	branch := onBranch(segments)
+	gap := 6
+	for _, s := range segments {
+		gap = max(gap, width(s.label)+4)
+	}
+	colX := make([]int, len(layers))
+	colW := make([]int, len(layers))
+	colH := make([]int, len(layers))
+	tallest := 0
+	for r, layer := range layers {
+		colW[r] = 1
+		for i, n := range layer {
+			h := 1
+			if !n.dummy {
+				n.w = width(n.label) + boxInset
+				colW[r] = max(colW[r], n.w)
+				h = 3
+			}
+			if i > 0 {
+				colH[r]++
+			}
+			colH[r] += h
+		}
+		tallest = max(tallest, colH[r])
+		if r > 0 {
+			colX[r] = colX[r-1] + colW[r-1] + gap
+		}
+	}
+	for r, layer := range layers {
+		y := (tallest - colH[r]) / 2
+		for _, n := range layer {
+			n.x, n.y = colX[r], y
+			if n.dummy {
+				n.w = colW[r]
+				y += 2
+				continue
+			}
+			c.box(n.x, n.y, n.w, n.label, n.rounded)
+			y += 4
+		}
+	}
+	middle := func(n *flowNode) int {
+		if n.dummy {
+			return n.y
+		}
+		return n.y + 1
+	}
+	type mark struct {
+		x, y int
+		text string
+	}
+	var marks []mark
+	for i, s := range segments {
+		ya, yb := middle(s.a), middle(s.b)
+		exit := s.a.x + s.a.w - 1
+		entry := s.b.x
+		xm := entry - 3
+		if branch[i] {
+			// Fork next to the node, leaving the gap to the branches and
+			// their labels.
+			xm = exit + 2
+		}
+		start := exit
+		if s.arrowA {
+			start = exit + 1
+			marks = append(marks, mark{exit + 1, ya, "◀"})
+		}
+		c.hline(ya, start, xm)
+		c.vline(xm, ya, yb)
+		switch {
+		case s.b.dummy:
+			c.hline(yb, xm, s.b.x+s.b.w-1)
+		case s.arrowB:
+			c.hline(yb, xm, entry-1)
+			marks = append(marks, mark{entry - 1, yb, "▶"})
+		default:
+			c.hline(yb, xm, entry)
+		}
+		if s.label != "" {
+			switch {
+			case branch[i]:
+				marks = append(marks, mark{xm + 2, yb - 1, s.label})
+			case s.labelAtA:
+				marks = append(marks, mark{exit + 2, ya - 1, s.label})
+			default:
+				marks = append(marks, mark{entry - 1 - width(s.label), yb - 1, s.label})
+			}
+		}
+	}
+	for _, m := range marks {
+		c.text(m.x, m.y, m.text)
+	}
+}
@@ -0,0 +1,27 @@
+package mermaid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecisionLabels(t *testing.T) {
+	for _, tc := range []struct {
+		src    string
+		labels []string
+	}{
+		{"graph TD\nA{Ok?} -->|Yes| B[Go]\nA -->|No| C[Stop]\n", []string{"Yes", "No"}},
+		{"graph LR\nA{Review} -->|approve| B[Merge]\nA -->|reject| C[Close]\n", []string{"approve", "reject"}},
+		{"graph TD\nA{Ok?} -->|Yes| B[Go]\nA -->|No| C[Stop]\nA -->|Maybe later| D[Wait]\n", []string{"Yes", "No", "Maybe later"}},
+	} {
+		out, err := Render(tc.src)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.src, err)
+		}
+		for _, label := range tc.labels {
+			if !strings.Contains(out, label) {
+				t.Errorf("%q: label %q is not drawn whole:\n%s", tc.src, label, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package mermaid draws mermaid diagrams as box-drawing text, so that a
+// terminal viewer can show them instead of their source. Flowcharts and
+// sequence diagrams are supported; other diagram types are reported as
+// unsupported and left to the caller. Graphviz graphs are drawn with the same
+// layout as flowcharts.
+package mermaid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupported is returned for diagram types that cannot be drawn.
+var ErrUnsupported = errors.New("未対応の mermaid 図です")
+
+// Render draws the mermaid diagram src.
+func Render(src string) (string, error) {
+	lines := statements(src)
+	if len(lines) == 0 {
+		return "", ErrUnsupported
+	}
+	header := strings.Fields(lines[0])
+	switch header[0] {
+	case "graph", "flowchart":
+		direction := "TD"
+		if len(header) > 1 {
+			direction = strings.ToUpper(header[1])
+		}
+		return renderFlowchart(direction, lines[1:])
+	case "sequenceDiagram":
+		return renderSequence(lines[1:])
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupported, header[0])
+}
+
+// statements returns the non-empty lines of src without comments, with
+// front matter and directives such as %%{init: ...}%% removed.
+func statements(src string) []string {
+	var out []string
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// unquote removes the quotes mermaid allows around labels and turns <br>
+// line breaks into spaces.
+func unquote(label string) string {
+	label = strings.TrimSpace(label)
+	if len(label) >= 2 && label[0] == '"' && label[len(label)-1] == '"' {
+		label = label[1 : len(label)-1]
+	}
+	for _, br := range []string{"<br/>", "<br />", "<br>"} {
+		label = strings.ReplaceAll(label, br, " ")
+	}
+	return label
+}
@@ -0,0 +1,242 @@
+package mermaid
+
+import (
+	"regexp"
+	"strings"
+)
+
+type participant struct {
+	label string
+	x, w  int
+}
+
+var (
+	participantLine = regexp.MustCompile(`^(?:participant|actor)\s+(.+?)(?:\s+as\s+(.+))?$`)
+	messageLine     = regexp.MustCompile(`^(.+?)\s*(<<-->>|<<->>|--?>>|--?>|--?x|--?\))\s*[+-]?\s*(.+?)\s*:\s*(.*)$`)
+	noteLine        = regexp.MustCompile(`^[Nn]ote\s+(left of|right of|over)\s+([^:]+?)\s*:\s*(.*)$`)
+	blockLine       = regexp.MustCompile(`^(loop|alt|else|opt|par|and|critical|option|break)\b\s*(.*)$`)
+)
+
+// sequence event kinds.
+const (
+	eventMessage = iota
+	eventNote
+	eventBlock
+)
+
+type event struct {
+	kind     int
+	from, to *participant
+	text     string
+	// Messages: dotted lines and whether an arrowhead ends them.
+	dotted, head, both bool
+	// Notes: "left of", "right of" or "over".
+	placement string
+}
+
+type sequence struct {
+	byID         map[string]*participant
+	participants []*participant
+	events       []event
+}
+
+func (s *sequence) participant(id string) *participant {
+	id = strings.TrimSpace(id)
+	if p := s.byID[id]; p != nil {
+		return p
+	}
+	p := &participant{label: id}
+	s.byID[id] = p
+	s.participants = append(s.participants, p)
+	return p
+}
+
+func renderSequence(lines []string) (string, error) {
+	s := &sequence{byID: make(map[string]*participant)}
+	for _, line := range lines {
+		if m := participantLine.FindStringSubmatch(line); m != nil {
+			p := s.participant(m[1])
+			if m[2] != "" {
+				p.label = unquote(m[2])
+			}
+			continue
+		}
+		if m := noteLine.FindStringSubmatch(line); m != nil {
+			ids := strings.Split(m[2], ",")
+			e := event{kind: eventNote, from: s.participant(ids[0]), text: unquote(m[3]), placement: m[1]}
+			e.to = e.from
+			if len(ids) > 1 {
+				e.to = s.participant(ids[1])
+			}
+			s.events = append(s.events, e)
+			continue
+		}
+		if m := blockLine.FindStringSubmatch(line); m != nil {
+			s.events = append(s.events, event{kind: eventBlock, text: strings.TrimSpace(m[1] + " " + m[2])})
+			continue
+		}
+		if m := messageLine.FindStringSubmatch(line); m != nil {
+			op := m[2]
+			s.events = append(s.events, event{
+				kind: eventMessage, from: s.participant(m[1]), to: s.participant(m[3]), text: unquote(m[4]),
+				dotted: strings.Contains(op, "--"), head: op != "->" && op != "-->", both: strings.HasPrefix(op, "<<"),
+			})
+		}
+		// Activations, autonumbering and block ends do not change the drawing.
+	}
+	if len(s.participants) == 0 {
+		return "", ErrUnsupported
+	}
+	s.layout()
+
+	var c canvas
+	top := 4
+	y := top
+	for _, e := range s.events {
+		switch e.kind {
+		case eventMessage:
+			if e.from == e.to {
+				y += 3
+			} else {
+				y += 2
+			}
+		default:
+			y += 2
+		}
+	}
+	bottom := y
+	for _, p := range s.participants {
+		c.vline(p.x, 2, bottom)
+	}
+	for _, p := range s.participants {
+		c.box(p.x-p.w/2, 0, p.w, p.label, false)
+		c.box(p.x-p.w/2, bottom, p.w, p.label, false)
+	}
+	y = top
+	for _, e := range s.events {
+		switch e.kind {
+		case eventMessage:
+			if e.from == e.to {
+				drawSelfMessage(&c, e, y)
+				y += 3
+				continue
+			}
+			drawMessage(&c, e, y)
+			y += 2
+		case eventNote:
+			text := "※ " + e.text
+			x := e.from.x + 2
+			switch e.placement {
+			case "left of":
+				x = e.from.x - 1 - width(text)
+			case "over":
+				x = (e.from.x+e.to.x)/2 - width(text)/2
+			}
+			c.text(max(x, 0), y, text)
+			y += 2
+		case eventBlock:
+			c.text(0, y, "["+e.text+"]")
+			y += 2
+		}
+	}
+	return c.String(), nil
+}
+
+// layout spaces the participants so that their boxes and the labels of the
+// messages between them fit.
+func (s *sequence) layout() {
+	index := make(map[*participant]int)
+	for i, p := range s.participants {
+		index[p] = i
+		p.w = width(p.label) + boxInset
+		if i == 0 {
+			p.x = p.w / 2
+			continue
+		}
+		prev := s.participants[i-1]
+		p.x = prev.x + (prev.w+p.w)/2 + 2
+	}
+	shift := func(from, by int) {
+		for _, p := range s.participants[from:] {
+			p.x += by
+		}
+	}
+	for _, e := range s.events {
+		lo, hi := index[e.from], index[e.to]
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		need := width(e.text) + 6
+		switch {
+		case e.kind == eventBlock:
+			continue
+		case e.kind == eventNote && e.placement == "right of" || e.kind == eventMessage && lo == hi:
+			if hi+1 < len(s.participants) {
+				hi++
+			} else {
+				continue
+			}
+		case e.kind == eventNote && e.placement == "left of":
+			if lo == 0 {
+				if gap := need - s.participants[0].x; gap > 0 {
+					shift(0, gap)
+				}
+				continue
+			}
+			lo--
+		case e.kind == eventNote:
+			if lo == hi {
+				continue
+			}
+		}
+		if gap := need - (s.participants[hi].x - s.participants[lo].x); gap > 0 {
+			shift(hi, gap)
+		}
+	}
+}
+
+func drawMessage(c *canvas, e event, y int) {
+	from, to := e.from.x, e.to.x
+	dir := 1
+	if to < from {
+		dir = -1
+	}
+	line := '─'
+	if e.dotted {
+		line = '╌'
+	}
+	for x := from + dir; x != to; x += dir {
+		c.put(x, y, line)
+	}
+	outward, inward := right, left
+	head, tail := '▶', '◀'
+	if dir < 0 {
+		outward, inward = left, right
+		head, tail = tail, head
+	}
+	c.at(from, y).lines |= outward
+	if e.head {
+		c.put(to-dir, y, head)
+	} else {
+		c.at(to, y).lines |= inward
+	}
+	if e.both {
+		c.put(from+dir, y, tail)
+	}
+	lo := min(from, to)
+	c.text(lo+(abs(to-from)-width(e.text))/2+1, y-1, e.text)
+}
+
+func drawSelfMessage(c *canvas, e event, y int) {
+	x := e.from.x
+	c.at(x, y).lines |= right
+	c.text(x+1, y, "──┐ "+e.text)
+	c.text(x+1, y+1, "◀─┘")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,105 @@
+package remote
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// readmeNames are tried in order for a repository or directory given
+// without a file, as the raw endpoints do not pick the README themselves.
+var readmeNames = []string{"README.md", "readme.md", "Readme.md", "README.markdown", "README"}
+
+// Resolve maps target to the URLs its document is downloaded from, tried in
+// order. GitHub repository, directory and file pages and gists, with or
+// without their scheme, resolve to their raw content; other http(s) URLs
+// are used as is. ok is false for anything else, including paths that exist
+// locally.
+func Resolve(target string) (candidates []string, ok bool) {
+	if _, err := os.Stat(target); err == nil {
+		return nil, false
+	}
+	raw := target
+	if !strings.Contains(raw, "://") && (strings.HasPrefix(raw, "github.com/") || strings.HasPrefix(raw, "gist.github.com/")) {
+		raw = "https://" + raw
+	}
+	if !IsURL(raw) {
+		return nil, false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch strings.ToLower(u.Host) {
+	case "github.com", "www.github.com":
+		if len(parts) < 2 {
+			break
+		}
+		repo := "https://raw.githubusercontent.com/" + parts[0] + "/" + strings.TrimSuffix(parts[1], ".git") + "/"
+		switch {
+		case len(parts) == 2:
+			return readmeCandidates(repo + "HEAD/"), true
+		case len(parts) >= 4 && parts[2] == "blob":
+			return []string{repo + strings.Join(parts[3:], "/")}, true
+		case len(parts) >= 4 && parts[2] == "tree":
+			return readmeCandidates(repo + strings.Join(parts[3:], "/") + "/"), true
+		}
+	case "gist.github.com":
+		if len(parts) >= 1 && parts[0] != "" {
+			return []string{"https://gist.github.com/" + strings.Join(parts, "/") + "/raw"}, true
+		}
+	}
+	return []string{raw}, true
+}
+
+func readmeCandidates(dir string) []string {
+	candidates := make([]string, len(readmeNames))
+	for i, name := range readmeNames {
+		candidates[i] = dir + name
+	}
+	return candidates
+}
+
+// Absolutize points the relative link and image destinations of src, a
+// document downloaded from docURL, at absolute URLs so they can be followed
+// from the viewer. Documents from GitHub's raw endpoint link to the pages
+// of the repository, as they do on GitHub, and keep images on the raw
+// endpoint; root-relative destinations resolve against the repository.
+func Absolutize(src, docURL string) string {
+	base, err := url.Parse(docURL)
+	if err != nil {
+		return src
+	}
+	linkBase, imageBase := base, base
+	var linkRoot, imageRoot string
+	if strings.EqualFold(base.Host, "raw.githubusercontent.com") {
+		// The path is /user/repo/ref/file; a ref holding slashes is taken
+		// as a directory, as the raw endpoint cannot tell them apart either.
+		if parts := strings.SplitN(strings.TrimPrefix(base.Path, "/"), "/", 4); len(parts) == 4 {
+			linkRoot = "/" + parts[0] + "/" + parts[1] + "/blob/" + parts[2]
+			imageRoot = "/" + parts[0] + "/" + parts[1] + "/" + parts[2]
+			linkBase = &url.URL{Scheme: "https", Host: "github.com", Path: linkRoot + "/" + parts[3]}
+		}
+	}
+	return markdown.RewriteDestinations(src, func(dest string, image bool) string {
+		if strings.HasPrefix(dest, "#") || markdown.IsURL(dest) {
+			return dest
+		}
+		ref, err := url.Parse(dest)
+		if err != nil {
+			return dest
+		}
+		base, root := linkBase, linkRoot
+		if image {
+			base, root = imageBase, imageRoot
+		}
+		if root != "" && strings.HasPrefix(ref.Path, "/") && ref.Host == "" {
+			ref.Path = path.Join(root, ref.Path)
+		}
+		return base.ResolveReference(ref).String()
+	})
+}
@@ -0,0 +1,163 @@
+// Package remote downloads documents given as http(s) URLs, so a README can
+// be read without cloning its repository. Changes are detected by polling
+// with conditional requests, as there is nothing for fsnotify to watch.
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+const (
+	// MaxSize bounds the size of a downloaded document.
+	MaxSize = 8 << 20
+	// PollInterval is how often a shown document is checked for changes.
+	PollInterval = 30 * time.Second
+	timeout      = 15 * time.Second
+)
+
+// ErrTooLarge is returned for documents larger than MaxSize.
+var ErrTooLarge = fmt.Errorf("%d MiB を超えるドキュメントは取得できません", MaxSize>>20)
+
+var errNotFound = errors.New("404 Not Found")
+
+var client = &http.Client{Timeout: timeout}
+
+// Document is a downloaded document and the validators used to ask whether
+// it has changed.
+type Document struct {
+	URL          string
+	Body         []byte
+	ETag         string
+	LastModified string
+}
+
+// IsURL reports whether target is an http or https URL.
+func IsURL(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Name returns the file name of the document at rawURL, used to pick how it
+// is converted, falling back to "index.md" for directory-like URLs.
+func Name(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "index.md"
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "index.md"
+	}
+	return name
+}
+
+// IsRemote reports whether target names a remote document, as Resolve
+// understands it.
+func IsRemote(target string) bool {
+	_, ok := Resolve(target)
+	return ok
+}
+
+// Fetch downloads the document target names, trying the candidates from
+// Resolve in order until one exists.
+func Fetch(target string) (Document, error) {
+	candidates, ok := Resolve(target)
+	if !ok {
+		return Document{}, fmt.Errorf("%s は URL ではありません", target)
+	}
+	var err error
+	for _, candidate := range candidates {
+		var doc Document
+		doc, _, err = Refresh(Document{URL: candidate})
+		if !errors.Is(err, errNotFound) {
+			return doc, err
+		}
+	}
+	if len(candidates) > 1 {
+		return Document{}, fmt.Errorf("%s に README が見つかりません", target)
+	}
+	return Document{}, err
+}
+
+// Markdown returns the markdown shown for the document, with its relative
+// destinations made absolute by Absolutize.
+func (d Document) Markdown() (string, error) {
+	content, err := markdown.Convert(Name(d.URL), d.Body)
+	if err != nil {
+		return "", err
+	}
+	return Absolutize(content, d.URL), nil
+}
+
+// Refresh downloads prev.URL again unless the server reports, from prev's
+// validators, that it has not changed. The returned flag tells whether the
+// document changed; when it did not, prev is returned as is.
+func Refresh(prev Document) (Document, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, prev.URL, nil)
+	if err != nil {
+		return prev, false, err
+	}
+	req.Header.Set("Accept", "text/markdown, text/plain;q=0.9, */*;q=0.1")
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return prev, false, fmt.Errorf("%s を取得できません: %w", prev.URL, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && prev.Body != nil {
+		return prev, false, nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return prev, false, fmt.Errorf("%s を取得できません: %w", prev.URL, errNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return prev, false, fmt.Errorf("%s を取得できません: %s", prev.URL, resp.Status)
+	}
+	if resp.ContentLength > MaxSize {
+		return prev, false, ErrTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return prev, false, fmt.Errorf("%s を取得できません: %w", prev.URL, err)
+	}
+	if len(body) > MaxSize {
+		return prev, false, ErrTooLarge
+	}
+
+	doc := Document{
+		URL:          prev.URL,
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	// Servers without validators are compared by content instead.
+	changed := prev.Body == nil || string(body) != string(prev.Body)
+	return doc, changed, nil
+}
+
+// unwrapURLError drops the "Get "url":" prefix of client errors, as callers
+// name the URL themselves.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return errors.New("タイムアウトしました")
+		}
+		return urlErr.Err
+	}
+	return err
+}
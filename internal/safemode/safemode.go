@@ -0,0 +1,96 @@
+// Package safemode is the single gateway for operations that modify the disk or
+// run external programs. Every such feature must go through it so that the
+// --readonly flag can guarantee neither happens, whatever keys are pressed or
+// settings are loaded.
+package safemode
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"sync/atomic"
+)
+
+// ErrReadOnly is returned for writes and command executions attempted while
+// read-only mode is enabled.
+var ErrReadOnly = errors.New("読み取り専用モード (--readonly) のため実行できません")
+
+var readOnly atomic.Bool
+
+// Enable switches the process into read-only mode. It cannot be undone.
+func Enable() {
+	readOnly.Store(true)
+}
+
+// Enabled reports whether read-only mode is active.
+func Enabled() bool {
+	return readOnly.Load()
+}
+
+// WriteFile behaves like os.WriteFile unless read-only mode is active.
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	if Enabled() {
+		return ErrReadOnly
+	}
+	return os.WriteFile(name, data, perm)
+}
+
+// MkdirAll behaves like os.MkdirAll unless read-only mode is active.
+func MkdirAll(path string, perm os.FileMode) error {
+	if Enabled() {
+		return ErrReadOnly
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// Remove behaves like os.Remove unless read-only mode is active.
+func Remove(name string) error {
+	if Enabled() {
+		return ErrReadOnly
+	}
+	return os.Remove(name)
+}
+
+// OpenFile behaves like os.OpenFile unless read-only mode is active and
+// flag asks for anything but reading.
+func OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	if Enabled() && flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, ErrReadOnly
+	}
+	return os.OpenFile(name, flag, perm)
+}
+
+// Rename behaves like os.Rename unless read-only mode is active.
+func Rename(oldpath, newpath string) error {
+	if Enabled() {
+		return ErrReadOnly
+	}
+	return os.Rename(oldpath, newpath)
+}
+
+// Listen behaves like net.Listen unless read-only mode is active, since a
+// unix socket is created on disk.
+func Listen(network, address string) (net.Listener, error) {
+	if Enabled() {
+		return nil, ErrReadOnly
+	}
+	return net.Listen(network, address)
+}
+
+// Command prepares an external command unless read-only mode is active.
+func Command(name string, args ...string) (*exec.Cmd, error) {
+	if Enabled() {
+		return nil, ErrReadOnly
+	}
+	return exec.Command(name, args...), nil
+}
+
+// CommandContext is Command for a command killed once ctx is done.
+func CommandContext(ctx context.Context, name string, args ...string) (*exec.Cmd, error) {
+	if Enabled() {
+		return nil, ErrReadOnly
+	}
+	return exec.CommandContext(ctx, name, args...), nil
+}
@@ -2,24 +2,44 @@ package tree
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kyaoi/mdview/internal/ignore"
+	"github.com/kyaoi/mdview/internal/markdown"
 )
 
-var errNotDir = errors.New("path is not a directory")
+var (
+	errNotDir = errors.New("path is not a directory")
+	allFiles  atomic.Bool
+)
 
 // FSLoader loads tree nodes by reading the filesystem under the given root.
+// Entries matched by the repository's ignore files are left out. It is safe
+// for concurrent use, so directories can be read in the background; what
+// HasMarkdown finds is kept for the session. The lock guards only the cache
+// and the ignore rules, never the reading of directories, so that
+// invalidating from the UI does not wait for a probe of a large subtree.
 type FSLoader struct {
-	root  string
-	cache map[string]bool
+	mu     sync.Mutex
+	root   string
+	cache  map[string]bool
+	ignore *ignore.Matcher
+	// gen counts invalidations, so that a probe started before one does
+	// not cache what it found.
+	gen int
 }
 
 // NewFSLoader creates a loader that reads from the provided root directory.
 func NewFSLoader(root string) *FSLoader {
 	return &FSLoader{
-		root:  root,
-		cache: make(map[string]bool),
+		root:   root,
+		cache:  make(map[string]bool),
+		ignore: ignore.New(root),
 	}
 }
 
@@ -41,42 +61,62 @@ func (l *FSLoader) List(relPath string) ([]*Node, error) {
 
 	var nodes []*Node
 	for _, entry := range entries {
+		entry = l.resolve(relPath, entry)
 		name := entry.Name()
+		if l.skip(relPath, entry) {
+			continue
+		}
 		if entry.IsDir() {
-			if shouldSkipDir(name) {
-				continue
-			}
 			childPath := join(relPath, name)
-			has, err := l.HasMarkdown(childPath)
+			has, err := l.hasMarkdown(childPath, MaxDepth())
 			if err != nil {
 				return nil, err
 			}
 			if !has {
 				continue
 			}
-			nodes = append(nodes, &Node{
+			nodes = append(nodes, withInfo(entry, &Node{
 				Name:  name,
 				Path:  childPath,
 				IsDir: true,
-			})
+			}))
 			continue
 		}
-		if !isMarkdown(name) {
+		if !Listed(name) {
 			continue
 		}
-		nodes = append(nodes, &Node{
+		nodes = append(nodes, withInfo(entry, &Node{
 			Name:  name,
 			Path:  join(relPath, name),
 			IsDir: false,
-		})
+		}))
 	}
 	return nodes, nil
 }
 
+// withInfo fills in the metadata of n from entry. Entries removed since the
+// directory was read keep zero metadata.
+func withInfo(entry os.DirEntry, n *Node) *Node {
+	if info, err := entry.Info(); err == nil {
+		n.SetInfo(info)
+	}
+	return n
+}
+
 // HasMarkdown reports whether the path (relative to the loader root) contains at
-// least one Markdown file within its subtree.
+// least one Markdown file, or any file when all files are listed, within its
+// subtree. With a depth limit set, only
+// that many levels are read; deeper directories are assumed to hold markdown
+// so that they are listed and read when expanded.
 func (l *FSLoader) HasMarkdown(relPath string) (bool, error) {
-	if cached, ok := l.cache[relPath]; ok {
+	return l.hasMarkdown(relPath, MaxDepth())
+}
+
+// hasMarkdown probes depth levels below relPath, or everything when depth is
+// zero. Two probes of the same directory at once only do the work twice.
+func (l *FSLoader) hasMarkdown(relPath string, depth int) (bool, error) {
+	cached, ok, gen := l.cached(relPath)
+	if ok {
 		return cached, nil
 	}
 
@@ -86,32 +126,126 @@ func (l *FSLoader) HasMarkdown(relPath string) (bool, error) {
 	}
 
 	for _, entry := range entries {
+		entry = l.resolve(relPath, entry)
 		name := entry.Name()
+		if l.skip(relPath, entry) {
+			continue
+		}
 		if entry.IsDir() {
-			if shouldSkipDir(name) {
-				continue
+			if depth == 1 {
+				l.store(relPath, true, gen)
+				return true, nil
 			}
-			childPath := join(relPath, name)
-			has, err := l.HasMarkdown(childPath)
+			has, err := l.hasMarkdown(join(relPath, name), max(depth-1, 0))
 			if err != nil {
 				return false, err
 			}
 			if has {
-				l.cache[relPath] = true
+				l.store(relPath, true, gen)
 				return true, nil
 			}
 			continue
 		}
-		if isMarkdown(name) {
-			l.cache[relPath] = true
+		if Listed(name) {
+			l.store(relPath, true, gen)
 			return true, nil
 		}
 	}
 
-	l.cache[relPath] = false
+	l.store(relPath, false, gen)
 	return false, nil
 }
 
+// cached returns what is known about relPath, and the generation to store a
+// new finding under.
+func (l *FSLoader) cached(relPath string) (has, ok bool, gen int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	has, ok = l.cache[relPath]
+	return has, ok, l.gen
+}
+
+// store caches has for relPath unless the cache was invalidated since gen.
+func (l *FSLoader) store(relPath string, has bool, gen int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if gen == l.gen {
+		l.cache[relPath] = has
+	}
+}
+
+// resolve returns entry as a directory when it is a symbolic link to one that
+// is followed; see SetFollowSymlinks.
+func (l *FSLoader) resolve(relPath string, entry os.DirEntry) os.DirEntry {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return entry
+	}
+	dir := l.abs(relPath)
+	if info, ok := linkedDir(filepath.Join(dir, entry.Name()), realAncestors(l.root, dir)); ok {
+		return fs.FileInfoToDirEntry(info)
+	}
+	return entry
+}
+
+// skip reports whether entry, found in the directory relPath, is left out of
+// the tree.
+func (l *FSLoader) skip(relPath string, entry os.DirEntry) bool {
+	if entry.IsDir() && ignore.SkipDir(entry.Name()) || ignore.Hidden(entry.Name()) {
+		return true
+	}
+	// The matcher reads ignore files as it goes, which is not safe to do
+	// concurrently.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ignore.Ignored(filepath.Join(l.abs(relPath), entry.Name()), entry.IsDir())
+}
+
+// Invalidate implements Invalidator. Invalidating the root also reads the
+// ignore files again.
+func (l *FSLoader) Invalidate(relPath string) {
+	var matcher *ignore.Matcher
+	if relPath == "" {
+		matcher = ignore.New(l.root)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.gen++
+	if relPath == "" {
+		l.ignore = matcher
+	}
+	prefix := relPath + "/"
+	for path := range l.cache {
+		if relPath == "" || path == relPath || strings.HasPrefix(path, prefix) {
+			delete(l.cache, path)
+		}
+	}
+	for dir := relPath; dir != ""; {
+		i := strings.LastIndexByte(dir, '/')
+		if i < 0 {
+			dir = ""
+		} else {
+			dir = dir[:i]
+		}
+		delete(l.cache, dir)
+	}
+}
+
+// SetAllFiles chooses whether the tree lists every file instead of only
+// markdown documents.
+func SetAllFiles(all bool) {
+	allFiles.Store(all)
+}
+
+// AllFiles reports whether the tree lists every file.
+func AllFiles() bool {
+	return allFiles.Load()
+}
+
+// Listed reports whether a file called name appears in the tree.
+func Listed(name string) bool {
+	return AllFiles() || markdown.Viewable(name)
+}
+
 func (l *FSLoader) abs(relPath string) string {
 	if relPath == "" {
 		return l.root
@@ -125,17 +259,3 @@ func join(base, part string) string {
 	}
 	return base + "/" + part
 }
-
-func shouldSkipDir(name string) bool {
-	switch strings.ToLower(name) {
-	case ".git", "node_modules", ".hg", ".svn", ".idea", ".vscode":
-		return true
-	default:
-		return false
-	}
-}
-
-func isMarkdown(name string) bool {
-	lower := strings.ToLower(name)
-	return strings.HasSuffix(lower, ".md") || strings.HasSuffix(lower, ".mdx")
-}
@@ -0,0 +1,114 @@
+package tree
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kyaoi/mdview/internal/ignore"
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// MarkdownFiles lists every document below root, markdown or converted to
+// markdown, as slash-separated paths relative to it, skipping the same
+// entries as the tree.
+func MarkdownFiles(root string) ([]string, error) {
+	var files []string
+	matcher := ignore.New(root)
+	err := Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && Skip(matcher, path, d) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !markdown.Viewable(d.Name()) || Skip(matcher, path, d) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Skip reports whether the walked entry at path is left out of the tree: a
+// skipped directory such as .git, a hidden entry, or an entry matched by
+// matcher.
+func Skip(matcher *ignore.Matcher, path string, d fs.DirEntry) bool {
+	if d.IsDir() && ignore.SkipDir(d.Name()) || ignore.Hidden(d.Name()) {
+		return true
+	}
+	return matcher.Ignored(path, d.IsDir())
+}
+
+// Dirs lists root and every directory below it, skipping the same
+// directories as the tree. Unreadable directories are left out rather than
+// failing the walk.
+func Dirs(root string) []string {
+	var dirs []string
+	matcher := ignore.New(root)
+	_ = Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && Skip(matcher, path, d) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs
+}
+
+// Entry is a file or directory recorded by Scan.
+type Entry struct {
+	// Path is slash-separated and relative to the scanned root.
+	Path    string    `json:"path"`
+	IsDir   bool      `json:"dir,omitempty"`
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size,omitempty"`
+}
+
+// Scan records every file listed in the tree and every directory below root,
+// the root itself excepted, skipping the same entries as the tree.
+func Scan(root string) ([]Entry, error) {
+	var entries []Entry
+	matcher := ignore.New(root)
+	err := Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() && Skip(matcher, path, d) {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && (!Listed(d.Name()) || Skip(matcher, path, d)) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entry := Entry{Path: filepath.ToSlash(rel), IsDir: d.IsDir()}
+		if info, err := d.Info(); err == nil {
+			entry.ModTime = info.ModTime()
+			if !d.IsDir() {
+				entry.Size = info.Size()
+			}
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
@@ -0,0 +1,133 @@
+package tree
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// IndexLoader serves the tree from entries recorded by Scan, such as the ones
+// kept by the daemon, instead of reading the disk. Directories that change
+// afterwards are invalidated and read from disk again.
+type IndexLoader struct {
+	fs       *FSLoader
+	root     string
+	entries  []Entry
+	children map[string][]*Node
+	stale    map[string]bool
+	// dropped is set once the whole index was invalidated.
+	dropped bool
+}
+
+// NewIndexLoader builds a loader for root from entries.
+func NewIndexLoader(root string, entries []Entry) *IndexLoader {
+	l := &IndexLoader{
+		fs:       NewFSLoader(root),
+		root:     root,
+		entries:  entries,
+		children: make(map[string][]*Node),
+		stale:    make(map[string]bool),
+	}
+	dirs := make(map[string]Entry)
+	for _, entry := range entries {
+		if entry.IsDir {
+			dirs[entry.Path] = entry
+		}
+	}
+	// Only directories leading to a markdown file are shown, as with
+	// FSLoader.
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		parent := ""
+		parts := strings.Split(entry.Path, "/")
+		for i, part := range parts {
+			path := join(parent, part)
+			if !seen[path] {
+				seen[path] = true
+				node := &Node{Name: part, Path: path, IsDir: i < len(parts)-1}
+				if node.IsDir {
+					node.ModTime = dirs[path].ModTime
+				} else {
+					node.ModTime, node.Size = entry.ModTime, entry.Size
+				}
+				l.children[parent] = append(l.children[parent], node)
+			}
+			parent = path
+		}
+	}
+	return l
+}
+
+// List implements Loader.
+func (l *IndexLoader) List(relPath string) ([]*Node, error) {
+	if l.stale[relPath] {
+		return l.fs.List(relPath)
+	}
+	var nodes []*Node
+	for _, node := range l.children[relPath] {
+		copied := *node
+		nodes = append(nodes, &copied)
+	}
+	return nodes, nil
+}
+
+// Invalidate implements Invalidator. The directories holding relPath, and
+// everything below relPath, are read from disk from then on.
+func (l *IndexLoader) Invalidate(relPath string) {
+	l.fs.Invalidate(relPath)
+	if relPath == "" {
+		l.dropped = true
+	}
+	l.stale[relPath] = true
+	for dir := relPath; dir != ""; {
+		i := strings.LastIndexByte(dir, '/')
+		if i < 0 {
+			dir = ""
+		} else {
+			dir = dir[:i]
+		}
+		l.stale[dir] = true
+	}
+	prefix := relPath + "/"
+	for path := range l.children {
+		if relPath == "" || strings.HasPrefix(path, prefix) {
+			l.stale[path] = true
+		}
+	}
+}
+
+// Dirs returns the root and the indexed directories as absolute paths, so
+// that they can be watched without walking the disk. Once the whole index
+// was invalidated they are read from disk.
+func (l *IndexLoader) Dirs() []string {
+	if l.dropped {
+		return Dirs(l.root)
+	}
+	dirs := []string{l.root}
+	for _, entry := range l.entries {
+		if entry.IsDir {
+			dirs = append(dirs, filepath.Join(l.root, filepath.FromSlash(entry.Path)))
+		}
+	}
+	return dirs
+}
+
+// Files returns the indexed documents relative to the root, in order.
+// Once the whole index was invalidated they are read from disk.
+func (l *IndexLoader) Files() []string {
+	if l.dropped {
+		files, _ := MarkdownFiles(l.root)
+		return files
+	}
+	var files []string
+	for _, entry := range l.entries {
+		if !entry.IsDir && markdown.Viewable(entry.Path) {
+			files = append(files, entry.Path)
+		}
+	}
+	return files
+}
@@ -1,8 +1,8 @@
 package tree
 
 import (
-	"sort"
-	"strings"
+	"io/fs"
+	"time"
 )
 
 // Loader retrieves child entries for a particular node path.
@@ -10,6 +10,23 @@ type Loader interface {
 	List(path string) ([]*Node, error)
 }
 
+// Invalidator is implemented by loaders that cache what they read, so that
+// the cache can be dropped when the disk changes.
+type Invalidator interface {
+	// Invalidate forgets what is cached about path, its ancestors and its
+	// descendants.
+	Invalidate(path string)
+}
+
+// Indexed is implemented by loaders that already know every directory and
+// markdown file below their root, such as IndexLoader.
+type Indexed interface {
+	// Dirs returns the absolute paths of the root and its directories.
+	Dirs() []string
+	// Files returns the markdown files relative to the root.
+	Files() []string
+}
+
 // Node represents a single entry in the file tree.
 type Node struct {
 	Name     string
@@ -18,9 +35,15 @@ type Node struct {
 	Open     bool
 	Parent   *Node
 	Children []*Node
+	// ModTime and Size describe the entry on disk; they are zero when
+	// unknown.
+	ModTime time.Time
+	Size    int64
 
-	loader Loader
-	loaded bool
+	loader   Loader
+	loaded   bool
+	loading  bool
+	sortMode SortMode
 }
 
 // NewRoot creates the root node for the tree.
@@ -54,27 +77,133 @@ func (n *Node) EnsureLoaded() error {
 	if err != nil {
 		return err
 	}
+	n.setChildren(children)
+	return nil
+}
+
+// Loaded reports whether the node's children are known: it is a file, a
+// directory already read, or part of a tree built in memory.
+func (n *Node) Loaded() bool {
+	return !n.IsDir || n.loaded || n.loader == nil
+}
+
+// Loading reports whether the directory is being read in the background.
+func (n *Node) Loading() bool {
+	return n.loading
+}
 
+// LoadFunc marks the directory as loading and returns the function reading
+// its children, to be run off the UI goroutine with its result handed to
+// FinishLoad. It returns nil when the children are known or already being
+// read.
+func (n *Node) LoadFunc() func() ([]*Node, error) {
+	if n.Loaded() || n.loading {
+		return nil
+	}
+	n.loading = true
+	loader, path := n.loader, n.Path
+	return func() ([]*Node, error) {
+		return loader.List(path)
+	}
+}
+
+// FinishLoad installs the children read by the function from LoadFunc. A
+// directory loaded synchronously in the meantime keeps its children.
+func (n *Node) FinishLoad(children []*Node, err error) error {
+	n.loading = false
+	if err != nil || n.loaded {
+		return err
+	}
+	n.setChildren(children)
+	return nil
+}
+
+func (n *Node) setChildren(children []*Node) {
 	n.Children = children
 	for _, child := range n.Children {
 		child.Parent = n
 		child.loader = n.loader
+		child.sortMode = n.sortMode
 	}
 	n.sortChildren()
 	n.loaded = true
+}
+
+// HasLoader reports whether the node's children are read from a loader, as
+// opposed to a tree built in memory.
+func (n *Node) HasLoader() bool {
+	return n.loader != nil
+}
+
+// Invalidate drops what the node's loader has cached about path, e.g. after
+// it was created or removed.
+func (n *Node) Invalidate(path string) {
+	if inv, ok := n.loader.(Invalidator); ok {
+		inv.Invalidate(path)
+	}
+}
+
+// Index returns the node's loader when it knows the whole tree up front.
+func (n *Node) Index() (Indexed, bool) {
+	index, ok := n.loader.(Indexed)
+	return index, ok
+}
+
+// Refresh lists the children of a loaded directory again after it changed on
+// disk. Entries that still exist keep their nodes, so open directories stay
+// open; a directory that was never loaded is left to load lazily.
+func (n *Node) Refresh() error {
+	if !n.IsDir || !n.loaded || n.loader == nil {
+		return nil
+	}
+	children, err := n.loader.List(n.Path)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]*Node, len(n.Children))
+	for _, child := range n.Children {
+		previous[child.Name] = child
+	}
+	for i, child := range children {
+		if old, ok := previous[child.Name]; ok && old.IsDir == child.IsDir {
+			old.ModTime, old.Size = child.ModTime, child.Size
+			children[i] = old
+			continue
+		}
+		child.Parent = n
+		child.loader = n.loader
+		child.sortMode = n.sortMode
+	}
+	n.Children = children
+	n.sortChildren()
 	return nil
 }
 
-func (n *Node) sortChildren() {
-	sort.Slice(n.Children, func(i, j int) bool {
-		ci, cj := n.Children[i], n.Children[j]
-		switch {
-		case ci.IsDir == cj.IsDir:
-			return strings.ToLower(ci.Name) < strings.ToLower(cj.Name)
-		case ci.IsDir:
-			return true
-		default:
-			return false
+// Reload drops everything the loader cached below n and lists every loaded
+// directory again, keeping the nodes of entries that still exist.
+func (n *Node) Reload() error {
+	n.Invalidate(n.Path)
+	return n.refreshLoaded()
+}
+
+func (n *Node) refreshLoaded() error {
+	if err := n.Refresh(); err != nil {
+		return err
+	}
+	for _, child := range n.Children {
+		if child.IsDir && child.loaded {
+			if err := child.refreshLoaded(); err != nil {
+				return err
+			}
 		}
-	})
+	}
+	return nil
+}
+
+// SetInfo records the metadata of the file backing n.
+func (n *Node) SetInfo(info fs.FileInfo) {
+	n.ModTime = info.ModTime()
+	if !info.IsDir() {
+		n.Size = info.Size()
+	}
 }
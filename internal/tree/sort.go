@@ -0,0 +1,71 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortMode orders the entries of a directory. Directories always come first.
+type SortMode string
+
+const (
+	// SortName orders entries alphabetically, ignoring case.
+	SortName SortMode = "name"
+	// SortModTime puts the most recently modified entries first.
+	SortModTime SortMode = "mtime"
+	// SortSize puts the largest files first; directories stay alphabetical.
+	SortSize SortMode = "size"
+)
+
+var sortModes = []SortMode{SortName, SortModTime, SortSize}
+
+// ParseSortMode validates a sort mode name, accepting "" as name.
+func ParseSortMode(name string) (SortMode, error) {
+	mode := SortMode(strings.ToLower(strings.TrimSpace(name)))
+	if mode == "" {
+		return SortName, nil
+	}
+	for _, m := range sortModes {
+		if m == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("不明な並び順 %q (name, mtime, size のいずれか)", name)
+}
+
+// Next returns the mode that follows s when cycling through the modes.
+func (s SortMode) Next() SortMode {
+	for i, m := range sortModes {
+		if m == s {
+			return sortModes[(i+1)%len(sortModes)]
+		}
+	}
+	return SortName
+}
+
+// Sort re-orders the children of n and of every descendant already in memory
+// by mode. Directories loaded later are sorted the same way.
+func (n *Node) Sort(mode SortMode) {
+	n.sortMode = mode
+	n.sortChildren()
+	for _, child := range n.Children {
+		child.Sort(mode)
+	}
+}
+
+func (n *Node) sortChildren() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		ci, cj := n.Children[i], n.Children[j]
+		if ci.IsDir != cj.IsDir {
+			return ci.IsDir
+		}
+		switch {
+		case n.sortMode == SortModTime && !ci.ModTime.Equal(cj.ModTime):
+			return ci.ModTime.After(cj.ModTime)
+		case n.sortMode == SortSize && !ci.IsDir && ci.Size != cj.Size:
+			return ci.Size > cj.Size
+		}
+		return strings.ToLower(ci.Name) < strings.ToLower(cj.Name)
+	})
+}
@@ -0,0 +1,132 @@
+package tree
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	followSymlinks atomic.Bool
+	maxDepth       atomic.Int64
+)
+
+// SetMaxDepth limits how many directory levels below a root are read by Walk
+// and by the tree's probing for markdown. Zero removes the limit.
+func SetMaxDepth(depth int) {
+	maxDepth.Store(int64(max(depth, 0)))
+}
+
+// MaxDepth returns the directory depth limit, zero meaning none.
+func MaxDepth() int {
+	return int(maxDepth.Load())
+}
+
+// SetFollowSymlinks chooses whether symbolic links to directories are listed
+// and descended into like ordinary directories. Links leading back into a
+// directory they are reached from are left out, so cycles end.
+func SetFollowSymlinks(follow bool) {
+	followSymlinks.Store(follow)
+}
+
+// FollowSymlinks reports whether symbolic links to directories are followed.
+func FollowSymlinks() bool {
+	return followSymlinks.Load()
+}
+
+// Walk behaves like filepath.WalkDir, except that root may be a symbolic link
+// and, when following them is enabled, so may the directories below it.
+// Directories at the depth limit are visited but not read.
+func Walk(root string, fn fs.WalkDirFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(root, root, 0, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walkDir visits path, depth levels below root, and for a directory
+// everything below it.
+func walkDir(root, path string, depth int, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+			return nil
+		}
+		return err
+	}
+	if limit := MaxDepth(); limit > 0 && depth >= limit {
+		return nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if err := fn(path, d, err); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				return nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		name := filepath.Join(path, entry.Name())
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if info, ok := linkedDir(name, realAncestors(root, path)); ok {
+				entry = fs.FileInfoToDirEntry(info)
+			}
+		}
+		if err := walkDir(root, name, depth+1, entry, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// linkedDir reports whether the symbolic link at path leads to a directory
+// that should be followed: following is enabled and the target is neither
+// one of the ancestors, given as real paths, nor above them. It returns the
+// target's information.
+func linkedDir(path string, ancestors []string) (fs.FileInfo, bool) {
+	if !FollowSymlinks() {
+		return nil, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return nil, false
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, false
+	}
+	for _, dir := range ancestors {
+		if dir == real || strings.HasPrefix(dir, real+string(filepath.Separator)) {
+			return nil, false
+		}
+	}
+	return info, true
+}
+
+// realAncestors returns the real paths of dir and of each directory above it
+// up to root. It is only computed for links, which are rare.
+func realAncestors(root, dir string) []string {
+	var ancestors []string
+	for {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			ancestors = append(ancestors, real)
+		}
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			return ancestors
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,81 @@
+package ui
+
+// anchorTextMinKey is the shortest match key trusted to find a line of text
+// again; shorter ones, such as list bullets, repeat too often.
+const anchorTextMinKey = 3
+
+// scrollAnchor remembers what the top of the viewport shows, so a reload can
+// bring the same place back into view even when the text above it changed
+// length.
+type scrollAnchor struct {
+	offset int
+	// heading is the match key of the heading owning the top of the
+	// viewport and occurrence tells repeated titles apart; headingDelta is
+	// how far below the heading the top is.
+	heading      string
+	occurrence   int
+	headingDelta int
+	// text is the match key of the first line with text from the top down,
+	// textDelta its row in the viewport.
+	text      string
+	textDelta int
+}
+
+// captureScrollAnchor records the place shown at the top of the viewport.
+func (m *Model) captureScrollAnchor() scrollAnchor {
+	anchor := scrollAnchor{offset: m.contentVP.YOffset}
+	if current := m.currentHeadingIndex(); current >= 0 {
+		h := m.headings[current]
+		anchor.heading = matchKey(h.Text)
+		for _, other := range m.headings[:current] {
+			if matchKey(other.Text) == anchor.heading {
+				anchor.occurrence++
+			}
+		}
+		anchor.headingDelta = anchor.offset - h.renderedLine
+	}
+	plain := m.plainLines()
+	for i := anchor.offset; i < len(plain) && i < anchor.offset+m.contentVP.Height; i++ {
+		if key := matchKey(plain[i]); len(key) >= anchorTextMinKey {
+			anchor.text, anchor.textDelta = key, i-anchor.offset
+			break
+		}
+	}
+	return anchor
+}
+
+// restoreScrollAnchor scrolls the re-rendered document back to anchor: to
+// the same heading first, then to the same line of text nearest to where it
+// should be, falling back to the old offset.
+func (m *Model) restoreScrollAnchor(anchor scrollAnchor) {
+	target := anchor.offset
+	if anchor.heading != "" {
+		seen := 0
+		for _, h := range m.headings {
+			if matchKey(h.Text) != anchor.heading {
+				continue
+			}
+			if seen == anchor.occurrence {
+				target = h.renderedLine + anchor.headingDelta
+				break
+			}
+			seen++
+		}
+	}
+	if anchor.text != "" {
+		expected := target + anchor.textDelta
+		best := -1
+		for i, line := range m.plainLines() {
+			if matchKey(line) != anchor.text {
+				continue
+			}
+			if best < 0 || absInt(i-expected) < absInt(best-expected) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			target = best - anchor.textDelta
+		}
+	}
+	m.contentVP.SetYOffset(max(target, 0))
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// anchorStyle dims the anchors shown next to headings.
+var anchorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#565f89"))
+
+// headingAnchors returns the anchor of every indexed heading in the
+// configured slug style, numbering repeated titles as the platforms do.
+func (m *Model) headingAnchors() []string {
+	slugger := markdown.NewSlugger(m.opts.Slug)
+	anchors := make([]string, len(m.headings))
+	for i, h := range m.headings {
+		anchors[i] = slugger.Slug(h.Text)
+	}
+	return anchors
+}
+
+// withHeadingAnchors appends the anchor of each heading to its rendered
+// line, in place of the padding glamour leaves there.
+func (m *Model) withHeadingAnchors(rendered string) string {
+	if len(m.headings) == 0 {
+		return rendered
+	}
+	lines := strings.Split(rendered, "\n")
+	for i, anchor := range m.headingAnchors() {
+		n := m.headings[i].renderedLine
+		if n >= len(lines) || anchor == "" {
+			continue
+		}
+		width := ansi.StringWidth(strings.TrimRight(ansi.Strip(lines[n]), " "))
+		line := ansi.Truncate(lines[n], width, "") + "  " + anchorStyle.Render("#"+anchor)
+		lines[n] = ansi.Truncate(line, max(m.wrapWidth, width), "…")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// toggleAnchors shows or hides the anchors next to headings.
+func (m *Model) toggleAnchors() {
+	m.showAnchors = !m.showAnchors
+	m.renderMarkdown()
+}
+
+// yankAnchor copies a file.md#anchor link to the heading owning the top of
+// the viewport, the first heading above it, to the clipboard. The path is
+// relative to the vault root so the link works from other documents there.
+func (m *Model) yankAnchor() {
+	if m.activeAbsPath == "" {
+		m.err = errors.New("ファイルが開かれていません")
+		return
+	}
+	link := m.activeWikiPath()
+	if link == "" {
+		link = filepath.Base(m.activeAbsPath)
+	}
+	if index := max(m.currentHeadingIndex(), 0); index < len(m.headings) {
+		link += "#" + m.headingAnchors()[index]
+	}
+	copyToClipboard(link)
+	m.notice = "コピーしました: " + link
+}
+
+// copyToClipboard sets the system clipboard with an OSC 52 sequence, which
+// terminals honour over SSH too and which needs no external command. It is
+// written only when stdout is a terminal, so headless runs stay clean.
+func copyToClipboard(text string) {
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	_, _ = os.Stdout.WriteString(ansi.SetSystemClipboard(text))
+}
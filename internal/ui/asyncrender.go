@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// renderAsyncAfter is how long a render may block the update loop. Renders
+// taking longer finish in the background while the previous output stays
+// on screen.
+const renderAsyncAfter = 100 * time.Millisecond
+
+type renderResult struct {
+	out string
+	err error
+}
+
+// renderJob is a render still running in the background.
+type renderJob struct {
+	gen      int
+	done     chan renderResult
+	deadline time.Time
+	key      renderKey
+	anywhere bool
+	// started is when the render began, for the debug log.
+	started time.Time
+	// partial is set when the start of the document is already shown,
+	// so the output replacing it keeps the scroll offset rather than the
+	// proportion.
+	partial bool
+	// awaited is set once a command waits for the job.
+	awaited bool
+}
+
+// renderedMsg delivers the output of the background render numbered gen.
+type renderedMsg struct {
+	gen int
+	renderResult
+}
+
+// startRender renders src with glamour in a goroutine, or takes the output
+// from the render cache when src was rendered before. Output arriving
+// within renderAsyncAfter is installed at once, so most renders behave as if
+// they were synchronous; otherwise the job is left to awaitRender. A
+// render that outlives the render timeout cannot be cancelled, so like an
+// abandoned one it keeps its renderer and a fresh one is made for later
+// renders.
+func (m *Model) startRender(src string, anywhere bool) {
+	if m.rendering != nil {
+		m.replaceRenderer()
+	}
+	m.rendering = nil
+	m.renderGen++
+	key := m.renderKey(src)
+	if out, ok := m.renderCache.get(key); ok {
+		debugf("render %d bytes: cached", len(src))
+		m.finishRender(out, nil, anywhere)
+		return
+	}
+	timeout := m.renderTimeout()
+	if len(src) > chunkRenderAbove && !m.opts.Sync && m.startChunkedRender(src, key, anywhere, timeout) {
+		return
+	}
+	started := time.Now()
+	done := make(chan renderResult, 1)
+	renderer := m.renderer
+	go func() {
+		out, err := renderer.Render(src)
+		done <- renderResult{out: out, err: err}
+	}()
+
+	wait := min(renderAsyncAfter, timeout)
+	if m.opts.Sync {
+		wait = timeout
+	}
+	select {
+	case res := <-done:
+		debugf("render %d bytes: %s", len(src), time.Since(started))
+		if res.err == nil {
+			m.renderCache.put(key, res.out)
+		}
+		m.finishRender(res.out, res.err, anywhere)
+		return
+	case <-time.After(wait):
+	}
+	if wait == timeout {
+		debugf("render %d bytes: timed out after %s", len(src), timeout)
+		m.replaceRenderer()
+		m.finishRender("", errRenderTimeout, anywhere)
+		return
+	}
+	m.rendering = &renderJob{
+		gen:      m.renderGen,
+		done:     done,
+		deadline: time.Now().Add(timeout - wait),
+		key:      key,
+		anywhere: anywhere,
+		started:  started,
+	}
+}
+
+// replaceRenderer gives later renders a renderer of their own, leaving the
+// current one to the render still using it.
+func (m *Model) replaceRenderer() {
+	if fresh, err := newRenderer(m.wrapWidth, m.opts); err == nil {
+		m.renderer = fresh
+	}
+}
+
+// awaitRender returns the commands that wait for the background render and
+// animate the spinner meanwhile, once per job.
+func (m *Model) awaitRender() tea.Cmd {
+	job := m.rendering
+	if job == nil || job.awaited {
+		return nil
+	}
+	job.awaited = true
+	wait := func() tea.Msg {
+		select {
+		case res := <-job.done:
+			return renderedMsg{gen: job.gen, renderResult: res}
+		case <-time.After(time.Until(job.deadline)):
+			return renderedMsg{gen: job.gen, renderResult: renderResult{err: errRenderTimeout}}
+		}
+	}
+	return tea.Batch(wait, m.spinner.Tick)
+}
+
+// handleRendered installs the output of the background render unless a
+// later render replaced it, keeping the reader at the same place.
+func (m *Model) handleRendered(msg renderedMsg) {
+	job := m.rendering
+	if job == nil || msg.gen != job.gen {
+		return
+	}
+	m.rendering = nil
+	debugf("render in background: %s (err %v)", time.Since(job.started), msg.err)
+	if errors.Is(msg.err, errRenderTimeout) {
+		m.replaceRenderer()
+	}
+	if msg.err == nil {
+		m.renderCache.put(job.key, msg.out)
+	}
+	ratio, offset := m.scrollRatio(), m.contentVP.YOffset
+	m.finishRender(msg.out, msg.err, job.anywhere)
+	switch target := m.afterRender; {
+	case target != nil:
+		m.afterRender = nil
+		target()
+	case job.partial:
+		m.contentVP.SetYOffset(offset)
+	default:
+		m.setScrollRatio(ratio)
+	}
+	m.applyInitialPosition()
+	if m.searchActive {
+		m.searchIncrementally(m.searchInput.Value())
+	}
+}
+
+// whenRendered runs place, which scrolls to a heading, line or match of the
+// document, once that document is shown: at once unless a render is still
+// running in the background, whose output would otherwise land at the old
+// scroll proportion, or the document is a large file waiting for the reader
+// to say how to open it. A later call replaces an earlier place.
+func (m *Model) whenRendered(place func()) {
+	if m.largePrompt != nil {
+		m.largePrompt.place = place
+		return
+	}
+	if m.rendering != nil {
+		m.afterRender = place
+		return
+	}
+	place()
+}
+
+// handleSpinner advances the spinner while a render runs in the background.
+func (m *Model) handleSpinner(msg spinner.TickMsg) tea.Cmd {
+	if m.rendering == nil {
+		return nil
+	}
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return cmd
+}
+
+// renderingStatus shows the spinner while a render runs in the background.
+func (m *Model) renderingStatus() string {
+	if m.rendering == nil {
+		return ""
+	}
+	return m.spinner.View() + " レンダリング中"
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/linkgraph"
+)
+
+var listSelectedStyle = lipgloss.NewStyle().Reverse(true)
+
+// backlinkIndex maps every document of the vault to the links pointing at
+// it, markdown links and wikilinks alike. It is built on first use and
+// dropped whenever a document in the tree changes.
+func (m *Model) backlinkIndex() map[string][]linkgraph.Edge {
+	if m.backlinks != nil {
+		return m.backlinks
+	}
+	m.backlinks = make(map[string][]linkgraph.Edge)
+	for _, edge := range m.linkGraph().Edges() {
+		m.backlinks[edge.To] = append(m.backlinks[edge.To], edge)
+	}
+	return m.backlinks
+}
+
+// activeBacklinks lists the links pointing at the open document.
+func (m *Model) activeBacklinks() []linkgraph.Edge {
+	if m.activeAbsPath == "" || m.vaultRoot() == "" {
+		return nil
+	}
+	return m.backlinkIndex()[m.activeWikiPath()]
+}
+
+// toggleBacklinks shows the backlinks pane and moves the keys into it. With
+// the pane shown but not focused it hides the pane instead.
+func (m *Model) toggleBacklinks() {
+	if m.backlinksVisible && !m.backlinksFocus {
+		m.backlinksVisible = false
+		m.resize(m.width, m.height)
+		return
+	}
+	m.backlinksVisible, m.backlinksFocus = true, true
+	m.linksVisible, m.linksFocus = false, false
+	m.historyVisible, m.historyFocus = false, false
+	m.hideGrepPane()
+	m.backlinkSelection = 0
+	m.resize(m.width, m.height)
+}
+
+func (m *Model) handleBacklinksKey(key string) tea.Cmd {
+	links := m.activeBacklinks()
+	switch key {
+	case "j", "down":
+		m.backlinkSelection = min(m.backlinkSelection+1, max(len(links)-1, 0))
+	case "k", "up":
+		m.backlinkSelection = max(m.backlinkSelection-1, 0)
+	case "esc":
+		m.backlinksFocus = false
+	case "b", "q":
+		m.backlinksVisible, m.backlinksFocus = false, false
+		m.resize(m.width, m.height)
+	case "enter":
+		if m.backlinkSelection >= len(links) {
+			return nil
+		}
+		link := links[m.backlinkSelection]
+		cmd, err := m.editFile(link.From)
+		if err != nil {
+			m.err = err
+			return nil
+		}
+		m.backlinkSelection = 0
+		m.whenRendered(func() { m.contentVP.SetYOffset(m.renderedLineForSource(link.Line + 1)) })
+		return cmd
+	}
+	return nil
+}
+
+// backlinksHeight returns the rows taken by the backlinks pane out of height.
+func (m *Model) backlinksHeight(height int) int {
+	if !m.backlinksVisible {
+		return 0
+	}
+	return listPaneHeight(height)
+}
+
+// backlinksView renders the title row and the links, keeping the selection
+// in view.
+func (m *Model) backlinksView() string {
+	links := m.activeBacklinks()
+	title := fmt.Sprintf(" バックリンク (%d)", len(links))
+	if m.backlinksFocus {
+		title += "  (j/k: 選択 / Enter: 開く / Esc: 本文へ戻る / b: 閉じる)"
+	}
+	rows := make([]string, len(links))
+	for i, link := range links {
+		rows[i] = fmt.Sprintf(" %s:%d  %s", link.From, link.Line+1, link.Context)
+	}
+	if len(rows) == 0 {
+		rows = append(rows, " このファイルへのリンクはありません")
+	}
+	return m.listPaneView(title, rows, m.backlinkSelection, m.backlinksFocus && len(links) > 0)
+}
+
+// listPaneHeight returns the rows taken by a list pane below the body, such
+// as the backlinks, out of height.
+func listPaneHeight(height int) int {
+	return clamp(height/4, minScratchHeight, max(height-minScratchHeight, minScratchHeight))
+}
+
+// listPaneView renders a list pane: the title row and the rows, keeping the
+// selection in view and highlighting it when selected is set.
+func (m *Model) listPaneView(title string, rows []string, selection int, selected bool) string {
+	height := listPaneHeight(m.height - headerHeight - statusBarHeight)
+	lines := []string{scratchTitleStyle.Width(m.width).Render(ansi.Truncate(title, m.width, "…"))}
+	first := max(0, selection-(height-2))
+	for i := first; i < len(rows) && len(lines) < height; i++ {
+		row := ansi.Truncate(rows[i], m.width, "…")
+		if selected && i == selection {
+			row = listSelectedStyle.Render(row)
+		}
+		lines = append(lines, row)
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// calloutColors are the 256-color palette indexes callout kinds are drawn in.
+var calloutColors = map[string]string{
+	"note": "39", "todo": "39", "abstract": "44", "tip": "42", "success": "42",
+	"important": "141", "example": "141", "question": "214", "warning": "214",
+	"caution": "203", "failure": "203", "danger": "203", "bug": "203", "quote": "245",
+}
+
+// colorCallouts colors the icon and the block quote bar of the callouts
+// marked by markdown.Callouts, and removes the marks.
+func colorCallouts(rendered string) string {
+	if !strings.Contains(rendered, markdown.CalloutMark) {
+		return rendered
+	}
+	lines := strings.Split(rendered, "\n")
+	on := ""
+	for i, line := range lines {
+		if at := strings.Index(line, markdown.CalloutMark); at >= 0 {
+			rest := line[at+len(markdown.CalloutMark):]
+			icon, size := utf8.DecodeRuneInString(rest)
+			on = ""
+			if color := calloutColors[markdown.CalloutKind(string(icon))]; color != "" {
+				on = "\x1b[38;5;" + color + "m"
+				rest = on + string(icon) + "\x1b[39m" + rest[size:]
+			}
+			line = line[:at] + rest
+		} else if on != "" && quoteBar(line) == "" {
+			on = ""
+		}
+		if on != "" {
+			if bar := quoteBar(line); bar != "" {
+				at := strings.Index(line, bar)
+				line = line[:at] + on + bar + "\x1b[39m" + line[at+len(bar):]
+			}
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quoteBar returns the block quote bar a rendered line starts with, or ""
+// when the line is not part of a block quote.
+func quoteBar(line string) string {
+	plain := strings.TrimLeft(ansi.Strip(line), " ")
+	for _, bar := range []string{"│", "|"} {
+		if strings.HasPrefix(plain, bar) {
+			return bar
+		}
+	}
+	return ""
+}
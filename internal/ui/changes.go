@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/linediff"
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// changeHighlightDuration is how long the lines changed by a reload stay
+// highlighted.
+const changeHighlightDuration = 3 * time.Second
+
+// changesFadedMsg ends the highlight of the reload numbered gen.
+type changesFadedMsg struct{ gen int }
+
+// showReloaded replaces the document with content, as re-read after it
+// changed, keeping the same place in view. The added and edited lines are
+// highlighted until the returned command fades them.
+func (m *Model) showReloaded(content string) tea.Cmd {
+	var changed map[int]bool
+	m.reloadChanged = 0
+	if content != m.rawContent {
+		script := linediff.Diff(strings.Split(m.rawContent, "\n"), strings.Split(content, "\n"))
+		inserted, deleted := linediff.Stats(script)
+		// An edited line counts once, not as a deletion and an insertion.
+		m.reloadChanged = max(inserted, deleted)
+		line := 0
+		for _, l := range script {
+			switch l.Kind {
+			case linediff.Insert:
+				if changed == nil {
+					changed = make(map[int]bool)
+				}
+				changed[line] = true
+				line++
+			case linediff.Equal:
+				line++
+			}
+		}
+	}
+	anchor := m.captureScrollAnchor()
+	m.setRawContent(content)
+	m.changedLines = changed
+	m.renderMarkdown()
+	m.whenRendered(func() {
+		if m.err == nil {
+			m.restoreScrollAnchor(anchor)
+		}
+	})
+	if changed == nil {
+		return nil
+	}
+	m.changeGen++
+	gen := m.changeGen
+	return tea.Tick(changeHighlightDuration, func(time.Time) tea.Msg {
+		return changesFadedMsg{gen: gen}
+	})
+}
+
+// reloadAndAnnounce reloads the active file after it changed on disk and
+// says so in the status bar.
+func (m *Model) reloadAndAnnounce() tea.Cmd {
+	m.reloadChanged = 0
+	cmd := m.reloadActiveFile()
+	m.announceReload()
+	return cmd
+}
+
+// announceReload reports the last reload in the status bar with the number
+// of lines it changed and when. The message goes when the highlight fades.
+func (m *Model) announceReload() {
+	if m.reloadChanged == 0 {
+		return
+	}
+	m.notice = fmt.Sprintf("再読み込みしました (%d 行変更, %s)", m.reloadChanged, time.Now().Format("15:04:05"))
+	m.reloadNotice = m.notice
+}
+
+// fadeChanges removes the highlight of the last reload unless a later one
+// replaced it.
+func (m *Model) fadeChanges(msg changesFadedMsg) {
+	if msg.gen != m.changeGen || m.changedLines == nil {
+		return
+	}
+	m.changedLines = nil
+	if m.notice != "" && m.notice == m.reloadNotice {
+		m.notice = ""
+	}
+	offset := m.contentVP.YOffset
+	m.renderMarkdown()
+	m.contentVP.SetYOffset(offset)
+}
+
+// changesSource marks the lines changed by the last reload in the active
+// document for highlightDiff.
+func (m *Model) changesSource() string {
+	lines := strings.Split(m.rawContent, "\n")
+	script := make([]linediff.Line, len(lines))
+	for i, line := range lines {
+		script[i] = linediff.Line{Kind: linediff.Equal, Text: line}
+		if m.changedLines[i] {
+			script[i].Kind = linediff.Insert
+		}
+	}
+	return markdown.MarkDiff(script)
+}
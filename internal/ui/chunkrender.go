@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// Documents whose markdown exceeds chunkRenderAbove bytes are rendered in
+// pieces of about renderChunkSize bytes: the first is shown at once and
+// the rest are rendered in parallel in the background.
+const (
+	chunkRenderAbove = 128 << 10
+	renderChunkSize  = 32 << 10
+)
+
+// startChunkedRender shows the first of chunks right away and leaves the
+// whole document to a background job, which replaces it once every chunk is
+// rendered. It reports false when src has no place to cut.
+func (m *Model) startChunkedRender(src string, key renderKey, anywhere bool, timeout time.Duration) bool {
+	chunks := markdown.Chunks(src, renderChunkSize)
+	if len(chunks) < 2 {
+		return false
+	}
+	started := time.Now()
+	first, err := m.renderer.Render(chunks[0])
+	debugf("render %d bytes in %d chunks: first shown after %s", len(src), len(chunks), time.Since(started))
+	if err != nil {
+		m.finishRender("", err, anywhere)
+		return true
+	}
+	done := make(chan renderResult, 1)
+	width, opts := m.wrapWidth, m.opts
+	go func() {
+		rest, err := renderChunks(chunks[1:], width, opts)
+		done <- renderResult{out: joinRendered(append([]string{first}, rest...)), err: err}
+	}()
+	m.rendering = &renderJob{
+		gen:      m.renderGen,
+		done:     done,
+		deadline: time.Now().Add(timeout),
+		key:      key,
+		anywhere: anywhere,
+		partial:  true,
+		started:  started,
+	}
+	m.finishRender(first, nil, anywhere)
+	return true
+}
+
+// renderChunks renders chunks in parallel, each worker with a renderer of
+// its own since a renderer is not safe for concurrent use.
+func renderChunks(chunks []string, width int, opts Options) ([]string, error) {
+	out := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(chunks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			renderer, err := newRenderer(width, opts)
+			for i := range next {
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				out[i], errs[i] = renderer.Render(chunks[i])
+			}
+		}()
+	}
+	for i := range chunks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// joinRendered joins the separately rendered chunks of a document with the
+// blank line glamour puts between blocks, dropping the document margins
+// each render adds at its ends.
+func joinRendered(parts []string) string {
+	for i := range parts {
+		if i > 0 {
+			parts[i] = strings.TrimLeft(parts[i], "\n")
+		}
+		if i < len(parts)-1 {
+			parts[i] = strings.TrimRight(parts[i], "\n")
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newCommandInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = ":"
+	input.CharLimit = 256
+	input.Blur()
+	return input
+}
+
+func (m *Model) enterCommandMode() tea.Cmd {
+	m.commandActive = true
+	m.pendingKey = ""
+	m.commandInput.SetValue("")
+	return m.commandInput.Focus()
+}
+
+func (m *Model) exitCommandMode() {
+	m.commandActive = false
+	m.completion = nil
+	m.commandInput.Blur()
+}
+
+// handleCommandKey routes keys to the command line while it is open.
+func (m *Model) handleCommandKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		line := strings.TrimSpace(m.commandInput.Value())
+		m.exitCommandMode()
+		cmd, err := m.executeCommand(line)
+		m.err = err
+		return cmd
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.exitCommandMode()
+		return nil
+	case tea.KeyTab:
+		m.completeCommand(1)
+		return nil
+	case tea.KeyShiftTab:
+		m.completeCommand(-1)
+		return nil
+	}
+	m.completion = nil
+	var cmd tea.Cmd
+	m.commandInput, cmd = m.commandInput.Update(msg)
+	return cmd
+}
+
+// executeCommand runs an ex-style command such as ":120" or ":set sourcelines".
+func (m *Model) executeCommand(line string) (tea.Cmd, error) {
+	if line == "" {
+		return nil, nil
+	}
+	if n, err := strconv.Atoi(line); err == nil {
+		m.gotoLine(n)
+		return nil, nil
+	}
+	name, args, _ := strings.Cut(line, " ")
+	switch name {
+	case "set", "se":
+		return nil, m.setOption(strings.TrimSpace(args))
+	case "anchor", "a":
+		return nil, m.jumpToAnchor(strings.TrimSpace(args))
+	case "e", "edit":
+		return m.editFile(strings.TrimSpace(args))
+	case "q", "quit":
+		m.quitting = true
+		return nil, nil
+	case "nohlsearch", "noh":
+		m.hideSearchHighlight()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("不明なコマンド: %s", line)
+}
+
+// setOption toggles a boolean option: "name" enables it, "noname" disables it
+// and "name!" inverts it.
+func (m *Model) setOption(arg string) error {
+	if arg == "" {
+		return fmt.Errorf(":set にはオプション名が必要です")
+	}
+	name := strings.TrimSuffix(arg, "!")
+	invert := name != arg
+	value := true
+	if strings.HasPrefix(name, "no") {
+		if _, ok := m.boolOption(name[2:]); ok {
+			name = name[2:]
+			value = false
+		}
+	}
+	target, ok := m.boolOption(name)
+	if !ok {
+		return fmt.Errorf("不明なオプション: %s", name)
+	}
+	if invert {
+		value = !*target
+	}
+	*target = value
+	switch name {
+	case "number", "nu":
+		m.refreshLayout()
+	case "ignorecase", "ic", "smartcase", "scs", "wholeword", "ww":
+		if m.searchQuery != "" {
+			m.performSearch(m.searchQuery, false)
+		}
+	}
+	return nil
+}
+
+// boolOption returns the storage for a boolean option settable with :set.
+func (m *Model) boolOption(name string) (*bool, bool) {
+	switch name {
+	case "sourcelines", "sl":
+		return &m.opts.SourceLines, true
+	case "number", "nu":
+		return &m.opts.LineNumbers, true
+	case "footnotepreview", "fnp":
+		return &m.opts.FootnotePreview, true
+	case "autoreload", "ar":
+		return &m.autoReload, true
+	case "ignorecase", "ic":
+		return &m.ignoreCase, true
+	case "smartcase", "scs":
+		return &m.smartCase, true
+	case "wholeword", "ww":
+		return &m.wholeWord, true
+	}
+	return nil, false
+}
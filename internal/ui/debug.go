@@ -0,0 +1,27 @@
+package ui
+
+import (
+	"io"
+	"log"
+	"time"
+)
+
+// slowUpdate is how long a message other than a key press has to take
+// before the debug log mentions it.
+const slowUpdate = 10 * time.Millisecond
+
+// debugLog receives the timings written with --debug, or is nil.
+var debugLog *log.Logger
+
+// SetDebugLog writes how long renders, directory reads and key handling
+// take, and the watcher's events, to w.
+func SetDebugLog(w io.Writer) {
+	debugLog = log.New(w, "mdview ", log.LstdFlags|log.Lmicroseconds)
+}
+
+// debugf logs to the debug log when there is one.
+func debugf(format string, args ...any) {
+	if debugLog != nil {
+		debugLog.Printf(format, args...)
+	}
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// diagramsDrawnMsg reports the diagrams drawn in the background and whether
+// any of them succeeded.
+type diagramsDrawnMsg struct {
+	diagrams []markdown.Diagram
+	drawn    bool
+}
+
+// queueDiagrams leaves the diagrams a render could not draw without waiting
+// for an external command to awaitDiagrams, skipping those already being
+// drawn.
+func (m *Model) queueDiagrams(pending []markdown.Diagram) {
+	for _, d := range pending {
+		if m.drawingDiagrams[d] {
+			continue
+		}
+		if m.drawingDiagrams == nil {
+			m.drawingDiagrams = make(map[markdown.Diagram]bool)
+		}
+		m.drawingDiagrams[d] = true
+		m.diagramQueue = append(m.diagramQueue, d)
+	}
+}
+
+// awaitDiagrams draws the queued diagrams in the background.
+func (m *Model) awaitDiagrams() tea.Cmd {
+	if len(m.diagramQueue) == 0 {
+		return nil
+	}
+	queue := m.diagramQueue
+	m.diagramQueue = nil
+	return func() tea.Msg {
+		drawn := false
+		for _, d := range queue {
+			if d.Draw() {
+				drawn = true
+			}
+		}
+		return diagramsDrawnMsg{diagrams: queue, drawn: drawn}
+	}
+}
+
+// handleDiagramsDrawn renders the document again so that the drawn diagrams
+// replace their source.
+func (m *Model) handleDiagramsDrawn(msg diagramsDrawnMsg) {
+	for _, d := range msg.diagrams {
+		delete(m.drawingDiagrams, d)
+	}
+	if msg.drawn {
+		m.rerenderKeepingPosition()
+	}
+}
@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/git"
+	"github.com/kyaoi/mdview/internal/linediff"
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+const (
+	diffInsertOn  = "\x1b[32m"
+	diffInsertOff = "\x1b[39m"
+	diffDeleteOn  = "\x1b[9;31m"
+	diffDeleteOff = "\x1b[29;39m"
+)
+
+// diffInsertGutter and diffDeleteGutter mark rows holding added and removed
+// text in the left margin, so changes stand out while scrolling.
+var (
+	diffInsertGutter = lipgloss.NewStyle().Foreground(lipgloss.Color("#9ece6a")).Render("▎")
+	diffDeleteGutter = lipgloss.NewStyle().Foreground(lipgloss.Color("#f7768e")).Render("▎")
+)
+
+// toggleDiff shows or hides the changes of the active file since its last
+// commit.
+func (m *Model) toggleDiff() {
+	if !m.diffMode {
+		if m.activeAbsPath == "" {
+			m.err = errors.New("ファイルが開かれていません")
+			return
+		}
+		if m.showingCode() || m.showingText() {
+			m.err = errors.New("差分は Markdown の文書でのみ表示できます")
+			return
+		}
+		// A commit made since the last toggle changes the base.
+		m.diffBase, m.diffBasePath = nil, ""
+	}
+	m.diffMode = !m.diffMode
+	m.rerenderKeepingPosition()
+}
+
+// loadDiffBase reads the committed version of the active file, once per
+// file.
+func (m *Model) loadDiffBase() error {
+	if m.diffBase != nil && m.diffBasePath == m.activeAbsPath {
+		return nil
+	}
+	data, err := git.Show(m.activeAbsPath, "HEAD")
+	if err != nil {
+		return err
+	}
+	content, err := markdown.Convert(m.activeAbsPath, data)
+	if err != nil {
+		return err
+	}
+	m.diffBase = strings.Split(content, "\n")
+	m.diffBasePath = m.activeAbsPath
+	m.diffScript = nil
+	return nil
+}
+
+// diffAgainstBase returns the edit script from the committed version to the
+// active file, diffing again only when either changed rather than on every
+// render.
+func (m *Model) diffAgainstBase() []linediff.Line {
+	if m.diffScript == nil || m.diffScriptOf != m.rawContent {
+		m.diffScript = linediff.Diff(m.diffBase, strings.Split(m.rawContent, "\n"))
+		m.diffScriptOf = m.rawContent
+	}
+	return m.diffScript
+}
+
+// diffSource returns the markdown to render: the active file, with the lines
+// changed by the last reload marked while they are highlighted, or in diff
+// mode merged with the lines removed since HEAD and marked up with
+// markdown.MarkDiff.
+func (m *Model) diffSource() string {
+	m.diffInserted, m.diffDeleted = 0, 0
+	if !m.diffMode {
+		if m.changedLines != nil {
+			return m.changesSource()
+		}
+		return m.rawContent
+	}
+	if err := m.loadDiffBase(); err != nil {
+		m.diffMode = false
+		m.notice = fmt.Sprintf("差分を表示できません: %v", err)
+		return m.rawContent
+	}
+	script := m.diffAgainstBase()
+	m.diffInserted, m.diffDeleted = linediff.Stats(script)
+	return markdown.MarkDiff(script)
+}
+
+// highlightDiff colours the text between the diff markers, green for added
+// and struck-through red for removed text, and marks the rows holding it in
+// the left margin.
+func highlightDiff(rendered string) string {
+	if !strings.Contains(rendered, markdown.DiffInsertStart) && !strings.Contains(rendered, markdown.DiffDeleteStart) {
+		return rendered
+	}
+	lines := strings.Split(rendered, "\n")
+	gutters := make([]string, len(lines))
+	var open string
+	for i, line := range lines {
+		if open != "" {
+			gutters[i] = open
+		}
+		for j := 0; j < len(line); {
+			switch {
+			case strings.HasPrefix(line[j:], markdown.DiffInsertStart):
+				open = diffInsertGutter
+				gutters[i] = open
+			case strings.HasPrefix(line[j:], markdown.DiffDeleteStart):
+				open = diffDeleteGutter
+				gutters[i] = open
+			case strings.HasPrefix(line[j:], markdown.DiffInsertEnd), strings.HasPrefix(line[j:], markdown.DiffDeleteEnd):
+				open = ""
+			}
+			j++
+		}
+	}
+	rendered, _ = highlightSpans(rendered, markdown.DiffInsertStart, markdown.DiffInsertEnd, spanStyle(diffInsertOn, diffInsertOff))
+	rendered, _ = highlightSpans(rendered, markdown.DiffDeleteStart, markdown.DiffDeleteEnd, spanStyle(diffDeleteOn, diffDeleteOff))
+	lines = strings.Split(rendered, "\n")
+	for i, gutter := range gutters {
+		if gutter != "" && i < len(lines) && strings.HasPrefix(ansi.Strip(lines[i]), " ") {
+			lines[i] = gutter + ansi.TruncateLeft(lines[i], 1, "")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffStatus summarises the changes shown in diff mode.
+func (m *Model) diffStatus() string {
+	if !m.diffMode {
+		return ""
+	}
+	return fmt.Sprintf("差分 (HEAD): +%d −%d", m.diffInserted, m.diffDeleted)
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/fuzzy"
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+// completionState remembers the ranked candidates while Tab cycles through
+// them, so repeated presses do not re-rank against the inserted completion.
+type completionState struct {
+	command    string
+	candidates []string
+	index      int
+}
+
+// vaultRoot is the directory paths given to :e are resolved against: the
+// browsed directory, or the directory of the file opened on the command line.
+func (m *Model) vaultRoot() string {
+	if m.rootDir != "" {
+		return m.rootDir
+	}
+	if m.activeAbsPath != "" {
+		return filepath.Dir(m.activeAbsPath)
+	}
+	return ""
+}
+
+// markdownIndex lists the markdown files under the vault root, relative to it.
+// It is built on first use and reused until the tree watch sees files come
+// or go.
+func (m *Model) markdownIndex() []string {
+	if m.fileIndex == nil && m.treeRoot != nil && m.rootDir != "" {
+		if index, ok := m.treeRoot.Index(); ok {
+			m.fileIndex = append([]string{}, index.Files()...)
+		}
+	}
+	if m.fileIndex == nil {
+		root := m.vaultRoot()
+		if root == "" {
+			return nil
+		}
+		files, err := tree.MarkdownFiles(root)
+		if err != nil {
+			m.err = err
+		}
+		m.fileIndex = append([]string{}, files...)
+	}
+	return m.fileIndex
+}
+
+// editFile implements ":e <path>", opening a file relative to the vault root.
+func (m *Model) editFile(arg string) (tea.Cmd, error) {
+	if arg == "" {
+		return nil, errors.New(":e にはファイルパスが必要です")
+	}
+	root := m.vaultRoot()
+	absPath := arg
+	if !filepath.IsAbs(absPath) {
+		absPath = filepath.Join(root, filepath.FromSlash(arg))
+	}
+	if info, err := os.Stat(absPath); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		return nil, errors.New("ディレクトリは開けません: " + arg)
+	}
+
+	if m.rootDir != "" {
+		if rel, err := filepath.Rel(m.rootDir, absPath); err == nil && !strings.HasPrefix(rel, "..") {
+			rel = filepath.ToSlash(rel)
+			return m.openFileEntry(&tree.Node{Name: filepath.Base(rel), Path: rel}), nil
+		}
+	}
+	headerPath := absPath
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, absPath); err == nil {
+			headerPath = filepath.ToSlash(rel)
+		}
+	}
+	return m.openFile(absPath, headerPath), nil
+}
+
+// completeCommand fills in the argument of ":e" with the best fuzzy match from
+// the markdown index; further presses cycle through the ranking in direction
+// dir.
+func (m *Model) completeCommand(dir int) {
+	if m.completion != nil {
+		c := m.completion
+		c.index = (c.index + dir + len(c.candidates)) % len(c.candidates)
+		m.setCommandLine(c.command + " " + c.candidates[c.index])
+		return
+	}
+	name, arg, _ := strings.Cut(strings.TrimLeft(m.commandInput.Value(), " "), " ")
+	switch name {
+	case "e", "edit":
+	default:
+		return
+	}
+	candidates := fuzzy.Rank(strings.TrimSpace(arg), m.markdownIndex())
+	if len(candidates) == 0 {
+		return
+	}
+	index := 0
+	if dir < 0 {
+		index = len(candidates) - 1
+	}
+	m.completion = &completionState{command: name, candidates: candidates, index: index}
+	m.setCommandLine(name + " " + candidates[index])
+}
+
+func (m *Model) setCommandLine(value string) {
+	m.commandInput.SetValue(value)
+	m.commandInput.CursorEnd()
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+)
+
+// footnoteInView returns the index into m.footnoteSpots of the first
+// footnote reference or definition shown in the viewport.
+func (m *Model) footnoteInView() (int, bool) {
+	top := m.contentVP.YOffset
+	for i, pos := range m.footnotePos {
+		if i >= len(m.footnoteSpots) || pos.line >= top+m.contentVP.Height {
+			break
+		}
+		if pos.line >= top {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// footnoteLine returns the rendered line of the first reference to footnote
+// n, or of its definition.
+func (m *Model) footnoteLine(n int, definition bool) (int, bool) {
+	for i, spot := range m.footnoteSpots {
+		if i < len(m.footnotePos) && spot.Number == n && spot.Definition == definition {
+			return m.footnotePos[i].line, true
+		}
+	}
+	return 0, false
+}
+
+// returningFromFootnote reports whether the definition jumpFootnote last
+// moved to is still in view, so the next jump goes back to the reference.
+func (m *Model) returningFromFootnote() bool {
+	if m.footnoteReturn == 0 {
+		return false
+	}
+	line, ok := m.footnoteLine(m.footnoteReturnNote, true)
+	top := m.contentVP.YOffset
+	return ok && line >= top && line < top+m.contentVP.Height
+}
+
+// jumpFootnote moves from the first visible footnote reference to its
+// definition and back again: from a definition it returns to the reference
+// it was reached from, or to the first reference when it was scrolled to by
+// hand.
+func (m *Model) jumpFootnote() {
+	if m.returningFromFootnote() {
+		m.contentVP.SetYOffset(m.footnoteReturn - 1)
+		m.footnoteReturn = 0
+		return
+	}
+	m.footnoteReturn = 0
+	i, ok := m.footnoteInView()
+	if !ok {
+		m.err = errors.New("表示範囲に脚注がありません")
+		return
+	}
+	spot := m.footnoteSpots[i]
+	if !spot.Definition {
+		m.jumpToFootnote(spot.Number)
+	} else if line, ok := m.footnoteLine(spot.Number, false); ok {
+		m.contentVP.SetYOffset(line)
+	}
+}
+
+// jumpToFootnote scrolls to the definition of footnote n, remembering where
+// to go back to.
+func (m *Model) jumpToFootnote(n int) {
+	if line, ok := m.footnoteLine(n, true); ok {
+		m.footnoteReturn, m.footnoteReturnNote = m.contentVP.YOffset+1, n
+		m.contentVP.SetYOffset(line)
+	}
+}
+
+// footnoteHintStatus points out the key jumping to the visible footnote and,
+// with the footnote preview on, shows the text of the footnote referenced.
+func (m *Model) footnoteHintStatus() string {
+	i, ok := m.footnoteInView()
+	if !ok {
+		return ""
+	}
+	spot := m.footnoteSpots[i]
+	if spot.Definition || m.returningFromFootnote() {
+		return "F: 参照元へ戻る"
+	}
+	if !m.opts.FootnotePreview || spot.Number > len(m.footnotes) {
+		return "F: 脚注へ"
+	}
+	return fmt.Sprintf("F: 脚注へ  [%d] %s", spot.Number, m.footnotes[spot.Number-1].Text)
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// frontMatterOverlayWidth caps the overlay so long values wrap instead of
+// spanning ultra-wide terminals.
+const frontMatterOverlayWidth = 100
+
+// toggleFrontMatter opens the raw front matter overlay, or explains why it
+// cannot when the document has none.
+func (m *Model) toggleFrontMatter() {
+	if m.showFrontMatter {
+		m.showFrontMatter = false
+		return
+	}
+	if markdown.FrontMatterBlock(m.rawContent) == "" {
+		m.err = errors.New("このファイルにはフロントマターがありません")
+		return
+	}
+	m.showFrontMatter = true
+}
+
+// frontMatterView renders the unparsed front matter block with YAML
+// highlighting inside a bordered box.
+func (m *Model) frontMatterView() string {
+	block := strings.TrimRight(markdown.FrontMatterBlock(m.rawContent), "\n")
+	width := min(frontMatterOverlayWidth, max(m.width-helpBoxStyle.GetHorizontalFrameSize(), minContentWidth))
+	body := block
+	if renderer, err := newRenderer(width, Options{}); err == nil {
+		fence := "```"
+		for strings.Contains(block, fence) {
+			fence += "`"
+		}
+		if rendered, err := renderer.Render(fence + "yaml\n" + block + "\n" + fence + "\n"); err == nil {
+			body = trimBlankLines(rendered)
+		}
+	}
+	title := "フロントマター (M / Esc: 閉じる)"
+	lines := strings.Split(body, "\n")
+	if limit := m.height - helpBoxStyle.GetVerticalFrameSize() - 1; limit > 0 && len(lines) > limit {
+		lines = append(lines[:limit-1], "…")
+	}
+	return helpBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, strings.Join(lines, "\n")))
+}
+
+// trimBlankLines drops the empty margin lines glamour puts around a block.
+func trimBlankLines(rendered string) string {
+	lines := strings.Split(rendered, "\n")
+	for len(lines) > 0 && strings.TrimSpace(ansi.Strip(lines[0])) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(ansi.Strip(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
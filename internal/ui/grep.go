@@ -0,0 +1,252 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/grep"
+)
+
+// grepLimit bounds the lines listed for one query; a query this common is
+// better narrowed down than scrolled through.
+const grepLimit = 1000
+
+// grepDoneMsg delivers the results of the search numbered gen.
+type grepDoneMsg struct {
+	gen     int
+	matches []grep.Match
+	err     error
+}
+
+func newGrepInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "grep: "
+	input.CharLimit = 256
+	input.Placeholder = "全文検索"
+	input.Blur()
+	return input
+}
+
+// toggleGrep shows the grep pane, which searches the text of every markdown
+// file under the vault root as the query is typed, and moves the keys into
+// it. With the pane shown but not focused it hides the pane instead.
+func (m *Model) toggleGrep() tea.Cmd {
+	if m.grepVisible && !m.grepFocus {
+		m.hideGrep()
+		return nil
+	}
+	if m.vaultRoot() == "" {
+		m.err = errors.New("ファイルが開かれていません")
+		return nil
+	}
+	m.grepVisible, m.grepFocus = true, true
+	m.backlinksVisible, m.backlinksFocus = false, false
+	m.linksVisible, m.linksFocus = false, false
+	m.historyVisible, m.historyFocus = false, false
+	m.resize(m.width, m.height)
+	m.grepInput.CursorEnd()
+	return m.grepInput.Focus()
+}
+
+// hideGrep closes the grep pane and lays out the rest.
+func (m *Model) hideGrep() {
+	m.hideGrepPane()
+	m.resize(m.width, m.height)
+}
+
+// hideGrepPane closes the grep pane, stopping a search still running, for
+// another list pane to take its place.
+func (m *Model) hideGrepPane() {
+	m.grepVisible, m.grepFocus = false, false
+	m.grepInput.Blur()
+	m.cancelGrep()
+}
+
+func (m *Model) handleGrepKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "down", "ctrl+n":
+		m.grepSelection = min(m.grepSelection+1, max(len(m.grepMatches)-1, 0))
+		return nil
+	case "up", "ctrl+p":
+		m.grepSelection = max(m.grepSelection-1, 0)
+		return nil
+	case "esc":
+		m.grepFocus = false
+		m.grepInput.Blur()
+		return nil
+	case "ctrl+g":
+		m.hideGrep()
+		return nil
+	case "enter":
+		return m.openGrepMatch()
+	}
+	var cmd tea.Cmd
+	m.grepInput, cmd = m.grepInput.Update(msg)
+	if query := strings.TrimSpace(m.grepInput.Value()); query != m.grepQuery {
+		return tea.Batch(cmd, m.startGrep(query))
+	}
+	return cmd
+}
+
+// startGrep searches for query in the background, abandoning the search for
+// the previous query. Headless replays search at once.
+func (m *Model) startGrep(query string) tea.Cmd {
+	m.cancelGrep()
+	m.grepQuery = query
+	m.grepGen++
+	m.grepMatches, m.grepSelection, m.grepErr = nil, 0, nil
+	if query == "" {
+		return nil
+	}
+	files := m.markdownIndex()
+	root := m.vaultRoot()
+	opts := grep.Options{
+		CaseSensitive: m.caseSensitive(query),
+		Limit:         grepLimit,
+		Ripgrep:       !m.opts.NoRipgrep,
+	}
+	if m.opts.Sync {
+		matches, err := grep.Search(context.Background(), root, files, query, opts)
+		m.handleGrepDone(grepDoneMsg{gen: m.grepGen, matches: matches, err: err})
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.grepCancel = cancel
+	m.grepRunning = true
+	gen := m.grepGen
+	return func() tea.Msg {
+		matches, err := grep.Search(ctx, root, files, query, opts)
+		return grepDoneMsg{gen: gen, matches: matches, err: err}
+	}
+}
+
+// cancelGrep stops the search running in the background, if any.
+func (m *Model) cancelGrep() {
+	if m.grepCancel != nil {
+		m.grepCancel()
+		m.grepCancel = nil
+	}
+	m.grepRunning = false
+}
+
+// handleGrepDone lists the results of the latest search.
+func (m *Model) handleGrepDone(msg grepDoneMsg) {
+	if msg.gen != m.grepGen {
+		return
+	}
+	m.grepCancel = nil
+	m.grepRunning = false
+	m.grepMatches, m.grepErr = msg.matches, msg.err
+	m.grepSelection = 0
+	if msg.err == nil {
+		m.quickfix, m.quickfixQuery, m.quickfixIndex = msg.matches, m.grepQuery, -1
+	}
+	debugf("grep %q: %d matches", m.grepQuery, len(msg.matches))
+}
+
+// openGrepMatch opens the file of the selected result, which ]q and [q
+// then step on from.
+func (m *Model) openGrepMatch() tea.Cmd {
+	if m.grepSelection >= len(m.grepMatches) {
+		return nil
+	}
+	m.quickfix, m.quickfixQuery, m.quickfixIndex = m.grepMatches, m.grepQuery, m.grepSelection
+	return m.openMatch(m.grepMatches[m.grepSelection], m.grepQuery)
+}
+
+// stepQuickfix opens the next (step 1) or previous (step -1) result of the
+// last grep, going from file to file and wrapping around like n and N. The
+// grep pane need not be open.
+func (m *Model) stepQuickfix(step int) tea.Cmd {
+	n := len(m.quickfix)
+	if n == 0 {
+		m.err = errors.New("全文検索 (Ctrl+g) の結果がありません")
+		return nil
+	}
+	switch {
+	case m.quickfixIndex < 0 && step > 0:
+		m.quickfixIndex = 0
+	case m.quickfixIndex < 0:
+		m.quickfixIndex = n - 1
+	default:
+		next := m.quickfixIndex + step
+		if next >= n {
+			m.notice = searchWrappedToTop
+		} else if next < 0 {
+			m.notice = searchWrappedToBottom
+		}
+		m.quickfixIndex = (next + n) % n
+	}
+	if m.grepQuery == m.quickfixQuery {
+		m.grepSelection = m.quickfixIndex
+	}
+	match := m.quickfix[m.quickfixIndex]
+	cmd := m.openMatch(match, m.quickfixQuery)
+	if m.notice == "" {
+		m.notice = fmt.Sprintf("(%d/%d) %s:%d", m.quickfixIndex+1, n, match.File, match.Line+1)
+	}
+	return cmd
+}
+
+// openMatch opens the file of a grep result, scrolled to its line and with
+// the query searched for in it.
+func (m *Model) openMatch(match grep.Match, query string) tea.Cmd {
+	cmd, err := m.editFile(match.File)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	m.whenRendered(func() {
+		line := m.renderedLineForSource(match.Line + 1)
+		m.contentVP.SetYOffset(line)
+		// Near the end of the document the offset stops short of the
+		// line, so the match to select is picked from the line itself.
+		m.searchFrom = line
+		m.performSearch(query, true)
+	})
+	// Keys then scroll the match rather than move through the tree.
+	m.blurTree()
+	return cmd
+}
+
+// grepHeight returns the rows taken by the grep pane out of height.
+func (m *Model) grepHeight(height int) int {
+	if !m.grepVisible {
+		return 0
+	}
+	return listPaneHeight(height)
+}
+
+// grepView renders the query row and the results, keeping the selection in
+// view.
+func (m *Model) grepView() string {
+	title := " " + m.grepInput.View()
+	switch {
+	case m.grepRunning:
+		title += "  検索中…"
+	case m.grepErr != nil:
+		title += "  " + m.grepErr.Error()
+	case m.grepQuery != "":
+		count := fmt.Sprintf("%d 件", len(m.grepMatches))
+		if len(m.grepMatches) == grepLimit {
+			count += "以上"
+		}
+		title += "  (" + count + ")"
+	}
+	if m.grepFocus {
+		title += "  (↑/↓: 選択 / Enter: 開く / Esc: 本文へ戻る / Ctrl+G: 閉じる)"
+	}
+	rows := make([]string, len(m.grepMatches))
+	for i, match := range m.grepMatches {
+		rows[i] = fmt.Sprintf(" %s:%d  %s", match.File, match.Line+1, match.Text)
+	}
+	if len(rows) == 0 && m.grepQuery != "" && !m.grepRunning {
+		rows = append(rows, " 一致する行はありません")
+	}
+	return m.listPaneView(title, rows, m.grepSelection, m.grepFocus && len(m.grepMatches) > 0)
+}
@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// headingTrailLimit caps how many visited headings are remembered per
+// document for ctrl+o / ctrl+i stepping.
+const headingTrailLimit = 10
+
+// renderedHeading ties a source heading to the rendered line it appears on.
+type renderedHeading struct {
+	markdown.Heading
+	renderedLine int
+}
+
+// indexHeadings locates every source heading in the rendered output. Headings
+// are matched in order so repeated titles resolve to the right occurrence.
+func (m *Model) indexHeadings() {
+	m.headings = m.headings[:0]
+	headings := markdown.Headings(m.rawContent)
+	if len(headings) == 0 {
+		return
+	}
+	lines := strings.Split(ansi.Strip(m.renderedContent), "\n")
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = matchKey(line)
+	}
+	cursor := 0
+	for _, h := range headings {
+		key := matchKey(h.Text)
+		if key == "" {
+			continue
+		}
+		for i := cursor; i < len(keys); i++ {
+			if headingLineMatches(keys[i], key) {
+				m.headings = append(m.headings, renderedHeading{Heading: h, renderedLine: i})
+				cursor = i + 1
+				break
+			}
+		}
+	}
+	m.clampHeadingTrail()
+}
+
+// headingLineMatches reports whether a rendered line holds the heading. Long
+// headings wrap, so a sufficiently long prefix of the heading also counts.
+func headingLineMatches(lineKey, headingKey string) bool {
+	if lineKey == headingKey {
+		return true
+	}
+	return len(lineKey) >= 8 && strings.HasPrefix(headingKey, lineKey)
+}
+
+// matchKey reduces text to lowercase letters and digits so headings can be
+// found in rendered output regardless of styling or typographic changes.
+func matchKey(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// currentHeadingIndex returns the heading that owns the top of the viewport,
+// or -1 when the viewport is above the first heading.
+func (m *Model) currentHeadingIndex() int {
+	current := -1
+	for i, h := range m.headings {
+		if h.renderedLine > m.contentVP.YOffset {
+			break
+		}
+		current = i
+	}
+	return current
+}
+
+// jumpHeading moves to the next (dir > 0) or previous (dir < 0) heading and
+// records the visit in the heading trail.
+func (m *Model) jumpHeading(dir int) {
+	if len(m.headings) == 0 {
+		return
+	}
+	current := m.currentHeadingIndex()
+	target := current + dir
+	if dir < 0 && current >= 0 && m.headings[current].renderedLine < m.contentVP.YOffset {
+		target = current
+	}
+	if target < 0 || target >= len(m.headings) {
+		return
+	}
+	if len(m.headingTrail) == 0 && current >= 0 {
+		m.headingTrail = []int{current}
+		m.headingTrailPos = 0
+	}
+	m.recordHeadingVisit(target)
+	m.scrollToHeading(target)
+}
+
+func (m *Model) recordHeadingVisit(index int) {
+	if len(m.headingTrail) > 0 {
+		m.headingTrail = m.headingTrail[:m.headingTrailPos+1]
+		if m.headingTrail[len(m.headingTrail)-1] == index {
+			return
+		}
+	}
+	m.headingTrail = append(m.headingTrail, index)
+	if over := len(m.headingTrail) - headingTrailLimit; over > 0 {
+		m.headingTrail = m.headingTrail[over:]
+	}
+	m.headingTrailPos = len(m.headingTrail) - 1
+}
+
+// stepHeadingTrail walks the heading trail backwards (ctrl+o) or forwards
+// (ctrl+i) without recording new entries.
+func (m *Model) stepHeadingTrail(delta int) bool {
+	pos := m.headingTrailPos + delta
+	if len(m.headingTrail) == 0 || pos < 0 || pos >= len(m.headingTrail) {
+		return false
+	}
+	m.headingTrailPos = pos
+	m.scrollToHeading(m.headingTrail[pos])
+	return true
+}
+
+func (m *Model) scrollToHeading(index int) {
+	if index < 0 || index >= len(m.headings) {
+		return
+	}
+	m.contentVP.SetYOffset(m.headings[index].renderedLine)
+}
+
+func (m *Model) resetHeadingTrail() {
+	m.headingTrail = nil
+	m.headingTrailPos = 0
+}
+
+func (m *Model) clampHeadingTrail() {
+	kept := m.headingTrail[:0]
+	for _, idx := range m.headingTrail {
+		if idx < len(m.headings) {
+			kept = append(kept, idx)
+		}
+	}
+	m.headingTrail = kept
+	if m.headingTrailPos >= len(m.headingTrail) {
+		m.headingTrailPos = max(len(m.headingTrail)-1, 0)
+	}
+}
+
+// headingTrailStatus describes the heading trail for the bottom bar, e.g.
+// "§ Setup (2/3)".
+func (m *Model) headingTrailStatus() string {
+	if len(m.headingTrail) == 0 {
+		return ""
+	}
+	index := m.headingTrail[m.headingTrailPos]
+	if index >= len(m.headings) {
+		return ""
+	}
+	return fmt.Sprintf("§ %s (%d/%d)", m.headings[index].Text, m.headingTrailPos+1, len(m.headingTrail))
+}
+
+// headingForAnchor resolves an in-document anchor such as "#setup-1" to a
+// heading index using the configured slug style, or -1 when none matches.
+// The heading text itself, as in "#Setup steps", is accepted as well.
+func (m *Model) headingForAnchor(anchor string) int {
+	anchor = strings.TrimPrefix(anchor, "#")
+	slug := strings.ToLower(anchor)
+	for i, candidate := range m.headingAnchors() {
+		if candidate == slug {
+			return i
+		}
+	}
+	key := matchKey(anchor)
+	for i, h := range m.headings {
+		if key != "" && matchKey(h.Text) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// jumpToAnchor scrolls to the heading named by anchor and records the visit in
+// the heading trail.
+func (m *Model) jumpToAnchor(anchor string) error {
+	if anchor == "" {
+		return fmt.Errorf(":anchor にはアンカー名が必要です")
+	}
+	index := m.headingForAnchor(anchor)
+	if index < 0 {
+		return fmt.Errorf("アンカー %s が見つかりません", anchor)
+	}
+	if len(m.headingTrail) == 0 {
+		if current := m.currentHeadingIndex(); current >= 0 {
+			m.headingTrail = []int{current}
+			m.headingTrailPos = 0
+		}
+	}
+	m.recordHeadingVisit(index)
+	m.scrollToHeading(index)
+	return nil
+}
@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// hintAlphabet lists the keys hint labels are made of, easiest first.
+const hintAlphabet = "asdfjklghqweruiotyzxcvbnmp"
+
+var hintStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#1a1b26")).
+	Background(lipgloss.Color("#e0af68")).
+	Bold(true)
+
+// linkHint is a followable spot of the viewport labelled in hint mode.
+type linkHint struct {
+	label string
+	pos   markPos
+	// dest describes where following the spot leads.
+	dest string
+	// follow opens what the spot links to.
+	follow func() tea.Cmd
+}
+
+// visibleHintTargets collects the links, wikilinks, footnote references and
+// images shown in the viewport, top to bottom and left to right.
+func (m *Model) visibleHintTargets() []linkHint {
+	top, bottom := m.contentVP.YOffset, m.contentVP.YOffset+m.contentVP.Height
+	visible := func(pos markPos) bool { return pos.line >= top && pos.line < bottom }
+	var hints []linkHint
+	for i, pos := range m.linkPos {
+		if i < len(m.links) && visible(pos) {
+			link := m.links[i]
+			hints = append(hints, linkHint{pos: pos, dest: m.linkPreview(link), follow: func() tea.Cmd { return m.followLink(link) }})
+		}
+	}
+	for i, pos := range m.wikiLinkPos {
+		if i < len(m.wikiLinks) && visible(pos) {
+			link := m.wikiLinks[i]
+			hints = append(hints, linkHint{pos: pos, dest: m.wikiLinkPreview(link), follow: func() tea.Cmd { return m.openWikiLink(link) }})
+		}
+	}
+	for i, pos := range m.footnotePos {
+		if i < len(m.footnoteSpots) && !m.footnoteSpots[i].Definition && visible(pos) {
+			n := m.footnoteSpots[i].Number
+			dest := fmt.Sprintf("脚注 [%d]", n)
+			if n <= len(m.footnotes) {
+				dest += " " + m.footnotes[n-1].Text
+			}
+			hints = append(hints, linkHint{pos: pos, dest: dest, follow: func() tea.Cmd { m.jumpToFootnote(n); return nil }})
+		}
+	}
+	lines := m.plainLines()
+	seen := 0
+	for i := 0; i < min(bottom, len(lines)) && seen < len(m.images); i++ {
+		for offset := 0; seen < len(m.images); {
+			n := strings.Index(lines[i][offset:], markdown.ImageLabel)
+			if n < 0 {
+				break
+			}
+			offset += n
+			if i >= top {
+				img := m.images[seen]
+				pos := markPos{line: i, col: ansi.StringWidth(lines[i][:offset])}
+				dest, _ := m.localPath(img.Target)
+				if abs, err := filepath.Abs(dest); err == nil && !markdown.IsURL(dest) {
+					dest = abs
+				}
+				hints = append(hints, linkHint{pos: pos, dest: dest, follow: func() tea.Cmd { m.openImageTarget(img); return nil }})
+			}
+			seen++
+			offset += len(markdown.ImageLabel)
+		}
+	}
+	sort.SliceStable(hints, func(i, j int) bool {
+		if hints[i].pos.line != hints[j].pos.line {
+			return hints[i].pos.line < hints[j].pos.line
+		}
+		return hints[i].pos.col < hints[j].pos.col
+	})
+	return hints
+}
+
+// hintLabels returns n labels of equal length, so none is a prefix of
+// another and a label is followed as soon as it is typed in full.
+func hintLabels(n int) []string {
+	labels := []string{""}
+	for len(labels) < n {
+		var next []string
+		for _, prefix := range labels {
+			for _, r := range hintAlphabet {
+				next = append(next, prefix+string(r))
+			}
+		}
+		labels = next
+	}
+	return labels[:n]
+}
+
+// enterHintMode labels every followable spot in the viewport.
+func (m *Model) enterHintMode() {
+	hints := m.visibleHintTargets()
+	if len(hints) == 0 {
+		m.err = errors.New("表示範囲にリンクがありません")
+		return
+	}
+	for i, label := range hintLabels(len(hints)) {
+		hints[i].label = label
+	}
+	m.hints, m.hintInput = hints, ""
+	m.showHints()
+}
+
+// exitHintMode drops the labels and restores the content.
+func (m *Model) exitHintMode() {
+	if m.hints == nil {
+		return
+	}
+	m.hints, m.hintInput = nil, ""
+	m.contentVP.SetContent(m.renderedContent)
+}
+
+func (m *Model) handleHintKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.exitHintMode()
+		return nil
+	case tea.KeyEnter:
+		if hint, ok := m.hintMatch(); ok && hint.label == m.hintInput {
+			m.exitHintMode()
+			return hint.follow()
+		}
+		return nil
+	case tea.KeyBackspace:
+		if m.hintInput != "" {
+			m.hintInput = m.hintInput[:len(m.hintInput)-1]
+			m.showHints()
+		}
+		return nil
+	case tea.KeyRunes:
+	default:
+		return nil
+	}
+	typed := string(msg.Runes)
+	// A label typed in upper case is only selected, to preview where it
+	// leads before Enter follows it.
+	preview := strings.IndexFunc(typed, unicode.IsUpper) >= 0
+	input := m.hintInput + strings.ToLower(typed)
+	matched := 0
+	var last linkHint
+	for _, hint := range m.hints {
+		if strings.HasPrefix(hint.label, input) {
+			matched++
+			last = hint
+		}
+	}
+	switch {
+	case matched == 0:
+		m.exitHintMode()
+		m.err = errors.New("一致するヒントがありません: " + input)
+		return nil
+	case matched == 1 && last.label == input && !preview:
+		m.exitHintMode()
+		return last.follow()
+	}
+	m.hintInput = input
+	m.showHints()
+	return nil
+}
+
+// hintMatch returns the only hint the typed input still matches.
+func (m *Model) hintMatch() (linkHint, bool) {
+	var found linkHint
+	matched := 0
+	for _, hint := range m.hints {
+		if strings.HasPrefix(hint.label, m.hintInput) {
+			found = hint
+			matched++
+		}
+	}
+	return found, matched == 1
+}
+
+// hintStatus prompts for a label and previews the destination once the
+// input leaves a single hint.
+func (m *Model) hintStatus() string {
+	if m.hints == nil {
+		return ""
+	}
+	hint, ok := m.hintMatch()
+	switch {
+	case ok && hint.label == m.hintInput:
+		return "→ " + hint.dest + "  (Enter: 開く / Esc: 中止)"
+	case ok:
+		return "ヒント: " + m.hintInput + "  → " + hint.dest
+	}
+	return "ヒントを入力 (大文字で選択のみ / Esc: 中止) " + m.hintInput
+}
+
+// showHints overlays the labels still matching the typed prefix on the
+// rendered content, covering the start of each link.
+func (m *Model) showHints() {
+	lines := strings.Split(m.renderedContent, "\n")
+	for _, hint := range m.hints {
+		if !strings.HasPrefix(hint.label, m.hintInput) || hint.pos.line >= len(lines) {
+			continue
+		}
+		// Typed characters blank out, except on a label selected in full.
+		shown := strings.Repeat(" ", len(m.hintInput)) + strings.ToUpper(strings.TrimPrefix(hint.label, m.hintInput))
+		if hint.label == m.hintInput {
+			shown = strings.ToUpper(hint.label)
+		}
+		line := lines[hint.pos.line]
+		lines[hint.pos.line] = ansi.Truncate(line, hint.pos.col, "") + hintStyle.Render(shown) +
+			ansi.TruncateLeft(line, hint.pos.col+len(hint.label), "")
+	}
+	m.contentVP.SetContent(strings.Join(lines, "\n"))
+}
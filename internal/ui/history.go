@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/git"
+)
+
+// toggleHistory lists the commits that touched the active file and moves
+// the keys into the pane. With the pane shown but not focused it hides the
+// pane instead.
+func (m *Model) toggleHistory() {
+	if m.historyVisible && !m.historyFocus {
+		m.historyVisible = false
+		m.resize(m.width, m.height)
+		return
+	}
+	if m.activeAbsPath == "" {
+		m.err = errors.New("ファイルが開かれていません")
+		return
+	}
+	commits, err := git.Log(m.activeAbsPath)
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.history = commits
+	m.historyVisible, m.historyFocus = true, true
+	m.backlinksVisible, m.backlinksFocus = false, false
+	m.linksVisible, m.linksFocus = false, false
+	m.hideGrepPane()
+	// The first row is the working tree.
+	m.historySelection = 0
+	for i, commit := range commits {
+		if commit.Hash == m.rev {
+			m.historySelection = i + 1
+		}
+	}
+	m.resize(m.width, m.height)
+}
+
+func (m *Model) handleHistoryKey(key string) tea.Cmd {
+	switch key {
+	case "j", "down":
+		m.historySelection = min(m.historySelection+1, len(m.history))
+	case "k", "up":
+		m.historySelection = max(m.historySelection-1, 0)
+	case "esc":
+		m.historyFocus = false
+	case "H", "q":
+		m.historyVisible, m.historyFocus = false, false
+		m.resize(m.width, m.height)
+	case "w":
+		m.historySelection = 0
+		return m.showWorkingTree()
+	case "enter":
+		if m.historySelection == 0 {
+			return m.showWorkingTree()
+		}
+		commit := m.history[m.historySelection-1]
+		return m.showRevision(commit.Hash, commit.Hash+" "+commit.Date, commit.Path)
+	}
+	return nil
+}
+
+// showRevision shows the active file as of rev like a reload, keeping the
+// scroll position and highlighting what differs from the version shown.
+// path is the file's path from the repository root in rev.
+func (m *Model) showRevision(rev, label, path string) tea.Cmd {
+	prev, prevLabel, prevPath := m.rev, m.revLabel, m.revPath
+	m.rev, m.revLabel, m.revPath = rev, label, path
+	cmd := m.reloadActiveFile()
+	if m.err != nil {
+		m.rev, m.revLabel, m.revPath = prev, prevLabel, prevPath
+	}
+	return cmd
+}
+
+// showWorkingTree returns from a revision to the file on disk and watches it
+// again.
+func (m *Model) showWorkingTree() tea.Cmd {
+	if m.rev == "" {
+		return nil
+	}
+	m.rev, m.revLabel, m.revPath = "", "", ""
+	cmd := m.reloadActiveFile()
+	if m.watchedFile == "" {
+		return tea.Batch(cmd, m.startWatching(m.activeAbsPath))
+	}
+	return cmd
+}
+
+// historyHeight returns the rows taken by the history pane out of height.
+func (m *Model) historyHeight(height int) int {
+	if !m.historyVisible {
+		return 0
+	}
+	return listPaneHeight(height)
+}
+
+// historyView lists the working tree and the commits of the active file,
+// marking the version shown.
+func (m *Model) historyView() string {
+	title := fmt.Sprintf(" 履歴 (%d)", len(m.history))
+	if m.historyFocus {
+		title += "  (j/k: 選択 / Enter: その版を表示 / w: 作業ツリーに戻る / Esc: 本文へ戻る / H: 閉じる)"
+	}
+	current := func(shown bool) string {
+		if shown {
+			return "●"
+		}
+		return " "
+	}
+	rows := []string{fmt.Sprintf(" %s 作業ツリー", current(m.rev == ""))}
+	for _, commit := range m.history {
+		rows = append(rows, fmt.Sprintf(" %s %s  %s  %s", current(commit.Hash == m.rev), commit.Hash, commit.Date, commit.Subject))
+	}
+	return m.listPaneView(title, rows, m.historySelection, m.historyFocus)
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// svgSize matches the width and height attributes of an SVG root element.
+var svgSize = regexp.MustCompile(`<svg[^>]*?\swidth="([\d.]+)(?:px)?"[^>]*?\sheight="([\d.]+)(?:px)?"`)
+
+// localPath resolves an image or link target relative to the open document,
+// or to the assets base when one is set. Remote targets are returned
+// unchanged with ok false.
+func (m *Model) localPath(target string) (string, bool) {
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "data:") {
+		return target, false
+	}
+	target, _, _ = strings.Cut(target, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	switch {
+	case m.opts.AssetsBase != "":
+		// Resolved like the links glamour renders with --assets-base.
+		return filepath.Join(m.opts.AssetsBase, filepath.FromSlash(strings.TrimPrefix(target, "/"))), true
+	case filepath.IsAbs(target) || m.activeAbsPath == "":
+		return filepath.FromSlash(target), true
+	}
+	return filepath.Join(filepath.Dir(m.activeAbsPath), filepath.FromSlash(target)), true
+}
+
+// imageSize reads the dimensions of a local image from its header.
+func (m *Model) imageSize(target string) (int, int, bool) {
+	path, ok := m.localPath(target)
+	if !ok {
+		return 0, 0, false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".svg") {
+		head := make([]byte, 2048)
+		n, _ := f.Read(head)
+		match := svgSize.FindSubmatch(head[:n])
+		if match == nil {
+			return 0, 0, false
+		}
+		w, _ := strconv.ParseFloat(string(match[1]), 64)
+		h, _ := strconv.ParseFloat(string(match[2]), 64)
+		return int(w), int(h), w > 0 && h > 0
+	}
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// imageInView returns the first image whose placeholder is visible.
+func (m *Model) imageInView() (markdown.Image, bool) {
+	if len(m.images) == 0 {
+		return markdown.Image{}, false
+	}
+	lines := m.plainLines()
+	top := m.contentVP.YOffset
+	bottom := min(top+m.contentVP.Height, len(lines))
+	seen := 0
+	for i := 0; i < bottom; i++ {
+		count := strings.Count(lines[i], markdown.ImageLabel)
+		if i >= top && count > 0 && seen < len(m.images) {
+			return m.images[seen], true
+		}
+		seen += count
+	}
+	return markdown.Image{}, false
+}
+
+// openImage hands the first visible image to the desktop's default viewer.
+func (m *Model) openImage() {
+	img, ok := m.imageInView()
+	if !ok {
+		m.err = errors.New("表示範囲に画像がありません")
+		return
+	}
+	m.openImageTarget(img)
+}
+
+// openImageTarget hands an image of the document to the default viewer.
+func (m *Model) openImageTarget(img markdown.Image) {
+	target, _ := m.localPath(img.Target)
+	if err := openExternal(target); err != nil {
+		m.err = err
+	}
+}
+
+// openExternal hands a path or URL to the system opener, which starts the
+// browser or viewer registered for it.
+func openExternal(target string) error {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+	cmd, err := safemode.Command(opener, target)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the opener once it exits; the viewer it starts lives on.
+	go cmd.Wait()
+	return nil
+}
+
+// imageHintStatus points out the key opening the visible image.
+func (m *Model) imageHintStatus() string {
+	if _, ok := m.imageInView(); !ok {
+		return ""
+	}
+	return "o: 画像を開く"
+}
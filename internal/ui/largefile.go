@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// largePreviewSize is how much of a large file the truncated preview shows.
+const largePreviewSize = 64 << 10
+
+// largeView is how a file above Options.LargeFileSize is shown.
+type largeView int
+
+const (
+	// largeFull renders the whole file like any other.
+	largeFull largeView = iota
+	// largePlain shows the whole file as unstyled text, which costs no
+	// markdown rendering.
+	largePlain
+	// largeTruncated renders only the start of the file.
+	largeTruncated
+)
+
+// largeFilePrompt is a file waiting for the reader to choose how to open it.
+type largeFilePrompt struct {
+	absPath    string
+	headerPath string
+	size       int64
+	// place is the position to open the file at, as given to whenRendered.
+	place func()
+}
+
+// promptLargeFile asks how to open absPath instead of opening it when it is
+// above the large file size, and reports whether it did.
+func (m *Model) promptLargeFile(absPath, headerPath string) bool {
+	if m.opts.LargeFileSize <= 0 {
+		return false
+	}
+	info, err := os.Stat(absPath)
+	if err != nil || info.Size() <= m.opts.LargeFileSize {
+		return false
+	}
+	m.largePrompt = &largeFilePrompt{absPath: absPath, headerPath: headerPath, size: info.Size()}
+	return true
+}
+
+func (m *Model) handleLargeFileKey(key string) tea.Cmd {
+	prompt := m.largePrompt
+	view := largeFull
+	switch key {
+	case "o", "enter":
+	case "p":
+		view = largePlain
+	case "t":
+		view = largeTruncated
+	case "esc", "n", "q":
+		m.largePrompt = nil
+		return nil
+	default:
+		return nil
+	}
+	m.largePrompt = nil
+	cmd := m.loadFile(prompt.absPath, prompt.headerPath, view)
+	if prompt.place != nil && m.activeAbsPath == prompt.absPath {
+		m.whenRendered(prompt.place)
+	}
+	return cmd
+}
+
+// readFileAs reads the file at path as view shows it.
+func readFileAs(path string, view largeView) ([]byte, error) {
+	if view != largeTruncated {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, largePreviewSize))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) == info.Size() {
+		return data, nil
+	}
+	// Cut at a line end so the last block is not left half written.
+	if i := bytes.LastIndexByte(data, '\n'); i > 0 {
+		data = data[:i+1]
+	}
+	note := fmt.Sprintf("\n\n---\n\n*先頭の %s のみ表示しています (全体は %s)。*\n", formatSize(int64(len(data))), formatSize(info.Size()))
+	return append(data, note...), nil
+}
+
+// largeFileStatus is the prompt for a large file, or names how the active
+// one is shown when not in full.
+func (m *Model) largeFileStatus() string {
+	if prompt := m.largePrompt; prompt != nil {
+		return fmt.Sprintf("%s は %s あります  o: そのまま開く / p: テキストとして表示 / t: 先頭だけ表示 / Esc: やめる",
+			filepath.Base(prompt.absPath), formatSize(prompt.size))
+	}
+	switch m.largeView {
+	case largePlain:
+		return "テキスト表示"
+	case largeTruncated:
+		return "先頭のみ表示"
+	}
+	return ""
+}
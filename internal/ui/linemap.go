@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// lineKeyPrefix is how much of a source line's match key is looked up in the
+// rendered output. Short enough to survive re-wrapping, long enough to be
+// distinctive.
+const lineKeyPrefix = 24
+
+// sourceLineMap returns, for every line of the raw markdown, the rendered line
+// it ended up on. Lines are located in order by their letters and digits, so
+// the mapping is approximate for re-wrapped paragraphs but never goes
+// backwards. Lines that cannot be found (blank lines, bare markup) inherit the
+// position after the previous match.
+func (m *Model) sourceLineMap() []int {
+	if m.sourceMap != nil {
+		return m.sourceMap
+	}
+	rendered := m.plainLines()
+	var joined strings.Builder
+	starts := make([]int, len(rendered))
+	for i, line := range rendered {
+		starts[i] = joined.Len()
+		joined.WriteString(matchKey(line))
+	}
+	haystack := joined.String()
+	lineAt := func(offset int) int {
+		return sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+	}
+
+	source := strings.Split(m.rawContent, "\n")
+	mapping := make([]int, len(source))
+	cursor := 0
+	last := 0
+	found := false
+	for i, line := range source {
+		key := matchKey(line)
+		if len(key) > lineKeyPrefix {
+			key = key[:lineKeyPrefix]
+		}
+		if len(key) >= 3 {
+			if pos := strings.Index(haystack[cursor:], key); pos >= 0 {
+				last = lineAt(cursor + pos)
+				cursor += pos + len(key)
+				mapping[i] = last
+				found = true
+				continue
+			}
+		}
+		if found {
+			mapping[i] = min(last+1, max(len(rendered)-1, 0))
+		} else {
+			mapping[i] = 0
+		}
+	}
+	m.sourceMap = mapping
+	return mapping
+}
+
+// setRawContent replaces the markdown being shown. The line map and plain
+// lines describe the previous source until the next render installs its
+// output, which may still be running in the background, so they are dropped
+// here rather than left to setRendered.
+func (m *Model) setRawContent(content string) {
+	m.rawContent = content
+	m.sourceMap = nil
+	m.plainContent = nil
+}
+
+// plainLines returns the rendered document split into lines with styling
+// removed. It is cached until the next render.
+func (m *Model) plainLines() []string {
+	if m.plainContent == nil {
+		m.plainContent = strings.Split(ansi.Strip(m.renderedContent), "\n")
+	}
+	return m.plainContent
+}
+
+// renderedLineForSource maps a 1-based source line to a 0-based rendered line.
+func (m *Model) renderedLineForSource(line int) int {
+	mapping := m.sourceLineMap()
+	if len(mapping) == 0 {
+		return 0
+	}
+	return mapping[clamp(line-1, 0, len(mapping)-1)]
+}
+
+// gotoLine scrolls the viewport so the given 1-based line is at the top. The
+// number refers to rendered lines unless the sourcelines option is set.
+func (m *Model) gotoLine(line int) {
+	if line <= 0 {
+		return
+	}
+	target := line - 1
+	if m.opts.SourceLines {
+		target = m.renderedLineForSource(line)
+	}
+	m.contentVP.SetYOffset(target)
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// minLineNumberDigits keeps the gutter from changing width for every short
+// document.
+const minLineNumberDigits = 3
+
+// wrapIndicator marks gutter rows that continue the line above, e.g. a
+// paragraph the renderer wrapped.
+const wrapIndicator = "↪"
+
+var lineNumberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#3b4261"))
+
+// lineNumbersShown reports whether the content pane is prefixed with source
+// line numbers.
+func (m *Model) lineNumbersShown() bool {
+	return m.opts.LineNumbers && !m.zenMode
+}
+
+// lineNumberWidth returns the gutter width, including the separating space,
+// needed for the current document.
+func (m *Model) lineNumberWidth() int {
+	if !m.lineNumbersShown() {
+		return 0
+	}
+	lines := strings.Count(m.rawContent, "\n") + 1
+	return max(len(strconv.Itoa(lines)), minLineNumberDigits) + 1
+}
+
+// lineNumberView renders the gutter for the visible rows. Each rendered line
+// is labelled with the first source line mapped onto it, so re-wrapped
+// paragraphs show approximate numbers; rows that only continue the text above
+// get a wrap indicator instead.
+func (m *Model) lineNumberView() string {
+	height := m.contentVP.Height
+	if height <= 0 || m.gutterWidth <= 0 {
+		return ""
+	}
+	labels := make(map[int]int)
+	source := strings.Split(m.rawContent, "\n")
+	for i, row := range m.sourceLineMap() {
+		if i >= len(source) {
+			break
+		}
+		// Blank source lines carry no text to place them by, so they would
+		// only guess a row.
+		if strings.TrimSpace(source[i]) == "" {
+			continue
+		}
+		if _, ok := labels[row]; !ok {
+			labels[row] = i + 1
+		}
+	}
+	top := m.contentVP.Style.GetPaddingTop() + m.contentVP.Style.GetBorderTopSize()
+	digits := m.gutterWidth - 1
+	blank := strings.Repeat(" ", m.gutterWidth)
+	plain := m.plainLines()
+	rows := make([]string, height)
+	for i := range rows {
+		rows[i] = blank
+		line := m.contentVP.YOffset + i - top
+		if i < top || line >= len(plain) {
+			continue
+		}
+		if n, ok := labels[line]; ok {
+			rows[i] = lineNumberStyle.Render(padLeft(strconv.Itoa(n), digits)) + " "
+		} else if line > 0 && strings.TrimSpace(plain[line]) != "" && strings.TrimSpace(plain[line-1]) != "" {
+			rows[i] = lineNumberStyle.Render(padLeft(wrapIndicator, digits)) + " "
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// sourceLineAtTop returns the 1-based source line shown at the top of the
+// viewport, or 0 when it cannot be determined.
+func (m *Model) sourceLineAtTop() int {
+	line := 0
+	for i, row := range m.sourceLineMap() {
+		if row > m.contentVP.YOffset {
+			break
+		}
+		line = i + 1
+	}
+	return line
+}
+
+func padLeft(s string, width int) string {
+	if n := ansi.StringWidth(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
+
+// refreshLayout recomputes the pane sizes and re-renders while keeping the
+// reading position, e.g. after an option that affects the layout changed.
+func (m *Model) refreshLayout() {
+	ratio := m.scrollRatio()
+	m.resize(m.width, m.height)
+	m.setScrollRatio(ratio)
+}
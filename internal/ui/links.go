@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// linkDestination resolves a link of the open document to what following
+// it opens: a URL, a local path, or "" for a #fragment of the document
+// itself. external reports whether it leaves the viewer.
+func (m *Model) linkDestination(link markdown.Link) (dest string, external bool) {
+	switch {
+	case markdown.IsURL(link.Target):
+		if link.Fragment != "" {
+			return link.Target + "#" + link.Fragment, true
+		}
+		return link.Target, true
+	case link.Target == "":
+		return "", false
+	}
+	path, _ := m.localPath(link.Target)
+	return path, !markdown.Viewable(path)
+}
+
+// linkPreview describes where a link leads for the status bar: the URL, or
+// the absolute path with the heading it names.
+func (m *Model) linkPreview(link markdown.Link) string {
+	dest, _ := m.linkDestination(link)
+	if abs, err := filepath.Abs(dest); err == nil && dest != "" && !markdown.IsURL(dest) {
+		dest = abs
+	}
+	if link.Fragment != "" && !markdown.IsURL(link.Target) {
+		dest += "#" + link.Fragment
+	}
+	return dest
+}
+
+// followLink opens a link of the open document: URLs in the browser,
+// documents in the viewer at the heading the fragment names, other files
+// with the system opener, and a bare #fragment in place.
+func (m *Model) followLink(link markdown.Link) tea.Cmd {
+	dest, external := m.linkDestination(link)
+	if dest != "" && !markdown.IsURL(dest) {
+		if _, err := os.Stat(dest); err != nil {
+			m.err = errors.New("リンク先が見つかりません: " + link.Target)
+			return nil
+		}
+	}
+	if external {
+		if err := openExternal(dest); err != nil {
+			m.err = err
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	if dest != "" {
+		var err error
+		if cmd, err = m.editFile(dest); err != nil {
+			m.err = err
+			return nil
+		}
+	}
+	if link.Fragment != "" {
+		m.whenRendered(func() {
+			if err := m.jumpToAnchor(link.Fragment); err != nil {
+				m.err = err
+			}
+		})
+	}
+	return cmd
+}
+
+// linkLabel returns the text a link shows without its markup.
+func linkLabel(link markdown.Link) string {
+	label := strings.TrimSpace(markdown.PlainText(link.Label))
+	if label == "" {
+		label = link.Target
+	}
+	return label
+}
+
+// toggleLinks shows the links pane and moves the keys into it. With the
+// pane shown but not focused it hides the pane instead.
+func (m *Model) toggleLinks() {
+	if m.linksVisible && !m.linksFocus {
+		m.linksVisible = false
+		m.resize(m.width, m.height)
+		return
+	}
+	m.linksVisible, m.linksFocus = true, true
+	m.backlinksVisible, m.backlinksFocus = false, false
+	m.historyVisible, m.historyFocus = false, false
+	m.hideGrepPane()
+	m.linkSelection = 0
+	m.resize(m.width, m.height)
+}
+
+func (m *Model) handleLinksKey(key string) tea.Cmd {
+	switch key {
+	case "j", "down":
+		m.linkSelection = min(m.linkSelection+1, max(len(m.links)-1, 0))
+	case "k", "up":
+		m.linkSelection = max(m.linkSelection-1, 0)
+	case "esc":
+		m.linksFocus = false
+	case "L", "q":
+		m.linksVisible, m.linksFocus = false, false
+		m.resize(m.width, m.height)
+	case "enter":
+		if m.linkSelection >= len(m.links) {
+			return nil
+		}
+		link := m.links[m.linkSelection]
+		if dest, external := m.linkDestination(link); !external && dest != "" {
+			// The pane now lists the links of the opened document.
+			m.linksFocus, m.linkSelection = false, 0
+		}
+		return m.followLink(link)
+	}
+	return nil
+}
+
+// linksHeight returns the rows taken by the links pane out of height.
+func (m *Model) linksHeight(height int) int {
+	if !m.linksVisible {
+		return 0
+	}
+	return listPaneHeight(height)
+}
+
+// linkPreviewStatus shows where the link selected in the links pane leads.
+func (m *Model) linkPreviewStatus() string {
+	if !m.linksFocus || m.linkSelection >= len(m.links) {
+		return ""
+	}
+	return "→ " + m.linkPreview(m.links[m.linkSelection])
+}
+
+// linksView lists the links of the open document with where they lead.
+func (m *Model) linksView() string {
+	title := fmt.Sprintf(" リンク (%d)", len(m.links))
+	if m.linksFocus {
+		title += "  (j/k: 選択 / Enter: 開く / Esc: 本文へ戻る / L: 閉じる)"
+	}
+	rows := make([]string, len(m.links))
+	for i, link := range m.links {
+		dest := link.Target
+		if link.Fragment != "" {
+			dest += "#" + link.Fragment
+		}
+		rows[i] = fmt.Sprintf(" %s  → %s", linkLabel(link), dest)
+	}
+	if len(rows) == 0 {
+		rows = append(rows, " この文書にはリンクがありません")
+	}
+	return m.listPaneView(title, rows, m.linkSelection, m.linksFocus && len(m.links) > 0)
+}
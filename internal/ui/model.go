@@ -1,21 +1,28 @@
 package ui
 
 import (
-	"fmt"
-	"os"
+	"context"
+	"errors"
 	"path/filepath"
 	"strings"
+	"time"
 	"unicode"
 
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
-	styles "github.com/charmbracelet/glamour/styles"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/charmbracelet/x/ansi"
 	"github.com/fsnotify/fsnotify"
 
+	"github.com/kyaoi/mdview/internal/git"
+	"github.com/kyaoi/mdview/internal/grep"
+	"github.com/kyaoi/mdview/internal/linediff"
+	"github.com/kyaoi/mdview/internal/linkgraph"
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/remote"
 	"github.com/kyaoi/mdview/internal/tree"
 )
 
@@ -43,17 +50,18 @@ var (
 			BorderStyle(lipgloss.RoundedBorder()).
 			BorderForeground(lipgloss.Color("#7aa2f7")).
 			Background(lipgloss.Color("#1f2335"))
-	searchBarStyle = lipgloss.NewStyle().
-			Padding(0, 1).
-			Foreground(lipgloss.Color("#a9b1d6")).
-			Background(lipgloss.Color("#1f2335"))
 )
 
 // Model implements the Bubble Tea program for the markdown viewer.
 type Model struct {
 	contentVP          viewport.Model
 	treeVP             viewport.Model
+	sourceVP           viewport.Model
 	renderer           *glamour.TermRenderer
+	sourceRenderer     *glamour.TermRenderer
+	wrapWidth          int
+	gutterWidth        int
+	opts               Options
 	rawContent         string
 	headerPath         string
 	treeVisible        bool
@@ -61,12 +69,23 @@ type Model struct {
 	treeContentWidth   int
 	treeFocus          bool
 	showHelp           bool
+	showFrontMatter    bool
+	showAnchors        bool
+	rawView            bool
+	splitView          bool
+	zenMode            bool
+	widthPreset        int
 	pendingKey         string
+	count              int
 	ready              bool
+	quitting           bool
 	width              int
 	height             int
 	err                error
-	treeWidthLocked    bool
+	// notice is an informational message shown until the next key.
+	notice           string
+	treeWidthLocked  bool
+	draggingSplitter bool
 
 	treeRoot        *tree.Node
 	flatTree        []treeLine
@@ -76,22 +95,193 @@ type Model struct {
 	activeAbsPath   string
 	renderedContent string
 
+	headings        []renderedHeading
+	headingTrail    []int
+	headingTrailPos int
+	// images lists the images of the rendered document in order.
+	images []markdown.Image
+	// wikiLinks lists the wikilinks of the rendered document in order, and
+	// wikiLinkPos where each starts in the rendered output.
+	wikiLinks   []markdown.WikiLink
+	wikiLinkPos []markPos
+	// links lists the other links of the rendered document in order, and
+	// linkPos where each starts.
+	links   []markdown.Link
+	linkPos []markPos
+	// hints holds the labelled links while hint mode is on and hintInput
+	// the part of a label typed so far.
+	hints     []linkHint
+	hintInput string
+	// footnotes lists the footnotes of the rendered document by number,
+	// footnoteSpots its references and definitions in order and
+	// footnotePos where each spot is. footnoteReturn is one past the offset
+	// to go back to from footnote footnoteReturnNote.
+	footnotes          []markdown.Footnote
+	footnoteSpots      []markdown.FootnoteSpot
+	footnotePos        []markPos
+	footnoteReturn     int
+	footnoteReturnNote int
+
+	commandInput  textinput.Model
+	commandActive bool
+	completion    *completionState
+	fileIndex     []string
+	graph         *linkgraph.Graph
+	sourceMap     []int
+	plainContent  []string
+
+	filterInput  textinput.Model
+	filterActive bool
+	treeFilter   string
+	filterKeep   map[*tree.Node]bool
+
 	searchInput   textinput.Model
 	searchActive  bool
 	searchQuery   string
 	searchMatches []int
 	searchIndex   int
-
-	watcher          *fsnotify.Watcher
-	watchDir         string
-	watchedFile      string
+	searchOrigin  searchOrigin
+	// searchHistory holds the past queries, oldest first. searchRecall is
+	// the entry shown in the search input, len(searchHistory) for the text
+	// typed, which searchDraft keeps while recalling.
+	searchHistory       []string
+	searchHistoryLoaded bool
+	searchRecall        int
+	searchDraft         string
+	// ignoreCase and smartCase are the :set options deciding whether a
+	// search tells upper from lower case.
+	ignoreCase bool
+	smartCase  bool
+	// wholeWord is the :set option limiting matches to whole words.
+	wholeWord bool
+	// searchFrom is the line a new search picks its first match from, the
+	// top of the view unless set beforehand, or -1 once the matches are
+	// known.
+	searchFrom int
+	// hideMatches leaves the matches unstyled until the next search or n/N.
+	hideMatches bool
+	// markedContent is the rendered output with the match markers still in
+	// place, so the current match can be styled anew without rendering.
+	markedContent string
+	// styledMatch and styledHidden are the current match and hideMatches
+	// the shown content was styled for.
+	styledMatch  int
+	styledHidden bool
+	searchStates map[string]searchState
+
+	scratchInput   textarea.Model
+	scratchVisible bool
+	scratchFocus   bool
+	scratchLoaded  bool
+	scratchSaved   string
+
+	backlinks         map[string][]linkgraph.Edge
+	backlinksVisible  bool
+	linksVisible      bool
+	linksFocus        bool
+	linkSelection     int
+	backlinksFocus    bool
+	backlinkSelection int
+	history           []git.Commit
+	historyVisible    bool
+	historyFocus      bool
+	historySelection  int
+	grepVisible       bool
+	grepFocus         bool
+	grepInput         textinput.Model
+	grepQuery         string
+	grepMatches       []grep.Match
+	grepSelection     int
+	grepErr           error
+	grepGen           int
+	grepRunning       bool
+	grepCancel        context.CancelFunc
+	// quickfix is the result list of the last grep that ]q and [q step
+	// through, quickfixIndex the result open or -1.
+	quickfix      []grep.Match
+	quickfixQuery string
+	quickfixIndex int
+
+	watcher       *fsnotify.Watcher
+	watchDir      string
+	treeWatchDirs map[string]bool
+	watchedFile   string
+	// watchedTarget is the file watchedFile links to, when it is a
+	// symlink, and watchTargetDir the extra directory watched for it.
+	watchedTarget  string
+	watchTargetDir string
+	// openFiles holds the files opened this session, which stay watched,
+	// and changedFiles those that changed while another was shown.
+	openFiles        map[string]bool
+	changedFiles     map[string]bool
 	watchChan        chan tea.Msg
 	initialWatchPath string
+	// initialAnchor is the heading to scroll to after the first render.
+	initialAnchor string
+	// initialLine is the source line to scroll to after the first render.
+	initialLine int
+	// remoteDoc is the downloaded document shown for a URL argument.
+	remoteDoc *remote.Document
+	// rev is the git revision the active file is shown at, or empty for
+	// the working tree, and revLabel how the status bar names it. revPath
+	// is where the file was in rev when it has been renamed since.
+	rev      string
+	revLabel string
+	revPath  string
+	// diffMode shows the active file's changes since HEAD; diffBase holds
+	// the lines of the committed version of diffBasePath, and diffScript
+	// the edit script from it to diffScriptOf.
+	diffMode     bool
+	diffBase     []string
+	diffBasePath string
+	diffScript   []linediff.Line
+	diffScriptOf string
+	diffInserted int
+	diffDeleted  int
+	// changedLines holds the source lines changed by the last reload while
+	// they are highlighted; changeGen numbers the reloads.
+	changedLines map[int]bool
+	changeGen    int
+	// reloadChanged counts the lines the last reload changed, and
+	// reloadNotice is the status message announcing it.
+	reloadChanged int
+	reloadNotice  string
+	// autoReload re-reads the active file when the watcher reports a
+	// change; while it is off, reloadPending records that one was missed.
+	autoReload    bool
+	reloadPending bool
+	// treeLoads queues the directories to read in the background, and
+	// pendingReveal is the entry to select once they are read.
+	treeLoads     []treeLoad
+	pendingReveal string
+	// largePrompt is the large file waiting to be opened, and largeView
+	// how the active file is shown.
+	largePrompt *largeFilePrompt
+	largeView   largeView
+	// rendering is the render running in the background, if any,
+	// renderGen numbers the renders so that only the latest is shown, and
+	// afterRender places the view once it is.
+	rendering   *renderJob
+	renderGen   int
+	afterRender func()
+	renderCache renderCache
+	spinner     spinner.Model
+	// diagramQueue holds the diagrams waiting for an external command,
+	// and drawingDiagrams those queued or being drawn.
+	diagramQueue    []markdown.Diagram
+	drawingDiagrams map[markdown.Diagram]bool
+	// reloadGen numbers the watcher events waiting out the debounce, and
+	// reloadOps gathers what they reported.
+	reloadGen int
+	reloadOps fsnotify.Op
+	// awaitGen numbers the waits for a replaced file to reappear.
+	awaitGen int
 }
 
 type treeLine struct {
-	entry *tree.Node
-	label string
+	entry  *tree.Node
+	label  string
+	detail string
 }
 
 type fileEventMsg struct {
@@ -99,6 +289,10 @@ type fileEventMsg struct {
 	op   fsnotify.Op
 }
 
+// reloadDueMsg reloads the active file once the watcher event numbered gen
+// was the last of its burst.
+type reloadDueMsg struct{ gen int }
+
 type fileWatchErrMsg struct {
 	err error
 }
@@ -106,16 +300,17 @@ type fileWatchErrMsg struct {
 // NewModel constructs the viewer model with the provided initial state.
 func NewModel(state State) *Model {
 	contentVP := viewport.New(0, 0)
-	contentVP.Style = lipgloss.NewStyle().Padding(0, 1)
+	contentVP.Style = contentStyle
 	contentVP.SetHorizontalStep(2)
 
 	treeVP := viewport.New(0, 0)
-	treeVP.Style = treePanelStyle(treeBlurBorderColor)
-	treeVP.MouseWheelEnabled = false
+	treeVP.Style = treePanelStyle(treeBlurBorderColor, state.Options.TreeRight)
 
 	m := &Model{
 		contentVP:          contentVP,
 		treeVP:             treeVP,
+		sourceVP:           newSourceViewport(),
+		opts:               state.Options,
 		rawContent:         state.RawContent,
 		headerPath:         state.HeaderPath,
 		treeVisible:        state.TreeVisible && state.TreeRoot != nil,
@@ -125,8 +320,12 @@ func NewModel(state State) *Model {
 		displayRoot:        state.DisplayRoot,
 		activeAbsPath:      state.ActiveAbsPath,
 		searchIndex:        -1,
+		searchFrom:         -1,
+		ignoreCase:         true,
+		autoReload:         !state.Options.ConfirmReload,
 	}
 
+	m.spinner = spinner.New(spinner.WithSpinner(spinner.MiniDot))
 	searchInput := textinput.New()
 	searchInput.Prompt = "/"
 	searchInput.CharLimit = 256
@@ -134,12 +333,36 @@ func NewModel(state State) *Model {
 	searchInput.CursorEnd()
 	searchInput.Blur()
 	m.searchInput = searchInput
+	m.commandInput = newCommandInput()
+	m.filterInput = newFilterInput()
+	m.scratchInput = newScratchInput()
+	m.grepInput = newGrepInput()
 
+	if state.Remote != nil {
+		m.remoteDoc = state.Remote
+		m.initialAnchor = state.Anchor
+	}
 	if state.ActiveAbsPath != "" {
-		m.initialWatchPath = state.ActiveAbsPath
+		m.rev, m.revLabel = state.Rev, state.RevLabel
+		if m.rev == "" {
+			m.initialWatchPath = state.ActiveAbsPath
+		}
+		m.initialAnchor = state.Anchor
+		m.initialLine = state.Line
+		if m.initialLine == 0 {
+			m.initialLine = state.Options.Line
+		}
 	}
 
+	if m.opts.TreeWidth > 0 {
+		m.treePreferredWidth = m.opts.TreeWidth
+		m.treeWidthLocked = true
+	}
+	if m.opts.TreeSort == "" {
+		m.opts.TreeSort = tree.SortName
+	}
 	if m.treeRoot != nil {
+		m.treeRoot.Sort(m.opts.TreeSort)
 		m.refreshTreeViewWithSelection(state.TreeSelectionPath)
 	}
 	m.updateTreePanelStyle()
@@ -153,10 +376,17 @@ func NewModel(state State) *Model {
 
 // Init implements tea.Model.
 func (m *Model) Init() tea.Cmd {
+	walk, watchingTree := m.startTreeWatch()
 	if m.initialWatchPath != "" {
 		path := m.initialWatchPath
 		m.initialWatchPath = ""
-		return m.startWatching(path)
+		return tea.Batch(walk, m.startWatching(path))
+	}
+	if watchingTree {
+		return tea.Batch(walk, m.waitForFileEvent())
+	}
+	if m.remoteDoc != nil {
+		return m.pollRemote()
 	}
 	return nil
 }
@@ -164,29 +394,106 @@ func (m *Model) Init() tea.Cmd {
 // View implements tea.Model.
 func (m *Model) View() string {
 	body := m.contentVP.View()
-	if m.treeVisible {
-		body = lipgloss.JoinHorizontal(lipgloss.Top, m.treeVP.View(), body)
+	if m.gutterWidth > 0 {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, m.lineNumberView(), body)
+	}
+	if m.scrollbarShown() {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, m.scrollbarView())
+	}
+	if m.splitView {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, m.sourceVP.View(), body)
 	}
 
-	if m.err != nil {
-		errLine := lipgloss.NewStyle().Foreground(lipgloss.Color("#ff6b6b")).Render(m.err.Error())
-		body = lipgloss.JoinVertical(lipgloss.Left, errLine, body)
+	if m.zenMode && m.width > 0 {
+		body = lipgloss.PlaceHorizontal(m.width, lipgloss.Center, body)
+	} else if m.widthPreset > 0 && m.width > 0 {
+		area := m.width
+		if m.treeShown() {
+			area -= m.treeVP.Width + 1
+		}
+		body = lipgloss.PlaceHorizontal(area, lipgloss.Center, body)
+	}
+
+	if m.treeShown() {
+		if m.opts.TreeRight {
+			body = lipgloss.JoinHorizontal(lipgloss.Top, body, m.treeVP.View())
+		} else {
+			body = lipgloss.JoinHorizontal(lipgloss.Top, m.treeVP.View(), body)
+		}
+	}
+
+	if m.backlinksVisible {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, m.backlinksView())
+	}
+	if m.linksVisible {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, m.linksView())
+	}
+	if m.historyVisible {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, m.historyView())
+	}
+	if m.grepVisible {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, m.grepView())
+	}
+	if m.scratchVisible {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, m.scratchView())
+	}
+
+	if m.showFrontMatter {
+		overlay := m.frontMatterView()
+		if m.width > 0 && m.height > 0 {
+			return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, overlay)
+		}
+		return overlay
 	}
 
 	if m.showHelp {
 		helpContent := strings.Join([]string{
 			"ヘルプ (?:閉じる / Esc)",
 			"Ctrl+h / Ctrl+l : ツリー↔本文フォーカス切替",
-			"Alt+h / Alt+l   : サイドバー幅縮小 / 拡張",
+			"Alt+h / Alt+l   : サイドバー幅縮小 / 拡張 (< / > も可)",
 			"j / k            : 選択/スクロール (フォーカス中のペイン)",
 			"Ctrl+d / Ctrl+u : 半ページ移動 (本文フォーカス時)",
 			"Ctrl+f / Ctrl+b : 半ページ移動 (ツリーフォーカス時)",
 			"gg / G           : 先頭 / 末尾へ移動",
+			"]] / [[          : 次 / 前の見出しへ移動",
+			"} / {            : 次 / 前の段落・ブロックへ移動",
+			"Ctrl+o / Tab     : 見出し移動履歴を戻る / 進む",
 			"h / l            : ツリー開閉・水平スクロール",
 			"Enter / l        : ツリーでファイルを開く",
-			"/                : 検索モード開始",
+			"/                : 検索モード開始 (ツリーフォーカス時は絞り込み)",
+			"f                : リンクにヒントを表示し、入力したヒントのリンクを開く (大文字で行き先を確認、ツリーでは絞り込み)",
 			"n / N            : 次 / 前の一致へ移動",
 			"t                : ツリー表示のトグル",
+			"D                : ツリーに更新日時・サイズを表示",
+			"S                : ツリーの並び順 (名前→更新日時→サイズ)",
+			"R                : ツリーをディスクから再読み込み",
+			"r                : 表示中のファイルを再読み込み",
+			":set noautoreload: 変更を検知しても自動で再読み込みしない",
+			".                : 隠しファイル・ディレクトリの表示切替",
+			"A                : ツリーに Markdown 以外のファイルも表示",
+			"z                : 集中 (Zen) モードのトグル",
+			"1 / 2 / 3        : 表示幅 80 / 100 / 全幅 (Alt+1 / 2 / 3 も可)",
+			"{count}%         : 文書の count% の位置へ移動",
+			":123 / 123G      : 指定行へ移動 (:set sourcelines でソース行基準)",
+			":anchor <名前>   : 見出しアンカーへ移動 (--slug の形式)",
+			":e <パス>        : ファイルを開く (Tab であいまい補完)",
+			":set number!     : ソース行番号の表示切替",
+			"T                : タイポグラフィ (引用符・ダッシュ) のトグル",
+			"v                : 整形表示 / Markdown ソース表示の切替",
+			"V                : ソースと整形表示の左右分割表示",
+			"M                : フロントマターを生のまま表示",
+			"o                : 表示中の画像を既定のビューアで開く",
+			"enter (本文)     : 表示中の最初の [[ウィキリンク]] を開く",
+			"#                : 見出しの横にアンカーを表示",
+			"d                : 最後のコミット (HEAD) からの変更を強調表示",
+			"y                : 画面先頭の見出しへのリンク (file.md#anchor) をコピー",
+			"F                : 表示中の脚注参照から脚注へ移動 / 参照元へ戻る",
+			"s                : スクラッチ欄の表示 (Esc で本文へ戻り保存)",
+			"b                : このファイルへのバックリンク一覧 (Enter で参照元を開く)",
+			"H                : このファイルの git の履歴 (Enter でその版を表示、w で作業ツリーに戻る)",
+			"L                : 文書内のリンク一覧 (Enter で開く。URL はブラウザで)",
+			"Ctrl+g           : 配下の Markdown を全文検索 (入力のたびに検索、Enter で該当行を開く)",
+			"]q / [q          : 全文検索の次 / 前の結果をファイルをまたいで開く",
 			"q / Ctrl+c       : 終了",
 		}, "\n")
 		helpOverlay := helpBoxStyle.Render(helpContent)
@@ -196,31 +503,122 @@ func (m *Model) View() string {
 		return helpOverlay
 	}
 
-	if m.searchActive {
-		body = lipgloss.JoinVertical(lipgloss.Left, body, searchBarStyle.Render(m.searchInput.View()))
-	} else if m.searchQuery != "" {
-		status := m.searchStatusLine()
-		if status != "" {
-			body = lipgloss.JoinVertical(lipgloss.Left, body, searchBarStyle.Render(status))
-		}
+	if status := m.statusBarView(); status != "" {
+		body = lipgloss.JoinVertical(lipgloss.Left, body, status)
 	}
-
 	return body
 }
 
 // Update implements tea.Model.
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	start := time.Now()
+	model, cmd := m.update(msg)
+	m.syncSourcePane()
+	if debugLog != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			debugf("key %q: %s", key.String(), time.Since(start))
+		} else if took := time.Since(start); took >= slowUpdate {
+			debugf("update %T: %s", msg, took)
+		}
+	}
+	if wait := m.awaitRender(); wait != nil {
+		cmd = tea.Batch(cmd, wait)
+	}
+	if loads := m.awaitTreeLoads(); loads != nil {
+		cmd = tea.Batch(cmd, loads)
+	}
+	if draw := m.awaitDiagrams(); draw != nil {
+		cmd = tea.Batch(cmd, draw)
+	}
+	return model, cmd
+}
+
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case fileEventMsg:
 		return m, m.handleFileEvent(msg)
 	case fileWatchErrMsg:
 		m.err = msg.err
 		return m, m.waitForFileEvent()
+	case remoteRefreshedMsg:
+		return m, m.handleRemoteRefresh(msg)
 	case tea.WindowSizeMsg:
 		m.resize(msg.Width, msg.Height)
 		return m, nil
+	case ReloadMsg:
+		return m, m.reloadAndAnnounce()
+	case treeLoadedMsg:
+		m.handleTreeLoaded(msg)
+		return m, nil
+	case treeDirsMsg:
+		if m.treeWatchDirs != nil {
+			m.watchDirs(msg.dirs)
+		}
+		return m, nil
+	case renderedMsg:
+		m.handleRendered(msg)
+		return m, nil
+	case diagramsDrawnMsg:
+		m.handleDiagramsDrawn(msg)
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.handleSpinner(msg)
+	case changesFadedMsg:
+		m.fadeChanges(msg)
+		return m, nil
+	case reloadDueMsg:
+		if msg.gen != m.reloadGen || !m.autoReload {
+			return m, nil
+		}
+		ops := m.reloadOps
+		m.reloadOps = 0
+		return m, m.reloadWatched(ops)
+	case fileReappearMsg:
+		return m, m.handleFileReappear(msg)
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+	case grepDoneMsg:
+		m.handleGrepDone(msg)
+		return m, nil
+	case treeWidthSavedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		return m, nil
 
 	case tea.KeyMsg:
+		m.notice = ""
+		if m.commandActive {
+			cmd := m.handleCommandKey(msg)
+			if m.quitting {
+				return m, tea.Quit
+			}
+			return m, cmd
+		}
+		if m.largePrompt != nil {
+			return m, m.handleLargeFileKey(msg.String())
+		}
+		if m.filterActive {
+			return m, m.handleFilterKey(msg)
+		}
+		if m.scratchFocus {
+			return m, m.handleScratchKey(msg)
+		}
+		if m.backlinksFocus {
+			return m, m.handleBacklinksKey(msg.String())
+		}
+		if m.linksFocus {
+			return m, m.handleLinksKey(msg.String())
+		}
+		if m.historyFocus {
+			return m, m.handleHistoryKey(msg.String())
+		}
+		if m.grepFocus {
+			return m, m.handleGrepKey(msg)
+		}
+		if m.hints != nil {
+			return m, m.handleHintKey(msg)
+		}
 		if m.searchActive {
 			switch msg.Type {
 			case tea.KeyEnter:
@@ -230,14 +628,29 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.clearSearch()
 					return m, nil
 				}
-				m.performSearch(query, true)
+				m.rememberSearch(query)
+				if query != m.searchQuery {
+					m.performSearch(query, true)
+				} else {
+					// Typing already searched; show the matches again
+					// in case they were hidden.
+					m.hideMatches = false
+					m.gotoSearchMatch()
+				}
 				return m, nil
 			case tea.KeyEsc, tea.KeyCtrlC:
-				m.exitSearchMode()
+				m.cancelSearch()
+				return m, nil
+			case tea.KeyUp:
+				m.recallSearch(-1)
+				return m, nil
+			case tea.KeyDown:
+				m.recallSearch(1)
 				return m, nil
 			}
 			var cmd tea.Cmd
 			m.searchInput, cmd = m.searchInput.Update(msg)
+			m.searchIncrementally(m.searchInput.Value())
 			return m, cmd
 		}
 
@@ -255,9 +668,17 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		key := msg.String()
-		if key != "g" {
+		prefix := m.pendingKey
+		if key != m.pendingKey {
 			m.pendingKey = ""
 		}
+		if !m.showHelp && !m.showFrontMatter && !(m.treeFocus && m.treeShown()) && m.consumeCountDigit(key) {
+			return m, nil
+		}
+		count := m.takeCount()
+		if m.presetFromCount(count, key) {
+			count = 0
+		}
 
 		if m.showHelp {
 			m.pendingKey = ""
@@ -267,27 +688,43 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		}
+		if m.showFrontMatter {
+			switch key {
+			case "q", "M", "esc":
+				m.showFrontMatter = false
+			}
+			return m, nil
+		}
+
+		if key == "q" && (prefix == "]" || prefix == "[") {
+			if prefix == "]" {
+				return m, m.stepQuickfix(1)
+			}
+			return m, m.stepQuickfix(-1)
+		}
 
 		switch key {
 		case "q", "ctrl+c":
+			m.saveScratch()
+			m.quitting = true
 			return m, tea.Quit
 		case "?":
 			m.showHelp = true
 			m.pendingKey = ""
 			return m, nil
 		case "ctrl+h":
-			if m.treeVisible {
+			if m.treeShown() {
 				m.focusTree()
 			}
 			return m, nil
 		case "ctrl+l":
 			m.blurTree()
 			return m, nil
-		case "alt+h":
+		case "alt+h", "<":
 			if m.adjustTreeWidth(-treeResizeStep) {
 				return m, nil
 			}
-		case "alt+l":
+		case "alt+l", ">":
 			if m.adjustTreeWidth(treeResizeStep) {
 				return m, nil
 			}
@@ -300,8 +737,82 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.resize(m.width, m.height)
 			}
 			return m, nil
+		case "T":
+			m.opts.Typography = !m.opts.Typography
+			m.rerenderKeepingPosition()
+			return m, nil
+		case "v":
+			if m.splitView {
+				m.toggleSplitView()
+			}
+			m.rawView = !m.rawView
+			m.rerenderKeepingPosition()
+			return m, nil
+		case "V":
+			if !m.zenMode {
+				m.toggleSplitView()
+			}
+			return m, nil
+		case "z":
+			m.toggleZenMode()
+			return m, nil
+		case "M":
+			m.toggleFrontMatter()
+			return m, nil
+		case "D":
+			m.toggleTreeDetails()
+			return m, nil
+		case "S":
+			m.cycleTreeSort()
+			return m, nil
+		case "s":
+			return m, m.toggleScratch()
+		case "b":
+			m.toggleBacklinks()
+			return m, nil
+		case "L":
+			m.toggleLinks()
+			return m, nil
+		case "H":
+			m.toggleHistory()
+			return m, nil
+		case "ctrl+g":
+			return m, m.toggleGrep()
+		case "R":
+			m.reloadTree()
+			return m, nil
+		case "r":
+			return m, m.forceReload()
+		case ".":
+			m.toggleHidden()
+			return m, nil
+		case "A":
+			m.toggleAllFiles()
+			return m, nil
+		case "o":
+			m.openImage()
+			return m, nil
+		case "enter":
+			if !(m.treeFocus && m.treeShown()) {
+				return m, m.followWikiLink()
+			}
+		case "1", "2", "3", "alt+1", "alt+2", "alt+3":
+			if m.applyWidthPreset(key) {
+				return m, nil
+			}
+		case "f":
+			if m.treeFocus && m.treeShown() {
+				return m, m.enterFilterMode()
+			}
+			m.enterHintMode()
+			return m, nil
 		case "/":
+			if m.treeFocus && m.treeShown() {
+				return m, m.enterFilterMode()
+			}
 			return m, m.enterSearchMode()
+		case ":":
+			return m, m.enterCommandMode()
 		case "n":
 			if len(m.searchMatches) > 0 {
 				m.nextSearchMatch()
@@ -314,7 +825,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-		if m.treeFocus && m.treeVisible {
+		if m.treeFocus && m.treeShown() {
 			handled, cmd := m.handleTreeKey(key)
 			if handled {
 				return m, cmd
@@ -325,7 +836,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		handled := m.handleContentKey(key)
+		handled := m.handleContentKey(key, count)
 		if handled {
 			return m, nil
 		}
@@ -340,7 +851,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *Model) handleContentKey(key string) bool {
+func (m *Model) handleContentKey(key string, count int) bool {
 	switch key {
 	case "j":
 		m.contentVP.ScrollDown(1)
@@ -364,7 +875,43 @@ func (m *Model) handleContentKey(key string) bool {
 		return true
 	case "G":
 		m.pendingKey = ""
-		m.contentVP.GotoBottom()
+		if count > 0 {
+			m.gotoLine(count)
+		} else {
+			m.contentVP.GotoBottom()
+		}
+	case "%":
+		m.jumpToPercent(count)
+	case "}":
+		m.jumpParagraph(1, count)
+	case "{":
+		m.jumpParagraph(-1, count)
+	case "]", "[":
+		if m.pendingKey == key {
+			if key == "]" {
+				m.jumpHeading(1)
+			} else {
+				m.jumpHeading(-1)
+			}
+			m.pendingKey = ""
+		} else {
+			m.pendingKey = key
+		}
+		return true
+	case "ctrl+o":
+		m.stepHeadingTrail(-1)
+	case "tab":
+		m.stepHeadingTrail(1)
+	case "F":
+		m.jumpFootnote()
+	case "#":
+		m.toggleAnchors()
+	case "d":
+		m.toggleDiff()
+	case "y":
+		m.yankAnchor()
+	case "esc":
+		return m.hideSearchHighlight()
 	default:
 		return false
 	}
@@ -412,6 +959,15 @@ func (m *Model) handleTreeKey(key string) (bool, tea.Cmd) {
 		return true, nil
 	case "enter":
 		return true, m.openOrDescend()
+	case "]", "[":
+		// Only ]q and [q step through the grep results from the tree.
+		m.pendingKey = key
+		return true, nil
+	case "esc":
+		if m.treeFilter != "" {
+			m.setTreeFilter("")
+			return true, nil
+		}
 	case "g":
 		if m.pendingKey == "g" {
 			if len(m.flatTree) > 0 {
@@ -438,7 +994,7 @@ func (m *Model) handleTreeKey(key string) (bool, tea.Cmd) {
 }
 
 func (m *Model) resize(width, height int) {
-	if width <= 0 || height <= headerHeight {
+	if width <= 0 || height <= headerHeight+statusBarHeight {
 		return
 	}
 
@@ -448,42 +1004,66 @@ func (m *Model) resize(width, height int) {
 
 	treeWidth := m.treeWidth(width)
 	contentWidth := width - treeWidth
-	if m.treeVisible && treeWidth > 0 {
+	if m.treeShown() && treeWidth > 0 {
 		contentWidth--
 	}
 	if contentWidth < minContentWidth {
 		contentWidth = minContentWidth
 	}
+	if m.zenMode {
+		contentWidth = m.zenWidth(width)
+	}
 
-	contentHeight := max(height-headerHeight, 1)
+	scratchHeight := m.scratchHeight(height - headerHeight - statusBarHeight)
+	m.resizeScratch(width, scratchHeight)
+	panesHeight := scratchHeight + m.backlinksHeight(height-headerHeight-statusBarHeight) + m.linksHeight(height-headerHeight-statusBarHeight) +
+		m.historyHeight(height-headerHeight-statusBarHeight) + m.grepHeight(height-headerHeight-statusBarHeight)
+	contentHeight := max(height-headerHeight-statusBarHeight-panesHeight, 1)
+	if m.scrollbarShown() {
+		contentWidth--
+	}
+	sourceWidth, contentWidth := m.splitWidths(contentWidth)
+	m.gutterWidth = m.lineNumberWidth()
+	contentWidth = max(contentWidth-m.gutterWidth, minContentWidth)
+	contentWidth = m.presetContentWidth(contentWidth)
 	m.contentVP.Width = contentWidth
 	m.contentVP.Height = contentHeight
+	m.sourceVP.Width = sourceWidth
+	m.sourceVP.Height = contentHeight
 
 	wrapWidth := contentWidth - m.contentVP.Style.GetHorizontalFrameSize()
 	if wrapWidth < 0 {
 		wrapWidth = 0
 	}
 
-	renderer, err := newRenderer(wrapWidth)
+	m.wrapWidth = wrapWidth
+	renderer, err := newRenderer(wrapWidth, m.opts)
 	if err != nil {
 		m.err = err
 		return
 	}
 	m.renderer = renderer
 
-	rendered, err := m.renderer.Render(m.rawContent)
-	if err != nil {
-		m.err = err
-		return
+	m.sourceRenderer = nil
+	if sourceWidth > 0 {
+		sourceWrap := max(sourceWidth-m.sourceVP.Style.GetHorizontalFrameSize(), 0)
+		sourceRenderer, err := newRenderer(sourceWrap, m.opts)
+		if err != nil {
+			m.err = err
+			return
+		}
+		m.sourceRenderer = sourceRenderer
 	}
-	m.err = nil
-	m.contentVP.SetContent(rendered)
-	m.renderedContent = rendered
-	m.onContentChanged()
+	m.renderMarkdown()
+	m.applyInitialPosition()
 
-	if m.treeVisible && treeWidth > 0 {
+	if m.treeShown() && treeWidth > 0 {
 		m.treeVP.Width = treeWidth
 		m.treeVP.Height = contentHeight
+		if m.opts.TreeDetails {
+			// The detail column is aligned to the panel's current width.
+			m.updateTreeContent(m.treeContentWidth)
+		}
 		m.ensureSelectionVisible()
 	} else {
 		m.treeVP.Width = 0
@@ -491,8 +1071,28 @@ func (m *Model) resize(width, height int) {
 	}
 }
 
+// applyInitialPosition scrolls to the anchor or line given on the command
+// line once the first document has been rendered, since its headings and
+// lines are only known then.
+func (m *Model) applyInitialPosition() {
+	if m.rendering != nil {
+		return
+	}
+	if m.initialAnchor != "" {
+		anchor := m.initialAnchor
+		m.initialAnchor = ""
+		if err := m.jumpToAnchor(anchor); err != nil {
+			m.err = err
+		}
+	}
+	if m.initialLine > 0 {
+		m.contentVP.SetYOffset(m.renderedLineForSource(m.initialLine))
+		m.initialLine = 0
+	}
+}
+
 func (m *Model) adjustTreeWidth(delta int) bool {
-	if !m.treeVisible || m.width == 0 || delta == 0 {
+	if !m.treeShown() || m.width == 0 || delta == 0 {
 		return false
 	}
 	preferred := m.treePreferredWidth
@@ -505,18 +1105,23 @@ func (m *Model) adjustTreeWidth(delta int) bool {
 	if maxPanel == 0 {
 		return false
 	}
-	newPreferred := clamp(preferred+delta, minPanel, maxPanel)
-	if newPreferred == preferred {
+	return m.setTreePreferredWidth(clamp(preferred+delta, minPanel, maxPanel))
+}
+
+// setTreePreferredWidth pins the tree panel content width, stopping it from
+// following the longest entry, and re-lays out the panes.
+func (m *Model) setTreePreferredWidth(width int) bool {
+	if width == m.treePreferredWidth && m.treeWidthLocked {
 		return false
 	}
 	m.treeWidthLocked = true
-	m.treePreferredWidth = newPreferred
+	m.treePreferredWidth = width
 	m.resize(m.width, m.height)
 	return true
 }
 
 func (m *Model) treeWidth(totalWidth int) int {
-	if !m.treeVisible {
+	if !m.treeShown() {
 		return 0
 	}
 	preferred := m.treePreferredWidth
@@ -554,14 +1159,12 @@ func (m *Model) openOrDescend() tea.Cmd {
 	}
 	if entry.IsDir {
 		if !entry.Open {
+			// Listing the directory reads it, in the background if need be.
 			entry.Open = true
-			if !m.loadNode(entry) {
-				return nil
-			}
 			m.refreshTreeViewWithSelection(entry.Path)
 			return nil
 		}
-		if !m.loadNode(entry) {
+		if !m.loadNodeAsync(entry) {
 			return nil
 		}
 		if len(entry.Children) > 0 {
@@ -606,14 +1209,43 @@ func (m *Model) openFileEntry(entry *tree.Node) tea.Cmd {
 		return nil
 	}
 	absPath := filepath.Join(m.rootDir, filepath.FromSlash(entry.Path))
-	data, err := os.ReadFile(absPath)
+	return m.openFile(absPath, composeDisplayPath(m.displayRoot, entry.Path))
+}
+
+// openFile shows the markdown file at absPath and starts watching it. A file
+// above the large file size is only opened once the reader says how.
+func (m *Model) openFile(absPath, headerPath string) tea.Cmd {
+	if m.promptLargeFile(absPath, headerPath) {
+		return nil
+	}
+	return m.loadFile(absPath, headerPath, largeFull)
+}
+
+// loadFile shows the file at absPath as view and starts watching it.
+func (m *Model) loadFile(absPath, headerPath string, view largeView) tea.Cmd {
+	data, err := readFileAs(absPath, view)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	content, err := markdown.Convert(absPath, data)
 	if err != nil {
 		m.err = err
 		return nil
 	}
-	m.rawContent = string(data)
+	m.saveSearchState()
+	m.afterRender = nil
+	m.setRawContent(content)
 	m.activeAbsPath = absPath
-	m.headerPath = composeDisplayPath(m.displayRoot, entry.Path)
+	m.headerPath = headerPath
+	m.largeView = view
+	m.rev, m.revLabel, m.revPath = "", "", ""
+	m.history = nil
+	m.changedLines = nil
+	m.restoreSearchState()
+	m.revealInTree(absPath)
+	m.resetHeadingTrail()
+	m.footnoteReturn = 0
 	m.renderMarkdown()
 	m.contentVP.GotoTop()
 	if m.err != nil {
@@ -622,19 +1254,21 @@ func (m *Model) openFileEntry(entry *tree.Node) tea.Cmd {
 	return m.startWatching(absPath)
 }
 
-func (m *Model) renderMarkdown() {
-	if m.renderer == nil {
+// revealInTree expands and selects absPath in the tree so that files opened
+// from anywhere other than the tree itself stay in sync with it.
+func (m *Model) revealInTree(absPath string) {
+	if m.treeRoot == nil || m.rootDir == "" {
 		return
 	}
-	rendered, err := m.renderer.Render(m.rawContent)
-	if err != nil {
-		m.err = err
+	rel, err := filepath.Rel(m.rootDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
 		return
 	}
-	m.err = nil
-	m.contentVP.SetContent(rendered)
-	m.renderedContent = rendered
-	m.onContentChanged()
+	rel = filepath.ToSlash(rel)
+	if entry := m.currentTreeEntry(); entry != nil && entry.Path == rel {
+		return
+	}
+	m.refreshTreeViewWithSelection(rel)
 }
 
 func (m *Model) refreshTreeViewWithSelection(path string) {
@@ -666,20 +1300,30 @@ func (m *Model) expandPath(path string) {
 	if !m.treeRoot.Open {
 		m.treeRoot.Open = true
 	}
-	parts := strings.Split(path, "/")
+	// Descend by path prefix rather than by name: nodes grouping a tag
+	// filter's roots may stand for several path segments at once.
 	current := m.treeRoot
-	for _, part := range parts {
-		if !m.loadNode(current) {
+	for current.Path != path {
+		if !m.loadNodeAsync(current) {
+			if current.Loading() {
+				m.pendingReveal = path
+			}
 			return
 		}
-		child := current.ChildByName(part)
-		if child == nil {
+		var next *tree.Node
+		for _, child := range current.Children {
+			if child.Path == path || strings.HasPrefix(path, child.Path+"/") {
+				next = child
+				break
+			}
+		}
+		if next == nil {
 			return
 		}
-		if child.IsDir {
-			child.Open = true
+		if next.IsDir {
+			next.Open = true
 		}
-		current = child
+		current = next
 	}
 }
 
@@ -690,15 +1334,30 @@ func (m *Model) rebuildFlatTree() int {
 	}
 	var lines []treeLine
 	maxWidth := 0
+	maxDetail := 0
+	now := time.Now()
 	var walk func(*tree.Node, int)
 	walk = func(node *tree.Node, depth int) {
-		label := formatTreeLabel(node, depth)
+		if m.filteredOut(node) {
+			return
+		}
+		// A filter shows every directory leading to a match.
+		open := node.Open || m.treeFilter != ""
+		label := formatTreeLabel(node, depth, open, m.opts.Icons) + m.changedMark(node.Path)
 		if w := lipgloss.Width(label); w > maxWidth {
 			maxWidth = w
 		}
-		lines = append(lines, treeLine{entry: node, label: label})
-		if node.IsDir && node.Open {
-			if !m.loadNode(node) {
+		line := treeLine{entry: node, label: label}
+		if m.opts.TreeDetails {
+			line.detail = entryDetail(node, now)
+			maxDetail = max(maxDetail, lipgloss.Width(line.detail))
+		}
+		lines = append(lines, line)
+		if node.IsDir && open {
+			if !m.loadNodeAsync(node) {
+				if node.Loading() {
+					lines = append(lines, treeLoadingLine(node, depth+1))
+				}
 				return
 			}
 			for _, child := range node.Children {
@@ -708,6 +1367,9 @@ func (m *Model) rebuildFlatTree() int {
 	}
 	walk(m.treeRoot, 0)
 	m.flatTree = lines
+	if maxDetail > 0 {
+		maxWidth += maxDetail + 2
+	}
 	return maxWidth
 }
 
@@ -718,16 +1380,20 @@ func (m *Model) updateTreeContent(width int) {
 	if width <= 0 {
 		width = minTreePanelWidth
 	}
+	inner := width
+	if m.treeVP.Width > 0 {
+		inner = m.treeVP.Width - m.treeVP.Style.GetHorizontalFrameSize()
+	}
 	var builder strings.Builder
 	for i, line := range m.flatTree {
-		text := line.label
+		text := alignTreeDetail(line.label, line.detail, inner)
 		switch {
 		case i == m.treeSelection && m.treeFocus:
 			builder.WriteString(treeSelectedActive.Render(text))
 		case i == m.treeSelection:
 			builder.WriteString(treeSelectedInactive.Render(text))
 		default:
-			builder.WriteString(treeLineStyle.Render(text))
+			builder.WriteString(m.treeEntryStyle(line.entry).Render(text))
 		}
 		if i < len(m.flatTree)-1 {
 			builder.WriteByte('\n')
@@ -785,30 +1451,36 @@ func (m *Model) updateTreePanelStyle() {
 	if m.treeFocus {
 		color = treeFocusBorderColor
 	}
-	m.treeVP.Style = treePanelStyle(color)
+	m.treeVP.Style = treePanelStyle(color, m.opts.TreeRight)
 }
 
-func treePanelStyle(color lipgloss.Color) lipgloss.Style {
+// treePanelStyle draws the tree's separating border on the side facing the
+// content pane.
+func treePanelStyle(color lipgloss.Color, right bool) lipgloss.Style {
 	return lipgloss.NewStyle().
 		Padding(0, 1).
 		BorderStyle(lipgloss.NormalBorder()).
-		BorderRight(true).
+		BorderRight(!right).
+		BorderLeft(right).
 		BorderForeground(color)
 }
 
-func formatTreeLabel(entry *tree.Node, depth int) string {
+func formatTreeLabel(entry *tree.Node, depth int, open bool, icons IconStyle) string {
 	if depth == 0 {
 		return entry.Name + "/"
 	}
 	indent := strings.Repeat("  ", depth-1)
 	indicator := "  "
 	if entry.IsDir {
-		if entry.Open {
+		if open {
 			indicator = "- "
 		} else {
 			indicator = "+ "
 		}
 	}
+	if icons == IconsNerd {
+		indicator += nerdIcon(entry, open) + " "
+	}
 	label := indent + indicator + entry.Name
 	if entry.IsDir {
 		label += "/"
@@ -827,16 +1499,6 @@ func composeDisplayPath(root, rel string) string {
 	return filepath.ToSlash(filepath.Join(root, rel))
 }
 
-func newRenderer(width int) (*glamour.TermRenderer, error) {
-	opts := []glamour.TermRendererOption{glamour.WithStandardStyle(styles.TokyoNightStyle)}
-	if width > 0 {
-		opts = append(opts, glamour.WithWordWrap(width))
-	} else {
-		opts = append(opts, glamour.WithWordWrap(0))
-	}
-	return glamour.NewTermRenderer(opts...)
-}
-
 func clamp(value, low, high int) int {
 	if value < low {
 		return low
@@ -858,181 +1520,23 @@ func (m *Model) loadNode(node *tree.Node) bool {
 	if node == nil {
 		return false
 	}
+	if !node.Loaded() && debugLog != nil {
+		start := time.Now()
+		defer func() { debugf("tree load %q: %s", node.Path, time.Since(start)) }()
+	}
 	if err := node.EnsureLoaded(); err != nil {
 		m.err = err
 		return false
 	}
-	return true
-}
-
-func (m *Model) enterSearchMode() tea.Cmd {
-	m.searchActive = true
-	m.pendingKey = ""
-	if m.searchQuery != "" {
-		m.searchInput.SetValue(m.searchQuery)
-		m.searchInput.CursorEnd()
-	} else {
-		m.searchInput.SetValue("")
-	}
-	return m.searchInput.Focus()
-}
-
-func (m *Model) exitSearchMode() {
-	m.searchActive = false
-	m.searchInput.Blur()
-}
-
-func (m *Model) clearSearch() {
-	m.searchQuery = ""
-	m.searchMatches = nil
-	m.searchIndex = -1
-	m.err = nil
-}
-
-func (m *Model) searchStatusLine() string {
-	if m.searchQuery == "" {
-		return ""
+	if m.treeWatchDirs != nil && node.IsDir && node.HasLoader() {
+		// Directories below --max-depth were not watched up front.
+		m.watchDirs([]string{filepath.Join(m.rootDir, filepath.FromSlash(node.Path))})
 	}
-	total := len(m.searchMatches)
-	if total == 0 || m.searchIndex < 0 {
-		return fmt.Sprintf("/%s (0/0)", m.searchQuery)
-	}
-	current := m.searchIndex + 1
-	return fmt.Sprintf("/%s (%d/%d)", m.searchQuery, current, total)
-}
-
-func (m *Model) performSearch(query string, resetIndex bool) {
-	query = strings.TrimSpace(query)
-	m.searchQuery = query
-	m.searchMatches = findSearchMatches(m.renderedContent, query)
-	if len(m.searchMatches) == 0 {
-		m.searchIndex = -1
-		m.err = fmt.Errorf("%q に一致しません。", query)
-		return
-	}
-	if resetIndex || m.searchIndex < 0 || m.searchIndex >= len(m.searchMatches) {
-		m.searchIndex = 0
-	}
-	m.err = nil
-	m.gotoSearchMatch()
-}
-
-func (m *Model) nextSearchMatch() {
-	if len(m.searchMatches) == 0 {
-		return
-	}
-	if m.searchIndex < 0 {
-		m.searchIndex = 0
-	} else {
-		m.searchIndex = (m.searchIndex + 1) % len(m.searchMatches)
-	}
-	m.err = nil
-	m.gotoSearchMatch()
-}
-
-func (m *Model) previousSearchMatch() {
-	if len(m.searchMatches) == 0 {
-		return
-	}
-	if m.searchIndex <= 0 {
-		m.searchIndex = len(m.searchMatches) - 1
-	} else {
-		m.searchIndex--
-	}
-	m.err = nil
-	m.gotoSearchMatch()
-}
-
-func (m *Model) gotoSearchMatch() {
-	if len(m.searchMatches) == 0 || m.searchIndex < 0 {
-		return
-	}
-	totalLines := strings.Count(m.renderedContent, "\n") + 1
-	if totalLines <= 0 {
-		return
-	}
-	targetLine := m.searchMatches[m.searchIndex]
-	maxOffset := max(totalLines-m.contentVP.Height, 0)
-	offset := clamp(targetLine, 0, maxOffset)
-	m.contentVP.SetYOffset(offset)
-}
-
-func (m *Model) onContentChanged() {
-	if m.searchQuery == "" {
-		return
-	}
-
-	prevLine := -1
-	if len(m.searchMatches) > 0 && m.searchIndex >= 0 && m.searchIndex < len(m.searchMatches) {
-		prevLine = m.searchMatches[m.searchIndex]
-	}
-
-	m.searchMatches = findSearchMatches(m.renderedContent, m.searchQuery)
-	if len(m.searchMatches) == 0 {
-		m.searchIndex = -1
-		m.err = fmt.Errorf("%q に一致しません。", m.searchQuery)
-		return
-	}
-
-	if prevLine >= 0 {
-		m.searchIndex = closestMatchIndex(m.searchMatches, prevLine)
-	} else if m.searchIndex < 0 || m.searchIndex >= len(m.searchMatches) {
-		m.searchIndex = 0
-	}
-	m.err = nil
-	m.gotoSearchMatch()
-}
-
-func findSearchMatches(content, query string) []int {
-	query = strings.TrimSpace(query)
-	if query == "" || content == "" {
-		return nil
-	}
-
-	stripped := ansi.Strip(content)
-	lowerContent := strings.ToLower(stripped)
-	lowerQuery := strings.ToLower(query)
-
-	var matches []int
-	offset := 0
-	for {
-		pos := strings.Index(lowerContent[offset:], lowerQuery)
-		if pos == -1 {
-			break
-		}
-		absolute := offset + pos
-		line := strings.Count(stripped[:absolute], "\n")
-		matches = append(matches, line)
-		offset = absolute + len(lowerQuery)
-	}
-	return matches
-}
-
-func closestMatchIndex(matches []int, line int) int {
-	if len(matches) == 0 {
-		return 0
-	}
-	bestIndex := 0
-	bestDiff := absInt(matches[0] - line)
-	for i := 1; i < len(matches); i++ {
-		diff := absInt(matches[i] - line)
-		if diff < bestDiff {
-			bestDiff = diff
-			bestIndex = i
-		}
-	}
-	return bestIndex
-}
-
-func absInt(v int) int {
-	if v < 0 {
-		return -v
-	}
-	return v
+	return true
 }
 
 func (m *Model) startWatching(path string) tea.Cmd {
-	if path == "" {
+	if path == "" || m.opts.NoWatch {
 		return nil
 	}
 	path = filepath.Clean(path)
@@ -1041,11 +1545,9 @@ func (m *Model) startWatching(path string) tea.Cmd {
 		return nil
 	}
 
+	// The previous directory stays watched for the files opened there.
 	dir := filepath.Dir(path)
 	if dir != m.watchDir {
-		if m.watchDir != "" {
-			_ = m.watcher.Remove(m.watchDir)
-		}
 		if err := m.watcher.Add(dir); err != nil {
 			m.err = err
 			return nil
@@ -1054,6 +1556,9 @@ func (m *Model) startWatching(path string) tea.Cmd {
 	}
 
 	m.watchedFile = path
+	m.keepWatching(path)
+	m.awaitGen++
+	m.armTarget()
 	return m.waitForFileEvent()
 }
 
@@ -1110,32 +1615,83 @@ func (m *Model) waitForFileEvent() tea.Cmd {
 }
 
 func (m *Model) handleFileEvent(msg fileEventMsg) tea.Cmd {
+	debugf("watch %s %s", msg.op, msg.path)
+	m.handleTreeEvent(msg)
+	if m.markOpenFileChanged(filepath.Clean(msg.path)) {
+		return m.waitForFileEvent()
+	}
 	if m.watchedFile == "" {
 		return m.waitForFileEvent()
 	}
 
-	if filepath.Clean(msg.path) != filepath.Clean(m.watchedFile) {
+	if filepath.Clean(msg.path) == m.watchDir && msg.op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		// The directory went away with its watch; wait for it to return.
+		m.awaitGen++
+		return tea.Batch(m.awaitReplacement(0), m.waitForFileEvent())
+	}
+	if !m.watchesFile(msg.path) {
+		return m.waitForFileEvent()
+	}
+	if !m.autoReload {
+		m.reloadPending = true
 		return m.waitForFileEvent()
 	}
+	if m.opts.ReloadDebounce > 0 {
+		// Each event restarts the wait, so a burst ends in one reload.
+		m.reloadGen++
+		m.reloadOps |= msg.op
+		gen := m.reloadGen
+		return tea.Batch(m.waitForFileEvent(), tea.Tick(m.opts.ReloadDebounce, func(time.Time) tea.Msg {
+			return reloadDueMsg{gen: gen}
+		}))
+	}
 
-	m.reloadActiveFile()
-	return m.waitForFileEvent()
+	return tea.Batch(m.reloadWatched(msg.op), m.waitForFileEvent())
 }
 
-func (m *Model) reloadActiveFile() {
+// readActiveFile reads the active file from the working tree, or from git
+// when it is shown at a revision.
+func (m *Model) readActiveFile() ([]byte, error) {
+	if m.rev != "" {
+		return git.ShowAt(m.activeAbsPath, m.rev, m.revPath)
+	}
+	return readFileAs(m.activeAbsPath, m.largeView)
+}
+
+// forceReload re-reads the active file on request, whether or not it is
+// known to have changed.
+func (m *Model) forceReload() tea.Cmd {
+	if m.activeAbsPath == "" && m.remoteDoc == nil {
+		m.err = errors.New("ファイルが開かれていません")
+		return nil
+	}
+	m.reloadPending = false
+	cmd := m.reloadAndAnnounce()
+	if m.err == nil && m.reloadChanged == 0 {
+		m.notice = "再読み込みしました"
+	}
+	return cmd
+}
+
+// reloadActiveFile re-reads the active file and returns the command fading
+// the highlight of the lines that changed.
+func (m *Model) reloadActiveFile() tea.Cmd {
+	if m.remoteDoc != nil && m.activeAbsPath == "" {
+		return m.applyRemote(refreshRemote(*m.remoteDoc))
+	}
 	if m.activeAbsPath == "" {
-		return
+		return nil
 	}
-	data, err := os.ReadFile(m.activeAbsPath)
+	data, err := m.readActiveFile()
 	if err != nil {
 		m.err = err
-		return
+		return nil
 	}
 
-	offset := m.contentVP.YOffset
-	m.rawContent = string(data)
-	m.renderMarkdown()
-	if m.err == nil {
-		m.contentVP.SetYOffset(offset)
+	content, err := markdown.Convert(m.activeAbsPath, data)
+	if err != nil {
+		m.err = err
+		return nil
 	}
+	return m.showReloaded(content)
 }
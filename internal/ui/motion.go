@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+)
+
+// countLimit caps the numeric prefix so typing a long run of digits cannot
+// overflow.
+const countLimit = 1_000_000
+
+// countMotions are the keys that use a count.
+var countMotions = map[string]bool{
+	"G": true,
+	"%": true,
+	"{": true,
+	"}": true,
+}
+
+// consumeCountDigit accumulates vim-style numeric prefixes such as the "50" in
+// "50%". A leading zero is not a count and is left to other bindings.
+func (m *Model) consumeCountDigit(key string) bool {
+	if len(key) != 1 || key[0] < '0' || key[0] > '9' {
+		return false
+	}
+	if key == "0" && m.count == 0 {
+		return false
+	}
+	digit, _ := strconv.Atoi(key)
+	m.count = min(m.count*10+digit, countLimit)
+	return true
+}
+
+// takeCount returns the pending numeric prefix and clears it.
+func (m *Model) takeCount() int {
+	count := m.count
+	m.count = 0
+	return count
+}
+
+// jumpToPercent scrolls so the line at percent of the rendered document is at
+// the top of the viewport, using the same rounding as vim's N%.
+func (m *Model) jumpToPercent(percent int) {
+	total := m.contentVP.TotalLineCount()
+	if total == 0 || percent <= 0 {
+		return
+	}
+	percent = min(percent, 100)
+	line := (percent*total+99)/100 - 1
+	m.contentVP.SetYOffset(max(line, 0))
+}
+
+// jumpParagraph moves the top of the viewport to the start of the count-th
+// next (dir > 0) or previous (dir < 0) block of rendered text, where blocks
+// are separated by blank lines as in vim's { and } motions.
+func (m *Model) jumpParagraph(dir, count int) {
+	lines := m.plainLines()
+	if len(lines) == 0 {
+		return
+	}
+	blank := func(i int) bool { return strings.TrimSpace(lines[i]) == "" }
+	isStart := func(i int) bool { return !blank(i) && (i == 0 || blank(i-1)) }
+
+	line := clamp(m.contentVP.YOffset, 0, len(lines)-1)
+	for n := max(count, 1); n > 0; n-- {
+		next := line
+		for {
+			next += dir
+			if next < 0 || next >= len(lines) {
+				next = -1
+				break
+			}
+			if isStart(next) {
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		line = next
+	}
+	if line == m.contentVP.YOffset && dir > 0 {
+		m.contentVP.GotoBottom()
+		return
+	}
+	if line == m.contentVP.YOffset && dir < 0 {
+		m.contentVP.GotoTop()
+		return
+	}
+	m.contentVP.SetYOffset(line)
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/config"
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// mouseWheelLines is how far one wheel notch scrolls either pane.
+const mouseWheelLines = 3
+
+// handleMouse scrolls the pane under the pointer and turns left clicks into
+// focus changes and tree selections.
+func (m *Model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.showHelp || m.showFrontMatter || m.searchActive || m.commandActive {
+		return nil
+	}
+	if m.draggingSplitter {
+		return m.dragSplitter(msg)
+	}
+	treeX := m.treeX(msg.X)
+	overTree := m.treeShown() && treeX >= 0 && treeX < m.treeVP.Width && msg.Y < m.treeVP.Height
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		lines := mouseWheelLines
+		if msg.Button == tea.MouseButtonWheelUp {
+			lines = -lines
+		}
+		if overTree {
+			m.treeVP.SetYOffset(m.treeVP.YOffset + lines)
+		} else {
+			m.contentVP.SetYOffset(m.contentVP.YOffset + lines)
+		}
+		return nil
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionPress {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if overTree && treeX == m.treeBorderX() {
+		m.draggingSplitter = true
+		return nil
+	}
+	if !overTree {
+		if msg.Y < m.contentVP.Height {
+			m.blurTree()
+		}
+		return nil
+	}
+	row := m.treeVP.YOffset + msg.Y - m.treeVP.Style.GetBorderTopSize() - m.treeVP.Style.GetPaddingTop()
+	if row < 0 || row >= len(m.flatTree) {
+		m.focusTree()
+		return nil
+	}
+	m.treeSelection = row
+	m.focusTree()
+	entry := m.currentTreeEntry()
+	if entry != nil && entry.IsDir && entry.Open {
+		m.closeOrAscend()
+		return nil
+	}
+	return m.openOrDescend()
+}
+
+// treeX converts a screen column into a column within the tree panel, which
+// is negative when x lies left of a right-hand tree. resize leaves one spare
+// column next to the tree, so a right-hand tree starts one column early.
+func (m *Model) treeX(x int) int {
+	if m.opts.TreeRight {
+		return x - (m.width - m.treeVP.Width - 1)
+	}
+	return x
+}
+
+// treeBorderX is the tree panel column holding the border that can be dragged.
+func (m *Model) treeBorderX() int {
+	if m.opts.TreeRight {
+		return 0
+	}
+	return m.treeVP.Width - 1
+}
+
+// treeWidthSavedMsg reports the outcome of persisting a dragged tree width.
+type treeWidthSavedMsg struct {
+	err error
+}
+
+// dragSplitter follows the pointer while the tree border is held and saves
+// the final width to the config file once the button is released.
+func (m *Model) dragSplitter(msg tea.MouseMsg) tea.Cmd {
+	frame := m.treeVP.Style.GetHorizontalFrameSize()
+	minPanel := max(minTreePanelWidth-frame, 0)
+	maxPanel := max(m.width/2-frame, minPanel)
+	width := clamp(m.treeX(msg.X)+1-frame, minPanel, maxPanel)
+	if m.opts.TreeRight {
+		width = clamp(m.treeVP.Width-m.treeX(msg.X)-frame, minPanel, maxPanel)
+	}
+
+	switch msg.Action {
+	case tea.MouseActionMotion:
+		m.setTreePreferredWidth(width)
+		return nil
+	case tea.MouseActionRelease:
+		m.draggingSplitter = false
+		m.setTreePreferredWidth(width)
+		return saveTreeWidth(m.treePreferredWidth)
+	}
+	return nil
+}
+
+func saveTreeWidth(width int) tea.Cmd {
+	if safemode.Enabled() {
+		return nil
+	}
+	return func() tea.Msg {
+		err := config.Set("tree_width", width)
+		if err != nil {
+			err = fmt.Errorf("ツリー幅を設定ファイルに保存できませんでした: %w", err)
+		}
+		return treeWidthSavedMsg{err: err}
+	}
+}
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// keepWatching records path as opened this session. Its directory stays
+// watched after another file is opened, so changes to it are noticed even
+// while it is not shown.
+func (m *Model) keepWatching(path string) {
+	if m.openFiles == nil {
+		m.openFiles = make(map[string]bool)
+	}
+	m.openFiles[path] = true
+	if m.changedFiles[path] {
+		delete(m.changedFiles, path)
+		m.refreshTreeViewWithSelection(m.selectedTreePath())
+	}
+}
+
+// markOpenFileChanged records that path, a file opened earlier but not shown
+// now, changed on disk, and reports whether it was one. Opening it again
+// reads it afresh.
+func (m *Model) markOpenFileChanged(path string) bool {
+	if !m.openFiles[path] || path == m.watchedFile {
+		return false
+	}
+	if m.changedFiles == nil {
+		m.changedFiles = make(map[string]bool)
+	}
+	if !m.changedFiles[path] {
+		m.changedFiles[path] = true
+		m.refreshTreeViewWithSelection(m.selectedTreePath())
+	}
+	return true
+}
+
+// changedMark returns the mark shown after a tree entry for a file opened
+// earlier that changed since.
+func (m *Model) changedMark(rel string) string {
+	if len(m.changedFiles) == 0 || m.rootDir == "" {
+		return ""
+	}
+	if m.changedFiles[filepath.Join(m.rootDir, filepath.FromSlash(rel))] {
+		return " ●"
+	}
+	return ""
+}
+
+// changedFilesStatus names the files opened earlier that changed since.
+func (m *Model) changedFilesStatus() string {
+	switch len(m.changedFiles) {
+	case 0:
+		return ""
+	case 1:
+		for path := range m.changedFiles {
+			return "更新あり: " + filepath.Base(path)
+		}
+	}
+	return fmt.Sprintf("更新あり: %d 件", len(m.changedFiles))
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/remote"
+)
+
+// remoteRefreshedMsg carries the result of checking a remote document for
+// changes.
+type remoteRefreshedMsg struct {
+	doc     remote.Document
+	changed bool
+	err     error
+}
+
+func refreshRemote(doc remote.Document) remoteRefreshedMsg {
+	doc, changed, err := remote.Refresh(doc)
+	return remoteRefreshedMsg{doc: doc, changed: changed, err: err}
+}
+
+// pollRemote schedules the next check of the remote document.
+func (m *Model) pollRemote() tea.Cmd {
+	if m.remoteDoc == nil || m.opts.NoWatch {
+		return nil
+	}
+	doc := *m.remoteDoc
+	return tea.Tick(remote.PollInterval, func(time.Time) tea.Msg {
+		return refreshRemote(doc)
+	})
+}
+
+func (m *Model) handleRemoteRefresh(msg remoteRefreshedMsg) tea.Cmd {
+	if !m.autoReload {
+		// The next check compares against the version shown, so the change
+		// is reported again until it is reloaded.
+		m.reloadPending = m.reloadPending || msg.changed
+		return m.pollRemote()
+	}
+	m.reloadChanged = 0
+	cmd := m.applyRemote(msg)
+	m.announceReload()
+	return tea.Batch(cmd, m.pollRemote())
+}
+
+// applyRemote shows the remote document again if it changed, like a reload
+// from disk. Failed checks are reported but keep the last version on screen.
+func (m *Model) applyRemote(msg remoteRefreshedMsg) tea.Cmd {
+	if m.remoteDoc == nil {
+		return nil
+	}
+	if msg.err != nil {
+		m.err = msg.err
+		return nil
+	}
+	m.remoteDoc = &msg.doc
+	if !msg.changed {
+		return nil
+	}
+	content, err := msg.doc.Markdown()
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	return m.showReloaded(content)
+}
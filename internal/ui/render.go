@@ -0,0 +1,258 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/glamour"
+	styles "github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// defaultRenderTimeout bounds how long a single glamour render may block the
+// update loop before the watchdog gives up on it.
+const defaultRenderTimeout = 3 * time.Second
+
+var errRenderTimeout = errors.New("render timed out")
+
+func (m *Model) renderMarkdown() {
+	if m.renderer == nil {
+		return
+	}
+	if m.lineNumberWidth() != m.gutterWidth {
+		// A document with more digits in its line count needs a wider gutter.
+		m.resize(m.width, m.height)
+		return
+	}
+	if m.showingText() {
+		m.err = nil
+		m.setRendered(m.wrapPlain(markdown.MarkMatches(m.rawContent, m.searchQuery, true, m.matchOptions())))
+		return
+	}
+	anywhere := !m.rawView && !m.showingCode() && wrapsAnywhere(m.opts.Wrap, m.rawContent)
+	m.startRender(m.renderSource(), anywhere)
+}
+
+// finishRender installs the output of a render, wrapping it anywhere when
+// the document's language asks for it.
+func (m *Model) finishRender(rendered string, err error, anywhere bool) {
+	if errors.Is(err, errRenderTimeout) {
+		m.showRenderFallback()
+		return
+	}
+	if err != nil {
+		m.err = err
+		return
+	}
+	if anywhere {
+		rendered = wrapAnywhere(rendered, m.wrapWidth)
+	}
+	m.err = nil
+	m.setRendered(rendered)
+}
+
+// setRendered installs freshly rendered output and refreshes everything that
+// is derived from it.
+func (m *Model) setRendered(rendered string) {
+	m.hints, m.hintInput = nil, ""
+	rendered, m.wikiLinkPos = takeMarks(rendered, markdown.WikiLinkMark)
+	rendered, m.footnotePos = takeMarks(rendered, markdown.FootnoteMark)
+	rendered, m.linkPos = takeMarks(rendered, markdown.LinkMark)
+	m.markedContent = highlightDiff(colorCallouts(rendered))
+	matches := m.styleMatches()
+	m.sourceMap = nil
+	m.plainContent = nil
+	m.indexHeadings()
+	if m.showAnchors {
+		m.renderedContent = m.withHeadingAnchors(m.renderedContent)
+		m.contentVP.SetContent(m.renderedContent)
+	}
+	m.onContentChanged(matches)
+	m.renderSourcePane()
+}
+
+// renderTimeout returns how long a render may take before the raw source is
+// shown instead.
+func (m *Model) renderTimeout() time.Duration {
+	if m.opts.RenderTimeout <= 0 {
+		return defaultRenderTimeout
+	}
+	return m.opts.RenderTimeout
+}
+
+// Render renders src as the viewer would at the given wrap width, for
+// subcommands that work without a terminal.
+func Render(src string, width int, opts Options) (string, error) {
+	renderer, err := newRenderer(width, opts)
+	if err != nil {
+		return "", err
+	}
+	source, _, _ := markdown.Footnotes(convertBlocks(src))
+	source, _ = markdown.WikiLinks(source, nil)
+	source, _ = markdown.ImagePlaceholders(source, nil)
+	if opts.Typography {
+		source = markdown.Typography(source)
+	}
+	rendered, err := renderer.Render(source)
+	if err != nil {
+		return "", err
+	}
+	if wrapsAnywhere(opts.Wrap, src) {
+		rendered = wrapAnywhere(rendered, width)
+	}
+	rendered, _ = takeMarks(rendered, markdown.WikiLinkMark)
+	rendered, _ = takeMarks(rendered, markdown.FootnoteMark)
+	return colorCallouts(rendered), nil
+}
+
+// showRenderFallback displays the raw markdown, wrapped but unstyled, when a
+// render took too long, and explains why in the status bar.
+func (m *Model) showRenderFallback() {
+	m.setRendered(m.wrapPlain(m.rawContent))
+	m.err = fmt.Errorf("レンダリングが %s 以内に終わらなかったため Markdown ソースを表示しています", m.renderTimeout())
+}
+
+// convertBlocks replaces the tables, diagrams, math and callouts written in
+// src with markdown glamour can show.
+func convertBlocks(src string) string {
+	return markdown.Callouts(markdown.Math(markdown.FencedDiagrams(markdown.FencedTables(src))))
+}
+
+// convertBlocksLater is convertBlocks for the viewer, which cannot wait for
+// external diagram commands: the blocks they have not drawn yet keep their
+// source and are queued to be drawn in the background.
+func (m *Model) convertBlocksLater(src string) string {
+	if m.opts.Sync {
+		return convertBlocks(src)
+	}
+	src, pending := markdown.CachedDiagrams(markdown.FencedTables(src))
+	m.queueDiagrams(pending)
+	return markdown.Callouts(markdown.Math(src))
+}
+
+// renderSource returns the markdown handed to glamour after the optional
+// source-level passes have been applied.
+func (m *Model) renderSource() string {
+	if m.showingCode() {
+		if isBinary(m.rawContent) {
+			return "*バイナリファイルのため表示できません。*"
+		}
+		return markdown.CodeBlock(markdown.MarkMatches(m.rawContent, m.searchQuery, true, m.matchOptions()), markdown.Language(m.activeAbsPath))
+	}
+	if m.rawView {
+		return markdown.SourceBlock(markdown.MarkMatches(m.rawContent, m.searchQuery, true, m.matchOptions()))
+	}
+	src := m.convertBlocksLater(markdown.Embeds(m.diffSource(), m.activeWikiPath(), m.loadEmbed))
+	src, m.links = markdown.MarkLinks(src)
+	src, m.footnotes, m.footnoteSpots = markdown.Footnotes(src)
+	src, m.wikiLinks = markdown.WikiLinks(src, m.resolveWikiLink)
+	src, m.images = markdown.ImagePlaceholders(src, m.imageSize)
+	if m.opts.Typography {
+		src = markdown.Typography(src)
+	}
+	return markdown.MarkMatches(src, m.searchQuery, false, m.matchOptions())
+}
+
+// showingCode reports whether the open file is not markdown, e.g. a source
+// file listed with --all-files, and is shown as highlighted code.
+func (m *Model) showingCode() bool {
+	return m.activeAbsPath != "" && !markdown.IsFile(m.activeAbsPath) && !markdown.Converted(m.activeAbsPath)
+}
+
+// showingText reports whether the open file is shown as wrapped plain text:
+// a file configured as text, one no highlighter knows, which a code block
+// would show unwrapped and uncoloured anyway, or a large file opened as text.
+func (m *Model) showingText() bool {
+	if m.largeView == largePlain {
+		return true
+	}
+	if !m.showingCode() || isBinary(m.rawContent) {
+		return false
+	}
+	return markdown.IsText(m.activeAbsPath) || lexers.Get(markdown.Language(m.activeAbsPath)) == nil
+}
+
+// plainMargin indents unstyled text like the document margin of the
+// bundled themes.
+const plainMargin = "  "
+
+// wrapPlain wraps unstyled text to the viewport.
+func (m *Model) wrapPlain(text string) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	width := max(m.wrapWidth-len(plainMargin), 0)
+	switch {
+	case wrapsAnywhere(m.opts.Wrap, text):
+		text = wrapAnywhere(text, width)
+	case width > 0:
+		text = ansi.Wrap(text, width, "")
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = plainMargin + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isBinary reports whether content cannot be shown as text.
+func isBinary(content string) bool {
+	return strings.ContainsRune(content, 0) || !utf8.ValidString(content)
+}
+
+// rerenderKeepingPosition renders the document again and restores the scroll
+// position proportionally, since the line count may change between views.
+func (m *Model) rerenderKeepingPosition() {
+	ratio := m.scrollRatio()
+	m.renderMarkdown()
+	m.setScrollRatio(ratio)
+}
+
+func (m *Model) scrollRatio() float64 {
+	total := m.contentVP.TotalLineCount()
+	if total <= 0 {
+		return 0
+	}
+	return float64(m.contentVP.YOffset) / float64(total)
+}
+
+func (m *Model) setScrollRatio(ratio float64) {
+	total := m.contentVP.TotalLineCount()
+	m.contentVP.SetYOffset(int(ratio*float64(total) + 0.5))
+}
+
+func newRenderer(width int, options Options) (*glamour.TermRenderer, error) {
+	opts := []glamour.TermRendererOption{glamour.WithStandardStyle(styles.TokyoNightStyle)}
+	if len(options.Style) > 0 {
+		opts = []glamour.TermRendererOption{glamour.WithStylesFromJSONBytes(options.Style)}
+	}
+	if width > 0 {
+		opts = append(opts, glamour.WithWordWrap(width))
+	} else {
+		opts = append(opts, glamour.WithWordWrap(0))
+	}
+	if options.AssetsBase != "" {
+		opts = append(opts, glamour.WithBaseURL(assetsBaseURL(options.AssetsBase)))
+	}
+	return glamour.NewTermRenderer(opts...)
+}
+
+// assetsBaseURL converts an absolute directory into the file URL glamour uses
+// to resolve relative link and image destinations. Root-relative paths such as
+// "/images/a.png" are resolved against the same directory, matching how static
+// site generators serve their asset folders.
+func assetsBaseURL(dir string) string {
+	path := filepath.ToSlash(dir)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
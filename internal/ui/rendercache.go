@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"strconv"
+)
+
+// renderCacheSize is how many renders are kept. A few cover switching
+// between files and widths; each entry holds a whole rendered document.
+const renderCacheSize = 8
+
+// renderKey identifies a render by the markdown handed to glamour, the wrap
+// width and the style.
+type renderKey [sha256.Size]byte
+
+// renderCache keeps glamour's output for recent renders, so that going back
+// to a file, a width or a view seen before skips glamour. The least
+// recently used entry goes first.
+type renderCache struct {
+	entries map[renderKey]string
+	order   []renderKey
+}
+
+func (c *renderCache) get(key renderKey) (string, bool) {
+	out, ok := c.entries[key]
+	if ok {
+		c.touch(key)
+	}
+	return out, ok
+}
+
+func (c *renderCache) put(key renderKey, out string) {
+	if c.entries == nil {
+		c.entries = make(map[renderKey]string)
+	}
+	if _, ok := c.entries[key]; !ok && len(c.order) >= renderCacheSize {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[key] = out
+	c.touch(key)
+}
+
+// touch moves key to the most recently used end.
+func (c *renderCache) touch(key renderKey) {
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, key)
+}
+
+// renderKey returns the cache key of rendering src with the current
+// renderer.
+func (m *Model) renderKey(src string) renderKey {
+	h := sha256.New()
+	h.Write(m.opts.Style)
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(m.wrapWidth)))
+	h.Write([]byte{0})
+	h.Write([]byte(src))
+	var key renderKey
+	h.Sum(key[:0])
+	return key
+}
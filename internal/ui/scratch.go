@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// minScratchHeight is the smallest scratch pane, title row included.
+const minScratchHeight = 4
+
+var scratchTitleStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#a9b1d6")).
+	Background(lipgloss.Color("#283457"))
+
+func newScratchInput() textarea.Model {
+	input := textarea.New()
+	input.ShowLineNumbers = false
+	input.Prompt = " "
+	input.Placeholder = "メモを入力 (Esc で本文へ戻ると保存します)"
+	input.CharLimit = 0
+	input.MaxHeight = 0
+	input.Blur()
+	return input
+}
+
+// toggleScratch shows the scratch pane and moves the keys into it, loading
+// the scratch file the first time. With the pane shown but not focused it
+// hides the pane instead.
+func (m *Model) toggleScratch() tea.Cmd {
+	if m.opts.ScratchFile == "" {
+		m.err = errors.New("スクラッチファイルが設定されていません (--scratch-file)")
+		return nil
+	}
+	if m.scratchVisible {
+		m.scratchVisible = false
+		m.resize(m.width, m.height)
+		return nil
+	}
+	if !m.scratchLoaded {
+		data, err := os.ReadFile(m.opts.ScratchFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			m.err = err
+			return nil
+		}
+		m.scratchInput.SetValue(string(data))
+		m.scratchSaved = string(data)
+		m.scratchLoaded = true
+	}
+	m.scratchVisible = true
+	m.resize(m.width, m.height)
+	return m.focusScratch()
+}
+
+func (m *Model) focusScratch() tea.Cmd {
+	m.scratchFocus = true
+	return m.scratchInput.Focus()
+}
+
+// leaveScratch returns the keys to the viewer, keeping the pane on screen,
+// and saves the notes.
+func (m *Model) leaveScratch() {
+	m.scratchFocus = false
+	m.scratchInput.Blur()
+	m.saveScratch()
+}
+
+func (m *Model) handleScratchKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.leaveScratch()
+		return nil
+	case "ctrl+s":
+		m.saveScratch()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.scratchInput, cmd = m.scratchInput.Update(msg)
+	return cmd
+}
+
+// saveScratch writes the notes to the scratch file when they changed.
+func (m *Model) saveScratch() {
+	value := m.scratchInput.Value()
+	if !m.scratchLoaded || value == m.scratchSaved {
+		return
+	}
+	if err := safemode.MkdirAll(filepath.Dir(m.opts.ScratchFile), 0o755); err != nil {
+		m.err = fmt.Errorf("スクラッチを保存できませんでした: %w", err)
+		return
+	}
+	if err := safemode.WriteFile(m.opts.ScratchFile, []byte(value), 0o644); err != nil {
+		m.err = fmt.Errorf("スクラッチを保存できませんでした: %w", err)
+		return
+	}
+	m.scratchSaved = value
+}
+
+// scratchHeight returns the rows taken by the scratch pane out of height.
+func (m *Model) scratchHeight(height int) int {
+	if !m.scratchVisible {
+		return 0
+	}
+	return clamp(height/3, minScratchHeight, max(height-minScratchHeight, minScratchHeight))
+}
+
+func (m *Model) resizeScratch(width, height int) {
+	m.scratchInput.SetWidth(max(width, 1))
+	m.scratchInput.SetHeight(max(height-1, 1))
+}
+
+// scratchView renders the title row and the editor.
+func (m *Model) scratchView() string {
+	title := " スクラッチ: " + m.opts.ScratchFile
+	if m.scratchInput.Value() != m.scratchSaved {
+		title += " [+]"
+	}
+	if m.scratchFocus {
+		title += "  (Esc: 本文へ戻る / Ctrl+s: 保存)"
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		scratchTitleStyle.Width(m.width).Render(ansi.Truncate(title, m.width, "…")),
+		m.scratchInput.View())
+}
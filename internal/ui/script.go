@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var namedKeys = map[string]tea.KeyType{
+	"enter":  tea.KeyEnter,
+	"cr":     tea.KeyEnter,
+	"esc":    tea.KeyEsc,
+	"tab":    tea.KeyTab,
+	"space":  tea.KeySpace,
+	"bs":     tea.KeyBackspace,
+	"up":     tea.KeyUp,
+	"down":   tea.KeyDown,
+	"left":   tea.KeyLeft,
+	"right":  tea.KeyRight,
+	"home":   tea.KeyHome,
+	"end":    tea.KeyEnd,
+	"pgup":   tea.KeyPgUp,
+	"pgdown": tea.KeyPgDown,
+}
+
+// ReloadMsg asks the model to re-read the active file from disk, as the file
+// watcher does when the file changes.
+type ReloadMsg struct{}
+
+// ParseScript converts a key script into messages. Plain characters stand for
+// themselves and special keys are written in angle brackets: <enter>, <esc>,
+// <tab>, <space>, <bs>, arrows such as <down>, <c-d> for ctrl and <a-h> for alt.
+// <reload> re-reads the active file. Whitespace between keys is ignored (use
+// <space> to type a space) and lines starting with '#' are comments, so scripts
+// can be laid out readably:
+//
+//	# search and step through matches
+//	/install<enter> n n
+func ParseScript(script string) ([]tea.Msg, error) {
+	var keys []tea.Msg
+	for lineNo, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for i := 0; i < len(line); {
+			if line[i] == '<' {
+				end := strings.IndexByte(line[i:], '>')
+				if end > 1 {
+					key, err := parseNamedKey(line[i+1 : i+end])
+					if err != nil {
+						return nil, fmt.Errorf("%d 行目: %w", lineNo+1, err)
+					}
+					keys = append(keys, key)
+					i += end + 1
+					continue
+				}
+			}
+			r, size := utf8.DecodeRuneInString(line[i:])
+			i += size
+			if r == ' ' || r == '\t' || r == '\r' {
+				continue
+			}
+			keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	return keys, nil
+}
+
+func parseNamedKey(name string) (tea.Msg, error) {
+	lower := strings.ToLower(name)
+	if lower == "reload" {
+		return ReloadMsg{}, nil
+	}
+	if key, ok := namedKeys[lower]; ok {
+		if key == tea.KeySpace {
+			return tea.KeyMsg{Type: key, Runes: []rune{' '}}, nil
+		}
+		return tea.KeyMsg{Type: key}, nil
+	}
+	switch {
+	case strings.HasPrefix(lower, "c-") && len(lower) == 3 && lower[2] >= 'a' && lower[2] <= 'z':
+		return tea.KeyMsg{Type: tea.KeyCtrlA + tea.KeyType(lower[2]-'a')}, nil
+	case strings.HasPrefix(lower, "a-") && utf8.RuneCountInString(name) == 3:
+		r, _ := utf8.DecodeRuneInString(name[2:])
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}, Alt: true}, nil
+	}
+	return nil, fmt.Errorf("不明なキー <%s>", name)
+}
+
+// Drive feeds messages through Update without a running Bubble Tea program, so
+// tree navigation, search and reloads can be exercised programmatically.
+// Commands returned by Update are not executed. It reports whether the model
+// asked to quit, in which case the remaining messages are skipped.
+func (m *Model) Drive(msgs ...tea.Msg) bool {
+	for _, msg := range msgs {
+		m.Update(msg)
+		if m.quitting {
+			return true
+		}
+	}
+	return false
+}
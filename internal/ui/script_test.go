@@ -0,0 +1,303 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+// newVaultModel writes files, keyed by slash-separated path, to a temporary
+// vault and opens it the way mdview opens a directory, with the tree focused
+// and everything read synchronously.
+func newVaultModel(t *testing.T, files map[string]string) (*Model, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := filepath.Base(dir)
+	m := NewModel(State{
+		HeaderPath:  name + "/",
+		TreeVisible: true,
+		TreeRoot:    tree.NewRoot(name, tree.NewFSLoader(dir)),
+		RootDir:     dir,
+		DisplayRoot: name,
+		FocusTree:   true,
+		Options:     Options{Sync: true, NoWatch: true},
+	})
+	m.Drive(tea.WindowSizeMsg{Width: 80, Height: 20})
+	return m, dir
+}
+
+// drive replays a key script like --script does.
+func drive(t *testing.T, m *Model, script string) {
+	t.Helper()
+	msgs, err := ParseScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Drive(msgs...)
+}
+
+// plainView returns the screen without escape sequences.
+func plainView(m *Model) string {
+	return ansi.Strip(m.View())
+}
+
+var testVault = map[string]string{
+	"alpha.md":      "# Alpha\n\napple one\n\nbanana\n\napple two\n",
+	"notes/beta.md": "# Beta\n\ncherry\n",
+}
+
+func TestDriveTreeNavigation(t *testing.T) {
+	m, dir := newVaultModel(t, testVault)
+
+	if view := plainView(m); strings.Contains(view, "beta.md") {
+		t.Fatalf("notes/ is expanded before it is opened:\n%s", view)
+	}
+	drive(t, m, "j <enter>")
+	if view := plainView(m); !strings.Contains(view, "beta.md") {
+		t.Fatalf("notes/ did not expand:\n%s", view)
+	}
+
+	drive(t, m, "j j <enter>")
+	if want := filepath.Join(dir, "alpha.md"); m.activeAbsPath != want {
+		t.Fatalf("active file = %q, want %q", m.activeAbsPath, want)
+	}
+	if view := plainView(m); !strings.Contains(view, "apple one") {
+		t.Fatalf("alpha.md is not shown:\n%s", view)
+	}
+}
+
+func TestDriveSearch(t *testing.T) {
+	m, _ := newVaultModel(t, testVault)
+	drive(t, m, "j j j <enter> <c-l> /apple<enter>")
+
+	if len(m.searchMatches) != 2 {
+		t.Fatalf("matches = %v, want 2", m.searchMatches)
+	}
+	if m.searchIndex != 0 {
+		t.Fatalf("search starts at match %d, want 0", m.searchIndex)
+	}
+	steps := []struct {
+		key    string
+		index  int
+		notice string
+	}{
+		{"n", 1, ""},
+		{"n", 0, searchWrappedToTop},
+		{"N", 1, searchWrappedToBottom},
+		{"N", 0, ""},
+	}
+	for i, step := range steps {
+		m.notice = ""
+		drive(t, m, step.key)
+		if m.searchIndex != step.index || m.notice != step.notice {
+			t.Errorf("step %d (%s): match %d, notice %q; want %d, %q", i, step.key, m.searchIndex, m.notice, step.index, step.notice)
+		}
+	}
+	if view := plainView(m); !strings.Contains(view, "/apple (1/2)") {
+		t.Errorf("status bar does not count the matches:\n%s", view)
+	}
+}
+
+func TestDriveReload(t *testing.T) {
+	m, dir := newVaultModel(t, testVault)
+	drive(t, m, "j j j <enter>")
+
+	if err := os.WriteFile(filepath.Join(dir, "alpha.md"), []byte("# Alpha\n\ndurian\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if view := plainView(m); strings.Contains(view, "durian") {
+		t.Fatalf("the change is shown before the reload:\n%s", view)
+	}
+	drive(t, m, "<reload>")
+	view := plainView(m)
+	if !strings.Contains(view, "durian") || strings.Contains(view, "apple one") {
+		t.Fatalf("the reload did not show the new contents:\n%s", view)
+	}
+}
+
+// slowMarkdown returns a document of about lines lines that takes glamour
+// well over renderAsyncAfter: long lines of highlighted code.
+func slowMarkdown(lines int) string {
+	code := strings.Repeat(`fmt.Println("x", 1, 2, 3); `, 40)
+	return "```go\n" + strings.Repeat(code+"\n", lines) + "```\n"
+}
+
+// finishRendering waits for the render running in the background and
+// delivers its output as the program would.
+func finishRendering(t *testing.T, m *Model) {
+	t.Helper()
+	job := m.rendering
+	if job == nil {
+		t.Skip("the document rendered before the render went to the background")
+	}
+	m.Drive(renderedMsg{gen: job.gen, renderResult: <-job.done})
+}
+
+func TestDriveLineNumbersWhileRendering(t *testing.T) {
+	var long strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&long, "line %d\n\n", i+1)
+	}
+	short := slowMarkdown(100)
+	m, _ := newVaultModel(t, map[string]string{"long.md": long.String(), "short.md": short})
+	m.opts.LineNumbers = true
+
+	drive(t, m, "j <enter>")
+	if view := plainView(m); !strings.Contains(view, "line 1") {
+		t.Fatalf("long.md is not shown:\n%s", view)
+	}
+	m.opts.Sync = false
+	drive(t, m, "j <enter>")
+	if m.rendering == nil {
+		t.Skip("short.md rendered before the render went to the background")
+	}
+	plainView(m)
+	parts := m.statusRightParts()
+	if len(parts) < 3 || parts[0] != m.renderingStatus() || parts[1] != "監視なし" || !strings.HasPrefix(parts[2], "L") {
+		t.Fatalf("status = %q, want the spinner, then the watch state, then the source line", parts)
+	}
+}
+
+func TestDriveWidthPresetsAndCounts(t *testing.T) {
+	m, _ := newVaultModel(t, testVault)
+	drive(t, m, "j j j <enter> <c-l>")
+
+	drive(t, m, "2")
+	if m.widthPreset != 0 {
+		t.Fatalf("2 set the width to %d before the next key", m.widthPreset)
+	}
+	drive(t, m, "j")
+	if m.widthPreset != 100 {
+		t.Fatalf("2 j set the width to %d, want 100", m.widthPreset)
+	}
+	drive(t, m, "1 G")
+	if m.widthPreset != 100 {
+		t.Fatalf("1G changed the width to %d, want 100", m.widthPreset)
+	}
+	drive(t, m, "3 5 %")
+	if m.widthPreset != 100 {
+		t.Fatalf("35%% changed the width to %d, want 100", m.widthPreset)
+	}
+	drive(t, m, "<a-1>")
+	if m.widthPreset != 80 {
+		t.Fatalf("Alt+1 set the width to %d, want 80", m.widthPreset)
+	}
+}
+
+func TestDriveSearchHistoryIsPrivate(t *testing.T) {
+	m, dir := newVaultModel(t, testVault)
+	path := filepath.Join(dir, "state", "search_history")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("older\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.opts.SearchHistory = path
+	drive(t, m, "j j j <enter> <c-l> /apple<enter>")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Fatalf("search history mode = %v, want 0600", mode)
+	}
+}
+
+func TestDriveSearchWhileRendering(t *testing.T) {
+	doc := "# Top\n\n" + slowMarkdown(100) + "\n## Zebra\n\nstripes\n"
+	m, _ := newVaultModel(t, map[string]string{"slow.md": doc})
+	drive(t, m, "j <enter> <c-l>")
+	m.opts.Sync = false
+
+	drive(t, m, "/Zebra<enter>")
+	finishRendering(t, m)
+	if len(m.searchMatches) != 1 {
+		t.Fatalf("matches = %v, want 1", m.searchMatches)
+	}
+	if m.contentVP.YOffset == 0 {
+		t.Fatalf("the view stayed at the top instead of moving to the match on line %d", m.searchMatches[0])
+	}
+	if view := plainView(m); !strings.Contains(view, "stripes") {
+		t.Fatalf("the match is not in view:\n%s", view)
+	}
+}
+
+func TestDriveWikiLinkWhileRendering(t *testing.T) {
+	m, _ := newVaultModel(t, map[string]string{
+		"a.md": "# A\n\nsee [[b#Target]]\n",
+		"b.md": "# B\n\n" + slowMarkdown(100) + "\n## Target\n\nfound it\n",
+	})
+	drive(t, m, "j <enter> <c-l>")
+	m.opts.Sync = false
+
+	drive(t, m, "<enter>")
+	finishRendering(t, m)
+	if view := plainView(m); !strings.Contains(view, "found it") {
+		t.Fatalf("the linked heading is not in view:\n%s", view)
+	}
+}
+
+func TestDriveWikiLinkToLargeFile(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("# B\n\n")
+	for i := range 200 {
+		fmt.Fprintf(&b, "filler %d\n\n", i)
+	}
+	b.WriteString("## Target\n\nfound it\n")
+	m, dir := newVaultModel(t, map[string]string{
+		"a.md": "# A\n\nsee [[b#Target]]\n",
+		"b.md": b.String(),
+	})
+	m.opts.LargeFileSize = 1 << 10
+	drive(t, m, "j <enter> <c-l> <enter>")
+	if m.largePrompt == nil {
+		t.Fatal("b.md opened without asking")
+	}
+	if m.err != nil {
+		t.Fatalf("the link acted on a.md while asking: %v", m.err)
+	}
+
+	drive(t, m, "o")
+	if want := filepath.Join(dir, "b.md"); m.activeAbsPath != want {
+		t.Fatalf("active file = %q, want %q", m.activeAbsPath, want)
+	}
+	if view := plainView(m); !strings.Contains(view, "found it") {
+		t.Fatalf("the linked heading is not in view:\n%s", view)
+	}
+}
+
+func TestDriveTypeAheadOfRender(t *testing.T) {
+	doc := "# Top\n\n" + slowMarkdown(100) + "\n## Zebra\n\nstripes\n"
+	m, _ := newVaultModel(t, map[string]string{"slow.md": doc})
+	drive(t, m, "j <enter> <c-l>")
+	m.opts.Sync = false
+
+	drive(t, m, "/Z")
+	job := m.rendering
+	drive(t, m, "eb")
+	if job != nil && m.rendering != job {
+		t.Fatal("typing started a render while one was running")
+	}
+	finishRendering(t, m)
+	if m.searchQuery != "Zeb" {
+		t.Fatalf("search query = %q after the render, want %q", m.searchQuery, "Zeb")
+	}
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	scrollTrackStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#292e42"))
+	scrollThumbStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#7aa2f7"))
+)
+
+// scrollbarShown reports whether the content pane reserves a scrollbar column.
+func (m *Model) scrollbarShown() bool {
+	return !m.zenMode
+}
+
+// scrollbarView renders a one-column gutter whose thumb reflects the visible
+// portion of the document and its position.
+func (m *Model) scrollbarView() string {
+	height := m.contentVP.Height
+	if height <= 0 {
+		return ""
+	}
+	total := m.contentVP.TotalLineCount()
+	rows := make([]string, height)
+	if total <= height {
+		for i := range rows {
+			rows[i] = scrollTrackStyle.Render(" ")
+		}
+		return strings.Join(rows, "\n")
+	}
+
+	thumb := max(1, height*height/total)
+	maxOffset := total - height
+	top := 0
+	if maxOffset > 0 {
+		top = (m.contentVP.YOffset*(height-thumb) + maxOffset/2) / maxOffset
+	}
+	for i := range rows {
+		if i >= top && i < top+thumb {
+			rows[i] = scrollThumbStyle.Render("┃")
+		} else {
+			rows[i] = scrollTrackStyle.Render("│")
+		}
+	}
+	return strings.Join(rows, "\n")
+}
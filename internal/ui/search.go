@@ -0,0 +1,447 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+const (
+	matchHighlightOn  = "\x1b[7m"
+	matchHighlightOff = "\x1b[27m"
+	// The current match is reversed in yellow to stand out from the rest.
+	currentMatchOn  = "\x1b[7;33m"
+	currentMatchOff = "\x1b[27;39m"
+)
+
+// searchState is the search a file had when another file was opened.
+type searchState struct {
+	query string
+	index int
+}
+
+// The notices shown when n or N wraps around the document, as Vim's "search
+// hit BOTTOM, continuing at TOP".
+const (
+	searchWrappedToTop    = "末尾まで検索したため先頭から続けます"
+	searchWrappedToBottom = "先頭まで検索したため末尾から続けます"
+)
+
+// searchOrigin is the search and scroll position the document had when the
+// search input was opened, restored when it is cancelled.
+type searchOrigin struct {
+	query  string
+	index  int
+	hidden bool
+	offset int
+}
+
+// saveSearchState remembers the search of the active file so that it can be
+// resumed when the file is opened again.
+func (m *Model) saveSearchState() {
+	if m.activeAbsPath == "" {
+		return
+	}
+	if m.searchQuery == "" {
+		delete(m.searchStates, m.activeAbsPath)
+		return
+	}
+	if m.searchStates == nil {
+		m.searchStates = make(map[string]searchState)
+	}
+	m.searchStates[m.activeAbsPath] = searchState{query: m.searchQuery, index: m.searchIndex}
+}
+
+// restoreSearchState brings back the search the active file had, or clears
+// the search for a file that had none. The matches are recomputed by the next
+// render, which keeps the saved index when it is still in range.
+func (m *Model) restoreSearchState() {
+	state, ok := m.searchStates[m.activeAbsPath]
+	if !ok {
+		state = searchState{index: -1}
+	}
+	m.searchQuery = state.query
+	m.searchMatches = nil
+	m.searchIndex = state.index
+}
+
+func (m *Model) enterSearchMode() tea.Cmd {
+	m.searchActive = true
+	m.pendingKey = ""
+	m.searchOrigin = searchOrigin{
+		query:  m.searchQuery,
+		index:  m.searchIndex,
+		hidden: m.hideMatches,
+		offset: m.contentVP.YOffset,
+	}
+	m.loadSearchHistory()
+	m.searchRecall = len(m.searchHistory)
+	if m.searchQuery != "" {
+		m.searchInput.SetValue(m.searchQuery)
+		m.searchInput.CursorEnd()
+	} else {
+		m.searchInput.SetValue("")
+	}
+	return m.searchInput.Focus()
+}
+
+func (m *Model) exitSearchMode() {
+	m.searchActive = false
+	m.searchInput.Blur()
+}
+
+// searchIncrementally searches for query as it is being typed, scrolling to
+// its first match below where the search started. An empty query shows
+// the document as it was before the search input was opened. While a render
+// runs in the background, which cannot be cancelled, the search waits for it
+// and handleRendered searches for what was typed meanwhile, so typing on a
+// slow document does not pile up renders.
+func (m *Model) searchIncrementally(query string) {
+	query = strings.TrimSpace(query)
+	if query == m.searchQuery || m.rendering != nil {
+		return
+	}
+	m.contentVP.SetYOffset(m.searchOrigin.offset)
+	if query == "" {
+		m.searchQuery = ""
+		m.searchMatches = nil
+		m.searchIndex = -1
+		m.err = nil
+		m.renderMarkdown()
+		m.whenRendered(func() { m.contentVP.SetYOffset(m.searchOrigin.offset) })
+		return
+	}
+	m.performSearch(query, true)
+}
+
+// cancelSearch closes the search input, bringing back the search and the
+// scroll position from before it was opened.
+func (m *Model) cancelSearch() {
+	m.exitSearchMode()
+	origin := m.searchOrigin
+	if m.searchQuery != origin.query {
+		m.searchQuery = origin.query
+		m.searchMatches = nil
+		m.searchFrom = -1
+		m.searchIndex = origin.index
+		m.err = nil
+		m.renderMarkdown()
+	}
+	m.searchIndex = origin.index
+	m.hideMatches = origin.hidden
+	m.restyleMatches()
+	m.whenRendered(func() { m.contentVP.SetYOffset(origin.offset) })
+}
+
+func (m *Model) clearSearch() {
+	m.hideMatches = false
+	m.searchQuery = ""
+	m.searchMatches = nil
+	m.searchIndex = -1
+	m.err = nil
+	m.renderMarkdown()
+}
+
+// matchOptions returns how the search matches: only whole words with :set
+// wholeword, and with case as caseSensitive says.
+func (m *Model) matchOptions() markdown.MatchOptions {
+	return markdown.MatchOptions{
+		CaseSensitive: m.caseSensitive(m.searchQuery),
+		WholeWord:     m.wholeWord,
+	}
+}
+
+// caseSensitive reports whether searching for query tells upper from lower
+// case: always with :set noignorecase, and with :set smartcase when query
+// has an upper case letter.
+func (m *Model) caseSensitive(query string) bool {
+	return !m.ignoreCase || m.smartCase && strings.IndexFunc(query, unicode.IsUpper) >= 0
+}
+
+func (m *Model) searchStatusLine() string {
+	if m.searchQuery == "" {
+		return ""
+	}
+	total := len(m.searchMatches)
+	if total == 0 || m.searchIndex < 0 {
+		return fmt.Sprintf("/%s (0/0)", m.searchQuery)
+	}
+	current := m.searchIndex + 1
+	return fmt.Sprintf("/%s (%d/%d)", m.searchQuery, current, total)
+}
+
+// performSearch re-renders the document with query marked in the source, so
+// matches are found and highlighted even when they span wrapped lines or
+// styled text.
+func (m *Model) performSearch(query string, resetIndex bool) {
+	query = strings.TrimSpace(query)
+	m.searchQuery = query
+	m.hideMatches = false
+	if resetIndex {
+		m.searchMatches = nil
+		m.searchIndex = -1
+		if m.searchFrom < 0 {
+			m.searchFrom = m.contentVP.YOffset
+		}
+	}
+	m.renderMarkdown()
+	m.whenRendered(m.gotoFoundMatch)
+}
+
+// gotoFoundMatch scrolls to the current match of a search just performed.
+func (m *Model) gotoFoundMatch() {
+	if len(m.searchMatches) == 0 {
+		return
+	}
+	if m.searchIndex < 0 || m.searchIndex >= len(m.searchMatches) {
+		m.searchIndex = 0
+	}
+	m.err = nil
+	m.gotoSearchMatch()
+}
+
+func (m *Model) nextSearchMatch() {
+	if len(m.searchMatches) == 0 {
+		return
+	}
+	if m.searchIndex < 0 {
+		m.searchIndex = 0
+	} else {
+		m.searchIndex = (m.searchIndex + 1) % len(m.searchMatches)
+		if m.searchIndex == 0 {
+			m.notice = searchWrappedToTop
+		}
+	}
+	m.hideMatches = false
+	m.err = nil
+	m.gotoSearchMatch()
+}
+
+func (m *Model) previousSearchMatch() {
+	if len(m.searchMatches) == 0 {
+		return
+	}
+	if m.searchIndex <= 0 {
+		if m.searchIndex == 0 {
+			m.notice = searchWrappedToBottom
+		}
+		m.searchIndex = len(m.searchMatches) - 1
+	} else {
+		m.searchIndex--
+	}
+	m.hideMatches = false
+	m.err = nil
+	m.gotoSearchMatch()
+}
+
+func (m *Model) gotoSearchMatch() {
+	if len(m.searchMatches) == 0 || m.searchIndex < 0 {
+		return
+	}
+	totalLines := strings.Count(m.renderedContent, "\n") + 1
+	if totalLines <= 0 {
+		return
+	}
+	m.restyleMatches()
+	targetLine := m.searchMatches[m.searchIndex]
+	maxOffset := max(totalLines-m.contentVP.Height, 0)
+	offset := clamp(targetLine, 0, maxOffset)
+	m.contentVP.SetYOffset(offset)
+}
+
+// hideSearchHighlight removes the highlighting of the matches, as :nohlsearch
+// does in Vim. The search is kept, and n or N shows the matches again.
+func (m *Model) hideSearchHighlight() bool {
+	if m.searchQuery == "" || m.hideMatches {
+		return false
+	}
+	m.hideMatches = true
+	m.restyleMatches()
+	return true
+}
+
+// styleMatches highlights the matches marked in the rendered output, the
+// current one apart from the rest, and shows the result.
+func (m *Model) styleMatches() []int {
+	rendered, matches := highlightMatches(m.markedContent, m.searchIndex, m.hideMatches)
+	m.styledMatch, m.styledHidden = m.searchIndex, m.hideMatches
+	m.renderedContent = rendered
+	m.contentVP.SetContent(rendered)
+	return matches
+}
+
+// restyleMatches styles the matches again when the current match or their
+// visibility changed, which is cheap next to rendering the document again.
+func (m *Model) restyleMatches() {
+	if m.styledMatch == m.searchIndex && m.styledHidden == m.hideMatches {
+		return
+	}
+	offset := m.contentVP.YOffset
+	m.styleMatches()
+	if m.showAnchors {
+		m.renderedContent = m.withHeadingAnchors(m.renderedContent)
+		m.contentVP.SetContent(m.renderedContent)
+	}
+	m.contentVP.SetYOffset(offset)
+}
+
+// onContentChanged receives the rendered lines of every match after a render
+// and keeps the current match as close as possible to where it was.
+func (m *Model) onContentChanged(matches []int) {
+	if m.searchQuery == "" {
+		m.searchMatches = nil
+		return
+	}
+
+	prevLine := -1
+	if len(m.searchMatches) > 0 && m.searchIndex >= 0 && m.searchIndex < len(m.searchMatches) {
+		prevLine = m.searchMatches[m.searchIndex]
+	}
+
+	m.searchMatches = matches
+	if len(m.searchMatches) == 0 {
+		m.searchIndex = -1
+		m.err = fmt.Errorf("%q に一致しません。", m.searchQuery)
+		return
+	}
+
+	from := m.searchFrom
+	m.searchFrom = -1
+	if prevLine >= 0 {
+		m.searchIndex = closestMatchIndex(m.searchMatches, prevLine)
+	} else if from >= 0 {
+		m.searchIndex = firstMatchFrom(m.searchMatches, from)
+	} else if m.searchIndex < 0 || m.searchIndex >= len(m.searchMatches) {
+		m.searchIndex = 0
+	}
+	m.err = nil
+	m.gotoSearchMatch()
+}
+
+// highlightMatches replaces the match markers left in the rendered output by
+// markdown.MarkMatches with reverse video, yellow for the match numbered
+// current, and reports the line each match starts on. Hidden matches are
+// left unstyled.
+func highlightMatches(rendered string, current int, hidden bool) (string, []int) {
+	return highlightSpans(rendered, markdown.MatchStart, markdown.MatchEnd, func(n int) (string, string) {
+		switch {
+		case hidden:
+			return "", ""
+		case n == current:
+			return currentMatchOn, currentMatchOff
+		}
+		return matchHighlightOn, matchHighlightOff
+	})
+}
+
+// spanStyle styles every span alike.
+func spanStyle(on, off string) func(int) (string, string) {
+	return func(int) (string, string) { return on, off }
+}
+
+// highlightSpans replaces the start and end markers of spans left in the
+// rendered output with the styles style returns for the span's number and
+// reports the line each span starts on. Styles emitted by the renderer
+// inside a span reset attributes, so the style is re-applied after them and
+// across line breaks.
+func highlightSpans(rendered, start, end string, style func(n int) (on, off string)) (string, []int) {
+	if !strings.Contains(rendered, start) {
+		return rendered, nil
+	}
+	var out strings.Builder
+	out.Grow(len(rendered) + 64)
+	var lines []int
+	line := 0
+	inSpan := false
+	reopen := false
+	var on, off string
+	for i := 0; i < len(rendered); {
+		switch {
+		case strings.HasPrefix(rendered[i:], start):
+			on, off = style(len(lines))
+			lines = append(lines, line)
+			out.WriteString(on)
+			inSpan, reopen = true, false
+			i += len(start)
+		case strings.HasPrefix(rendered[i:], end):
+			if inSpan && !reopen {
+				out.WriteString(off)
+			}
+			inSpan, reopen = false, false
+			i += len(end)
+		case rendered[i] == '\x1b':
+			csi := csiEnd(rendered, i)
+			out.WriteString(rendered[i:csi])
+			if inSpan && !reopen && rendered[csi-1] == 'm' {
+				out.WriteString(on)
+			}
+			i = csi
+		case rendered[i] == '\n':
+			if inSpan && !reopen {
+				out.WriteString(off)
+				reopen = true
+			}
+			out.WriteByte('\n')
+			line++
+			i++
+		default:
+			if reopen && rendered[i] != ' ' {
+				out.WriteString(on)
+				reopen = false
+			}
+			out.WriteByte(rendered[i])
+			i++
+		}
+	}
+	return out.String(), lines
+}
+
+// csiEnd returns the index just past the escape sequence starting at i.
+func csiEnd(s string, i int) int {
+	j := i + 1
+	if j < len(s) && s[j] == '[' {
+		j++
+		for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+			j++
+		}
+	}
+	return min(j+1, len(s))
+}
+
+// firstMatchFrom returns the index of the first match on or below line,
+// wrapping around to the first match.
+func firstMatchFrom(matches []int, line int) int {
+	for i, match := range matches {
+		if match >= line {
+			return i
+		}
+	}
+	return 0
+}
+
+func closestMatchIndex(matches []int, line int) int {
+	if len(matches) == 0 {
+		return 0
+	}
+	bestIndex := 0
+	bestDiff := absInt(matches[0] - line)
+	for i := 1; i < len(matches); i++ {
+		diff := absInt(matches[i] - line)
+		if diff < bestDiff {
+			bestDiff = diff
+			bestIndex = i
+		}
+	}
+	return bestIndex
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kyaoi/mdview/internal/safemode"
+)
+
+// searchHistoryLimit is how many past queries are remembered.
+const searchHistoryLimit = 100
+
+// loadSearchHistory reads the queries of earlier sessions the first time the
+// search input opens. The history is a convenience, so a missing or
+// unreadable file just starts an empty one.
+func (m *Model) loadSearchHistory() {
+	if m.searchHistoryLoaded {
+		return
+	}
+	m.searchHistoryLoaded = true
+	if m.opts.SearchHistory == "" {
+		return
+	}
+	data, err := os.ReadFile(m.opts.SearchHistory)
+	if err != nil {
+		return
+	}
+	var past []string
+	for _, query := range strings.Split(string(data), "\n") {
+		if query != "" {
+			past = append(past, query)
+		}
+	}
+	// Queries typed before the file was read are newer.
+	m.searchHistory = append(past, m.searchHistory...)
+}
+
+// rememberSearch moves query to the end of the history, the newest entry,
+// and saves the history when it is kept across sessions. Saving failures,
+// including read-only mode, are ignored like for the recent tags.
+func (m *Model) rememberSearch(query string) {
+	m.searchHistory = slices.DeleteFunc(m.searchHistory, func(past string) bool { return past == query })
+	m.searchHistory = append(m.searchHistory, query)
+	if over := len(m.searchHistory) - searchHistoryLimit; over > 0 {
+		m.searchHistory = m.searchHistory[over:]
+	}
+	path := m.opts.SearchHistory
+	if path == "" {
+		return
+	}
+	if err := safemode.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	// Queries can be private, so the file is readable only by the user, as
+	// Vim keeps viminfo. Chmod also narrows a file saved before with 0o644.
+	if err := safemode.WriteFile(path, []byte(strings.Join(m.searchHistory, "\n")+"\n"), 0o600); err == nil {
+		_ = os.Chmod(path, 0o600)
+	}
+}
+
+// recallSearch replaces the search input with the previous (step -1) or next
+// (step 1) query in the history starting with what was typed before
+// recalling, as Vim does. Stepping past the newest brings the typed text back.
+func (m *Model) recallSearch(step int) {
+	if m.searchRecall == len(m.searchHistory) {
+		m.searchDraft = m.searchInput.Value()
+	}
+	i := m.searchRecall
+	for {
+		i += step
+		if i < 0 {
+			return
+		}
+		if i >= len(m.searchHistory) {
+			i = len(m.searchHistory)
+			break
+		}
+		if strings.HasPrefix(m.searchHistory[i], m.searchDraft) {
+			break
+		}
+	}
+	m.searchRecall = i
+	value := m.searchDraft
+	if i < len(m.searchHistory) {
+		value = m.searchHistory[i]
+	}
+	m.searchInput.SetValue(value)
+	m.searchInput.CursorEnd()
+	m.searchIncrementally(value)
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+var splitBorderColor = lipgloss.Color("#3b4261")
+
+func newSourceViewport() viewport.Model {
+	vp := viewport.New(0, 0)
+	vp.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderRight(true).
+		BorderForeground(splitBorderColor)
+	vp.MouseWheelEnabled = false
+	return vp
+}
+
+// toggleSplitView switches between the single rendered pane and the
+// side-by-side layout with the markdown source on the left.
+func (m *Model) toggleSplitView() {
+	m.splitView = !m.splitView
+	if m.splitView {
+		m.rawView = false
+	}
+	ratio := m.scrollRatio()
+	m.resize(m.width, m.height)
+	m.setScrollRatio(ratio)
+	m.syncSourcePane()
+}
+
+// splitWidths divides the available content width between the source and the
+// rendered panes. The source pane gets zero width when the split is inactive.
+func (m *Model) splitWidths(total int) (source, rendered int) {
+	if !m.splitView || m.zenMode || total < 2*minContentWidth {
+		return 0, total
+	}
+	source = total / 2
+	return source, total - source
+}
+
+// renderSourcePane fills the source viewport with the highlighted markdown.
+func (m *Model) renderSourcePane() {
+	if !m.splitView || m.sourceRenderer == nil {
+		m.sourceVP.SetContent("")
+		return
+	}
+	rendered, err := m.sourceRenderer.Render(markdown.SourceBlock(m.rawContent))
+	if err != nil {
+		m.sourceVP.SetContent(m.rawContent)
+		return
+	}
+	m.sourceVP.SetContent(rendered)
+	m.syncSourcePane()
+}
+
+// syncSourcePane keeps the source pane aligned with the rendered pane by
+// scrolling it to the same relative position.
+func (m *Model) syncSourcePane() {
+	if !m.splitView {
+		return
+	}
+	total := m.sourceVP.TotalLineCount()
+	m.sourceVP.SetYOffset(int(m.scrollRatio()*float64(total) + 0.5))
+}
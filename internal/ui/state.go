@@ -1,6 +1,12 @@
 package ui
 
-import "github.com/kyaoi/mdview/internal/tree"
+import (
+	"time"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/remote"
+	"github.com/kyaoi/mdview/internal/tree"
+)
 
 // State contains the data required to bootstrap the Bubble Tea model.
 type State struct {
@@ -14,4 +20,99 @@ type State struct {
 	DisplayRoot        string
 	ActiveAbsPath      string
 	FocusTree          bool
+	// Anchor names the heading of the file to scroll to once it is shown,
+	// from a "file.md#section" argument.
+	Anchor string
+	// Line is the 1-based source line of the file to scroll to once it is
+	// shown, from a "file.md:120" argument.
+	Line int
+	// Remote is the document downloaded for a URL argument. It is polled
+	// for changes rather than watched.
+	Remote *remote.Document
+	// Rev is the git revision RawContent was read at, if not the working
+	// tree. Such a file is not watched. RevLabel describes it in the
+	// status bar.
+	Rev      string
+	RevLabel string
+	Options  Options
+}
+
+// Options holds viewer settings chosen on the command line.
+type Options struct {
+	// Typography converts straight quotes, dashes and ellipses into their
+	// typographic forms before rendering.
+	Typography bool
+	// AssetsBase is an absolute directory used to resolve relative link and
+	// image destinations instead of leaving them as written.
+	AssetsBase string
+	// ZenWidth is the maximum text width used by zen mode. Zero selects the
+	// default of 80 columns.
+	ZenWidth int
+	// SourceLines makes line numbers in :N and NG refer to the raw markdown
+	// instead of the rendered output.
+	SourceLines bool
+	// LineNumbers prefixes the content pane with the source line each
+	// rendered line came from.
+	LineNumbers bool
+	// FootnotePreview shows the text of the first visible footnote
+	// reference in the status bar.
+	FootnotePreview bool
+	// Line is the 1-based source line to open the first file at, from the
+	// --line flag. A line given in the path argument takes precedence.
+	Line int
+	// Rev is the git revision the first file is shown at, from --rev.
+	Rev string
+	// RenderTimeout bounds a single render before the raw source is shown
+	// instead. Zero selects the default of three seconds.
+	RenderTimeout time.Duration
+	// Sync finishes renders and directory loads before Update returns
+	// rather than in the background, for headless runs that print the
+	// screen without running commands.
+	Sync bool
+	// LargeFileSize is the size in bytes above which opening a file asks
+	// first whether to show it in full, as text or only its start. Zero
+	// opens every file in full.
+	LargeFileSize int64
+	// ReloadDebounce is how long a burst of watcher events, as editors fire
+	// on save, must settle before the file is reloaded once. Zero reloads
+	// on every event.
+	ReloadDebounce time.Duration
+	// ConfirmReload starts with automatic reloading off, so changes on
+	// disk are only announced until r reloads them.
+	ConfirmReload bool
+	// Slug selects the platform whose heading anchor rules are used when
+	// resolving "#fragment" links.
+	Slug markdown.SlugStyle
+	// TreeWidth is the initial tree panel content width. Zero lets the
+	// panel follow its longest entry.
+	TreeWidth int
+	// TreeRight places the tree to the right of the content instead of the
+	// left.
+	TreeRight bool
+	// Icons selects type colours and glyphs for tree entries.
+	Icons IconStyle
+	// TreeDetails right-aligns each tree entry's size and relative
+	// modification time.
+	TreeDetails bool
+	// TreeSort orders the entries of each tree directory.
+	TreeSort tree.SortMode
+	// Style is the glamour style as JSON. Nil selects the built-in
+	// tokyo-night style.
+	Style []byte
+	// Wrap selects the line breaking rules; auto follows the script the
+	// document is written in.
+	Wrap WrapMode
+	// ScratchFile is where the notes typed into the scratch pane are saved.
+	ScratchFile string
+	// NoRipgrep makes the grep pane read the files itself even when rg is
+	// on PATH.
+	NoRipgrep bool
+	// SearchHistory is where past search queries are kept across sessions,
+	// one per line. Empty keeps them for the session only.
+	SearchHistory string
+	// NoMouse leaves mouse events to the terminal so text can be selected
+	// with the usual drag.
+	NoMouse bool
+	// NoWatch disables the file watcher, e.g. for headless script replays.
+	NoWatch bool
 }
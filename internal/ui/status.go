@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// statusBarHeight is the number of rows reserved for the bottom status line.
+const statusBarHeight = 1
+
+var (
+	statusBarStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#a9b1d6")).
+			Background(lipgloss.Color("#1f2335"))
+	statusModeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#1a1b26")).
+			Background(lipgloss.Color("#7aa2f7")).
+			Bold(true).
+			Padding(0, 1)
+	statusErrorStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#ff6b6b")).
+				Background(lipgloss.Color("#1f2335"))
+)
+
+// statusBarView renders the bottom line: mode, active file and messages on the
+// left, watch state and position on the right. While searching it turns into
+// the search input. Zen mode keeps the row but only shows input and errors.
+func (m *Model) statusBarView() string {
+	width := m.width
+	if width <= 0 {
+		return ""
+	}
+	if m.searchActive {
+		return statusBarStyle.Width(width).Render(" " + m.searchInput.View())
+	}
+	if m.commandActive {
+		return statusBarStyle.Width(width).Render(" " + m.commandInput.View())
+	}
+	if m.filterActive {
+		return statusBarStyle.Width(width).Render(" " + m.filterInput.View())
+	}
+	if m.zenMode {
+		if m.err != nil {
+			return statusErrorStyle.Width(width).Render(" " + m.err.Error())
+		}
+		return statusBarStyle.Width(width).Render("")
+	}
+
+	mode := statusModeStyle.Render(m.modeLabel())
+	right := statusBarStyle.Render(" " + strings.Join(m.statusRightParts(), "  ") + " ")
+
+	var message string
+	if m.err != nil {
+		message = statusErrorStyle.Render(m.err.Error())
+	} else {
+		message = statusBarStyle.Render(strings.Join(m.statusMessages(), "  "))
+	}
+	header := m.headerPath
+	if m.rev != "" {
+		header += " @ " + m.revLabel
+	}
+	left := statusBarStyle.Render(" "+header+"  ") + message
+
+	room := width - lipgloss.Width(mode) - lipgloss.Width(right)
+	if room < 0 {
+		return ansi.Truncate(mode+right, width, "")
+	}
+	left = ansi.Truncate(left, room, "…")
+	gap := statusBarStyle.Render(strings.Repeat(" ", room-lipgloss.Width(left)))
+	return mode + left + gap + right
+}
+
+// modeLabel names the pane or input that currently receives keys.
+func (m *Model) modeLabel() string {
+	var label string
+	switch {
+	case m.searchActive:
+		label = "SEARCH"
+	case m.commandActive:
+		label = "COMMAND"
+	case m.scratchFocus:
+		label = "SCRATCH"
+	case m.hints != nil:
+		label = "HINT"
+	case m.treeFocus && m.treeShown():
+		label = "TREE"
+	default:
+		label = "CONTENT"
+	}
+	switch {
+	case m.splitView:
+		label += "·SPLIT"
+	case m.rawView:
+		label += "·RAW"
+	}
+	return label
+}
+
+// statusMessages collects the informational messages shown after the path.
+func (m *Model) statusMessages() []string {
+	var parts []string
+	if prompt := m.largePrompt; prompt != nil {
+		return []string{m.largeFileStatus()}
+	}
+	if m.notice != "" {
+		parts = append(parts, m.notice)
+	}
+	if large := m.largeFileStatus(); large != "" {
+		parts = append(parts, large)
+	}
+	// While picking a link its destination is all that matters.
+	if hint := m.hintStatus(); hint != "" {
+		return append(parts, hint)
+	}
+	if preview := m.linkPreviewStatus(); preview != "" {
+		return append(parts, preview)
+	}
+	if changed := m.changedFilesStatus(); changed != "" {
+		parts = append(parts, changed)
+	}
+	if diff := m.diffStatus(); diff != "" {
+		parts = append(parts, diff)
+	}
+	if status := m.searchStatusLine(); status != "" {
+		parts = append(parts, status)
+	}
+	if trail := m.headingTrailStatus(); trail != "" {
+		parts = append(parts, trail)
+	}
+	if filter := m.treeFilterStatus(); filter != "" {
+		parts = append(parts, filter)
+	}
+	if order := m.treeSortStatus(); order != "" {
+		parts = append(parts, order)
+	}
+	if listing := m.treeListingStatus(); listing != "" {
+		parts = append(parts, listing)
+	}
+	if hint := m.imageHintStatus(); hint != "" {
+		parts = append(parts, hint)
+	}
+	if hint := m.wikiLinkHintStatus(); hint != "" {
+		parts = append(parts, hint)
+	}
+	if hint := m.footnoteHintStatus(); hint != "" {
+		parts = append(parts, hint)
+	}
+	return parts
+}
+
+// statusRightParts returns the watch state and scroll position.
+func (m *Model) statusRightParts() []string {
+	watch := "監視なし"
+	switch {
+	case m.reloadPending:
+		watch = "変更あり (r で再読み込み)"
+	case (m.watchedFile != "" || m.remoteDoc != nil && !m.opts.NoWatch) && !m.autoReload:
+		watch = "自動リロード停止"
+	case m.watchedFile != "":
+		watch = "監視中"
+	case m.remoteDoc != nil && !m.opts.NoWatch:
+		watch = "監視中 (取得)"
+	}
+	total := m.contentVP.TotalLineCount()
+	line := 0
+	if total > 0 {
+		line = min(m.contentVP.YOffset+1, total)
+	}
+	percent := int(m.contentVP.ScrollPercent()*100 + 0.5)
+	parts := []string{watch, fmt.Sprintf("%d/%d", line, total), fmt.Sprintf("%3d%%", percent)}
+	if m.lineNumbersShown() {
+		if src := m.sourceLineAtTop(); src > 0 {
+			parts = append(parts[:1], append([]string{fmt.Sprintf("L%d", src)}, parts[1:]...)...)
+		}
+	}
+	if rendering := m.renderingStatus(); rendering != "" {
+		parts = append([]string{rendering}, parts...)
+	}
+	if m.count > 0 {
+		parts = append([]string{fmt.Sprintf("%d", m.count)}, parts...)
+	}
+	return parts
+}
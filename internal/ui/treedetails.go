@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+const (
+	detailSizeWidth = 5
+	detailAgeWidth  = 8
+)
+
+// toggleTreeDetails shows or hides the modification time and size columns.
+func (m *Model) toggleTreeDetails() {
+	if m.treeRoot == nil {
+		return
+	}
+	m.opts.TreeDetails = !m.opts.TreeDetails
+	m.refreshTreeViewWithSelection(m.selectedTreePath())
+	m.resize(m.width, m.height)
+}
+
+func (m *Model) selectedTreePath() string {
+	if entry := m.currentTreeEntry(); entry != nil {
+		return entry.Path
+	}
+	return ""
+}
+
+// entryDetail formats the size and relative modification time of entry,
+// leaving the size blank for directories.
+func entryDetail(entry *tree.Node, now time.Time) string {
+	if entry.ModTime.IsZero() {
+		return ""
+	}
+	size := ""
+	if !entry.IsDir {
+		size = formatSize(entry.Size)
+	}
+	return padLeft(size, detailSizeWidth) + " " + padLeft(relativeTime(entry.ModTime, now), detailAgeWidth)
+}
+
+// cycleTreeSort switches the tree to the next sort mode, keeping the
+// selected entry.
+func (m *Model) cycleTreeSort() {
+	if m.treeRoot == nil {
+		return
+	}
+	m.opts.TreeSort = m.opts.TreeSort.Next()
+	m.treeRoot.Sort(m.opts.TreeSort)
+	m.refreshTreeViewWithSelection(m.selectedTreePath())
+}
+
+// treeSortStatus names the sort mode in the status bar unless it is the
+// default alphabetical order.
+func (m *Model) treeSortStatus() string {
+	if !m.treeShown() {
+		return ""
+	}
+	switch m.opts.TreeSort {
+	case tree.SortModTime:
+		return "並び: 更新日時"
+	case tree.SortSize:
+		return "並び: サイズ"
+	}
+	return ""
+}
+
+// alignTreeDetail right-aligns detail within width, cutting the label short
+// when the two do not fit.
+func alignTreeDetail(label, detail string, width int) string {
+	if detail == "" {
+		return label
+	}
+	room := width - ansi.StringWidth(detail) - 1
+	if room <= 0 {
+		return label + " " + detail
+	}
+	if ansi.StringWidth(label) > room {
+		label = ansi.Truncate(label, room, "…")
+	}
+	return label + strings.Repeat(" ", room-ansi.StringWidth(label)+1) + detail
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value := float64(n)
+	for _, suffix := range []string{"K", "M", "G", "T"} {
+		value /= unit
+		if value < 10 {
+			return fmt.Sprintf("%.1f%s", value, suffix)
+		}
+		if value < unit || suffix == "T" {
+			return fmt.Sprintf("%.0f%s", value, suffix)
+		}
+	}
+	return ""
+}
+
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "たった今"
+	case d < time.Hour:
+		return fmt.Sprintf("%d分前", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d時間前", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%d日前", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dか月前", int(d/(30*24*time.Hour)))
+	}
+	return fmt.Sprintf("%d年前", int(d/(365*24*time.Hour)))
+}
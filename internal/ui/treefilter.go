@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/fuzzy"
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+func newFilterInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "絞り込み: "
+	input.CharLimit = 256
+	input.Placeholder = "パス"
+	input.Blur()
+	return input
+}
+
+// enterFilterMode focuses the tree and opens the filter input, starting from
+// the filter currently applied.
+func (m *Model) enterFilterMode() tea.Cmd {
+	if m.treeRoot == nil {
+		return nil
+	}
+	if !m.treeVisible {
+		m.treeVisible = true
+		m.resize(m.width, m.height)
+	}
+	m.focusTree()
+	m.filterActive = true
+	m.pendingKey = ""
+	m.filterInput.SetValue(m.treeFilter)
+	m.filterInput.CursorEnd()
+	return m.filterInput.Focus()
+}
+
+// handleFilterKey routes keys to the filter input while it is open. The tree
+// is narrowed on every keystroke; Enter keeps the filter and Esc drops it.
+func (m *Model) handleFilterKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filterActive = false
+		m.filterInput.Blur()
+		return nil
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.filterActive = false
+		m.filterInput.Blur()
+		m.setTreeFilter("")
+		return nil
+	}
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.setTreeFilter(m.filterInput.Value())
+	return cmd
+}
+
+// setTreeFilter narrows the tree to the files whose path matches pattern and
+// the directories leading to them, selecting the best match. An empty
+// pattern shows the whole tree again, keeping the selected entry.
+func (m *Model) setTreeFilter(pattern string) {
+	if pattern == m.treeFilter {
+		return
+	}
+	selected := m.selectedTreePath()
+	m.treeFilter = pattern
+	m.filterKeep = nil
+	if pattern == "" {
+		m.refreshTreeViewWithSelection(selected)
+		return
+	}
+
+	m.filterKeep = map[*tree.Node]bool{m.treeRoot: true}
+	var best *tree.Node
+	bestScore := 0
+	m.walkAllFiles(m.treeRoot, func(file *tree.Node) {
+		score, ok := fuzzy.Score(pattern, file.Path)
+		if !ok {
+			return
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = file, score
+		}
+		for n := file; n != nil && !m.filterKeep[n]; n = n.Parent {
+			m.filterKeep[n] = true
+		}
+	})
+	m.treeContentWidth = m.rebuildFlatTree()
+	m.treeSelection = 0
+	if best != nil {
+		if idx := m.indexForPath(best.Path); idx >= 0 {
+			m.treeSelection = idx
+		}
+	}
+	m.updateTreeContent(m.treeContentWidth)
+}
+
+// walkAllFiles calls fn for every file below node, loading directories that
+// have not been opened yet.
+func (m *Model) walkAllFiles(node *tree.Node, fn func(*tree.Node)) {
+	if !node.IsDir {
+		fn(node)
+		return
+	}
+	if !m.loadNode(node) {
+		return
+	}
+	for _, child := range node.Children {
+		m.walkAllFiles(child, fn)
+	}
+}
+
+// filteredOut reports whether the tree filter hides node.
+func (m *Model) filteredOut(node *tree.Node) bool {
+	return m.treeFilter != "" && !m.filterKeep[node]
+}
+
+// treeFilterStatus describes the applied filter for the status bar.
+func (m *Model) treeFilterStatus() string {
+	if m.treeFilter == "" {
+		return ""
+	}
+	files := 0
+	for node := range m.filterKeep {
+		if !node.IsDir {
+			files++
+		}
+	}
+	return fmt.Sprintf("絞り込み: %s (%d件)", m.treeFilter, files)
+}
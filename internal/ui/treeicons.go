@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+// IconStyle selects how tree entries are decorated.
+type IconStyle string
+
+const (
+	// IconsNone keeps the plain +/- indicators in a single colour.
+	IconsNone IconStyle = "none"
+	// IconsASCII colours entries by type but only uses ASCII indicators, for
+	// terminals without a patched font.
+	IconsASCII IconStyle = "ascii"
+	// IconsNerd colours entries and prefixes them with Nerd Font glyphs.
+	IconsNerd IconStyle = "nerd"
+)
+
+// ParseIconStyle validates an icon style name, accepting "" as ascii.
+func ParseIconStyle(name string) (IconStyle, error) {
+	switch style := IconStyle(strings.ToLower(strings.TrimSpace(name))); style {
+	case "":
+		return IconsASCII, nil
+	case IconsNone, IconsASCII, IconsNerd:
+		return style, nil
+	}
+	return "", fmt.Errorf("不明なアイコン形式 %q (none, ascii, nerd のいずれか)", name)
+}
+
+// entryKind groups tree entries that share an icon and colour.
+type entryKind int
+
+const (
+	kindFile entryKind = iota
+	kindDir
+	kindMarkdown
+	kindMDX
+	kindImage
+)
+
+var (
+	treeKindStyles = map[entryKind]lipgloss.Style{
+		kindFile:     treeLineStyle,
+		kindDir:      lipgloss.NewStyle().Foreground(lipgloss.Color("#7aa2f7")).Bold(true),
+		kindMarkdown: lipgloss.NewStyle().Foreground(lipgloss.Color("#a9b1d6")),
+		kindMDX:      lipgloss.NewStyle().Foreground(lipgloss.Color("#e0af68")),
+		kindImage:    lipgloss.NewStyle().Foreground(lipgloss.Color("#bb9af7")),
+	}
+	nerdIcons = map[entryKind]string{
+		kindFile:     "", // nf-fa-file
+		kindMarkdown: "", // nf-dev-markdown
+		kindMDX:      "", // nf-dev-react
+		kindImage:    "", // nf-fa-file_image_o
+	}
+)
+
+const (
+	nerdDirClosed = "" // nf-fa-folder
+	nerdDirOpen   = "" // nf-fa-folder_open
+)
+
+func classifyEntry(entry *tree.Node) entryKind {
+	if entry.IsDir {
+		return kindDir
+	}
+	switch strings.ToLower(path.Ext(entry.Name)) {
+	case ".mdx":
+		return kindMDX
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".bmp":
+		return kindImage
+	}
+	if markdown.IsFile(entry.Name) || markdown.Converted(entry.Name) {
+		return kindMarkdown
+	}
+	return kindFile
+}
+
+// treeEntryStyle returns the colour for an unselected tree row.
+func (m *Model) treeEntryStyle(entry *tree.Node) lipgloss.Style {
+	if m.opts.Icons == IconsNone || entry == nil {
+		return treeLineStyle
+	}
+	return treeKindStyles[classifyEntry(entry)]
+}
+
+// nerdIcon returns the glyph shown before an entry in nerd mode.
+func nerdIcon(entry *tree.Node, open bool) string {
+	if entry.IsDir {
+		if open {
+			return nerdDirOpen
+		}
+		return nerdDirClosed
+	}
+	return nerdIcons[classifyEntry(entry)]
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+// treeLoadingLabel stands in for the children of a directory being read.
+const treeLoadingLabel = "…読み込み中"
+
+// treeLoad is a directory read queued for the background.
+type treeLoad struct {
+	node *tree.Node
+	load func() ([]*tree.Node, error)
+}
+
+// treeLoadedMsg delivers the children read for node.
+type treeLoadedMsg struct {
+	node     *tree.Node
+	children []*tree.Node
+	err      error
+	took     time.Duration
+}
+
+// loadNodeAsync reports whether the children of node are known, queueing a
+// background read when they are not. Reading a directory can mean walking
+// its whole subtree for markdown, which stalls on slow filesystems. Trees
+// known up front are loaded at once.
+func (m *Model) loadNodeAsync(node *tree.Node) bool {
+	if node == nil {
+		return false
+	}
+	if _, indexed := node.Index(); node.Loaded() || indexed || m.opts.Sync {
+		return m.loadNode(node)
+	}
+	if load := node.LoadFunc(); load != nil {
+		m.treeLoads = append(m.treeLoads, treeLoad{node: node, load: load})
+	}
+	return false
+}
+
+// awaitTreeLoads starts the queued directory reads.
+func (m *Model) awaitTreeLoads() tea.Cmd {
+	if len(m.treeLoads) == 0 {
+		return nil
+	}
+	cmds := make([]tea.Cmd, len(m.treeLoads))
+	for i, job := range m.treeLoads {
+		cmds[i] = func() tea.Msg {
+			start := time.Now()
+			children, err := job.load()
+			return treeLoadedMsg{node: job.node, children: children, err: err, took: time.Since(start)}
+		}
+	}
+	m.treeLoads = nil
+	return tea.Batch(cmds...)
+}
+
+// handleTreeLoaded shows the children read for a directory, selecting the
+// entry a reveal was waiting for if any.
+func (m *Model) handleTreeLoaded(msg treeLoadedMsg) {
+	debugf("tree load %q in background: %s", msg.node.Path, msg.took)
+	if err := msg.node.FinishLoad(msg.children, msg.err); err != nil {
+		m.err = err
+		// Closed, the directory is not read again until expanded.
+		msg.node.Open = false
+	} else if !m.loadNode(msg.node) {
+		return
+	}
+	if path := m.pendingReveal; path != "" {
+		m.pendingReveal = ""
+		m.refreshTreeViewWithSelection(path)
+		return
+	}
+	m.refreshTreeAfterChange()
+}
+
+// treeLoadingLine is the placeholder listed below a directory being read.
+func treeLoadingLine(node *tree.Node, depth int) treeLine {
+	return treeLine{entry: node, label: strings.Repeat("  ", depth) + treeLoadingLabel}
+}
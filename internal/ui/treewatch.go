@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/kyaoi/mdview/internal/ignore"
+	"github.com/kyaoi/mdview/internal/markdown"
+	"github.com/kyaoi/mdview/internal/tree"
+)
+
+// treeDirsMsg delivers the directories below the tree root found by the walk
+// startTreeWatch leaves to the background.
+type treeDirsMsg struct {
+	dirs []string
+}
+
+// startTreeWatch watches every directory below the tree root so that the
+// tree follows files being created, removed or renamed. It reports whether
+// watching started; trees built in memory, such as tag filters, are not
+// watched. Without an index the directories are found by walking the vault,
+// which the returned command does so as not to hold up the first frame; the
+// root is watched meanwhile.
+func (m *Model) startTreeWatch() (tea.Cmd, bool) {
+	if m.opts.NoWatch || m.treeRoot == nil || !m.treeRoot.HasLoader() || m.rootDir == "" {
+		return nil, false
+	}
+	if err := m.ensureWatcher(); err != nil {
+		m.err = err
+		return nil, false
+	}
+	m.treeWatchDirs = make(map[string]bool)
+	if index, ok := m.treeRoot.Index(); ok {
+		m.watchDirs(index.Dirs())
+		return nil, true
+	}
+	if m.opts.Sync {
+		m.watchDirs(tree.Dirs(m.rootDir))
+		return nil, true
+	}
+	m.watchDirs([]string{m.rootDir})
+	root := m.rootDir
+	return func() tea.Msg {
+		start := time.Now()
+		defer func() { debugf("tree watch walk: %s", time.Since(start)) }()
+		return treeDirsMsg{dirs: tree.Dirs(root)}
+	}, true
+}
+
+// watchDirs adds watches for dirs.
+func (m *Model) watchDirs(dirs []string) {
+	for _, path := range dirs {
+		if m.treeWatchDirs[path] {
+			continue
+		}
+		if err := m.watcher.Add(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Removed since it was found; the tree hears of it
+				// from the parent.
+				continue
+			}
+			// Typically the inotify watch limit; the rest of the tree
+			// simply stays unwatched.
+			m.err = err
+			return
+		}
+		m.treeWatchDirs[path] = true
+	}
+}
+
+// handleTreeEvent refreshes the directory a filesystem event happened in.
+// Writes only matter when the tree shows or sorts by file metadata.
+func (m *Model) handleTreeEvent(msg fileEventMsg) {
+	path := filepath.Clean(msg.path)
+	dir := filepath.Dir(path)
+	if !m.treeWatchDirs[dir] {
+		return
+	}
+	if markdown.Viewable(path) {
+		// Links may have been added or removed.
+		m.graph, m.backlinks = nil, nil
+	}
+	structural := msg.op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0
+	if structural {
+		// The files grep and completion go through have changed.
+		m.fileIndex = nil
+	}
+	if !structural && !m.treeShowsMetadata() {
+		return
+	}
+	if msg.op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		// The kernel drops the watches of a removed directory itself.
+		for watched := range m.treeWatchDirs {
+			if watched == path || strings.HasPrefix(watched, path+string(filepath.Separator)) {
+				delete(m.treeWatchDirs, watched)
+			}
+		}
+	}
+	if msg.op&fsnotify.Create != 0 {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			m.watchDirs(tree.Dirs(path))
+		}
+	}
+
+	rel, err := filepath.Rel(m.rootDir, path)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+	m.treeRoot.Invalidate(rel)
+	node := m.treeNodeFor(strings.TrimPrefix(filepath.ToSlash(filepath.Dir(rel)), "."))
+	if err := node.Refresh(); err != nil {
+		m.err = err
+		return
+	}
+	m.refreshTreeAfterChange()
+}
+
+func (m *Model) treeShowsMetadata() bool {
+	return m.opts.TreeDetails || m.opts.TreeSort != tree.SortName
+}
+
+// treeNodeFor returns the directory node for rel, or its deepest ancestor in
+// the tree when rel itself is not shown, e.g. because it held no markdown
+// until now. Nothing is loaded along the way.
+func (m *Model) treeNodeFor(rel string) *tree.Node {
+	current := m.treeRoot
+	for current.Path != rel {
+		var next *tree.Node
+		for _, child := range current.Children {
+			if child.IsDir && (child.Path == rel || strings.HasPrefix(rel, child.Path+"/")) {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		current = next
+	}
+	return current
+}
+
+// refreshTreeAfterChange redraws the tree after its nodes changed on disk,
+// keeping the selection and the filter.
+func (m *Model) refreshTreeAfterChange() {
+	if m.treeFilter != "" {
+		pattern := m.treeFilter
+		m.treeFilter = ""
+		m.setTreeFilter(pattern)
+		return
+	}
+	m.refreshTreeViewWithSelection(m.selectedTreePath())
+}
+
+// toggleHidden lists or hides dot directories and dotfiles, reading the tree
+// again.
+func (m *Model) toggleHidden() {
+	if m.treeRoot == nil {
+		return
+	}
+	ignore.SetShowHidden(!ignore.ShowHidden())
+	m.reloadTree()
+}
+
+// toggleAllFiles lists every file in the tree or only markdown documents,
+// reading the tree again.
+func (m *Model) toggleAllFiles() {
+	if m.treeRoot == nil {
+		return
+	}
+	tree.SetAllFiles(!tree.AllFiles())
+	m.reloadTree()
+}
+
+// treeListingStatus notes in the status bar that the tree lists more than
+// the visible markdown documents.
+func (m *Model) treeListingStatus() string {
+	if !m.treeShown() {
+		return ""
+	}
+	var notes []string
+	if tree.AllFiles() {
+		notes = append(notes, "全ファイル表示")
+	}
+	if ignore.ShowHidden() {
+		notes = append(notes, "隠しファイル表示")
+	}
+	return strings.Join(notes, "  ")
+}
+
+// reloadTree reads the whole tree from disk again, for changes the watcher
+// missed or when watching is off, keeping the selection, the open directories
+// and the filter.
+func (m *Model) reloadTree() {
+	if m.treeRoot == nil {
+		return
+	}
+	if !m.treeRoot.HasLoader() {
+		m.err = errors.New("このツリーはディスクから再読み込みできません")
+		return
+	}
+	m.err = nil
+	if err := m.treeRoot.Reload(); err != nil {
+		m.err = err
+		return
+	}
+	m.fileIndex = nil
+	m.graph = nil
+	m.backlinks = nil
+	if m.treeWatchDirs != nil {
+		m.watchDirs(tree.Dirs(m.rootDir))
+	}
+	m.refreshTreeAfterChange()
+}
@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Editors that save by writing a temporary file and renaming it over the
+// original leave the watched name missing for a moment. The watcher then
+// checks for the file every replaceRetryDelay, giving up on a quick
+// replacement after replaceAttempts checks and carrying on every
+// replaceGoneDelay.
+const (
+	replaceRetryDelay = 50 * time.Millisecond
+	replaceAttempts   = 10
+	replaceGoneDelay  = time.Second
+)
+
+// fileReappearMsg checks whether the watched file is back. gen numbers the
+// waits so a newer one, or another file being opened, drops older checks.
+type fileReappearMsg struct {
+	gen     int
+	attempt int
+}
+
+// watchesFile reports whether path names the watched file, or the file its
+// symlink points to.
+func (m *Model) watchesFile(path string) bool {
+	path = filepath.Clean(path)
+	return path == m.watchedFile || m.watchedTarget != "" && path == m.watchedTarget
+}
+
+// armTarget watches the directory of the file the watched symlink points
+// to, since writes through the link happen there. It follows the link again
+// on every call, so a link repointed by the save is picked up.
+func (m *Model) armTarget() {
+	target, err := filepath.EvalSymlinks(m.watchedFile)
+	if err != nil || target == m.watchedFile {
+		target = ""
+	}
+	dir := ""
+	if target != "" {
+		dir = filepath.Dir(target)
+		if dir == m.watchDir {
+			dir = ""
+		}
+	}
+	if dir != m.watchTargetDir {
+		if m.watchTargetDir != "" && !m.treeWatchDirs[m.watchTargetDir] {
+			_ = m.watcher.Remove(m.watchTargetDir)
+		}
+		if dir != "" && m.watcher.Add(dir) != nil {
+			dir = ""
+		}
+		m.watchTargetDir = dir
+	}
+	m.watchedTarget = target
+}
+
+// rearm watches the watched file's directory again. Removing or replacing
+// the directory, as a branch switch can, drops its watch along with it.
+func (m *Model) rearm() {
+	if m.watcher == nil || m.watchedFile == "" {
+		return
+	}
+	_ = m.watcher.Add(m.watchDir)
+	m.armTarget()
+}
+
+// reloadWatched reloads the watched file after op reported it changed. A
+// file removed or renamed away is waited for rather than reported missing,
+// as it is usually replaced a moment later.
+func (m *Model) reloadWatched(op fsnotify.Op) tea.Cmd {
+	if op&(fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+		m.rearm()
+	}
+	if m.rev == "" {
+		if _, err := os.Stat(m.watchedFile); errors.Is(err, fs.ErrNotExist) {
+			m.awaitGen++
+			return m.awaitReplacement(0)
+		}
+	}
+	return m.reloadAndAnnounce()
+}
+
+func (m *Model) awaitReplacement(attempt int) tea.Cmd {
+	delay := replaceRetryDelay
+	if attempt >= replaceAttempts {
+		delay = replaceGoneDelay
+	}
+	gen := m.awaitGen
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return fileReappearMsg{gen: gen, attempt: attempt + 1}
+	})
+}
+
+// handleFileReappear reloads the watched file once it exists again, or
+// reports it gone when no replacement turned up in time.
+func (m *Model) handleFileReappear(msg fileReappearMsg) tea.Cmd {
+	if msg.gen != m.awaitGen || m.watchedFile == "" {
+		return nil
+	}
+	m.rearm()
+	if _, err := os.Stat(m.watchedFile); err != nil {
+		if msg.attempt == replaceAttempts {
+			m.err = fmt.Errorf("%s が見つかりません (削除または移動されました)", filepath.Base(m.watchedFile))
+		}
+		return m.awaitReplacement(msg.attempt)
+	}
+	if !m.autoReload {
+		m.reloadPending = true
+		return nil
+	}
+	m.err = nil
+	return m.reloadAndAnnounce()
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+)
+
+// widthPresets maps the preset keys to wrap widths. Zero means the full pane
+// width.
+var widthPresets = map[string]int{
+	"1": 80,
+	"2": 100,
+	"3": 0,
+}
+
+// applyWidthPreset re-renders the content at the preset width of key, bare
+// or with Alt, centring it in the content area to simulate how the document
+// reads at that width.
+func (m *Model) applyWidthPreset(key string) bool {
+	width, ok := widthPresets[strings.TrimPrefix(key, "alt+")]
+	if !ok {
+		return false
+	}
+	m.setWidthPreset(width)
+	return true
+}
+
+func (m *Model) setWidthPreset(width int) {
+	if width == m.widthPreset {
+		return
+	}
+	m.widthPreset = width
+	ratio := m.scrollRatio()
+	m.resize(m.width, m.height)
+	m.setScrollRatio(ratio)
+}
+
+// presetFromCount applies the width preset of a bare 1, 2 or 3 taken as a
+// count once key, the key after it, shows it does not start one as in 2G
+// or 25%, and reports whether it did. Esc drops the digit instead.
+func (m *Model) presetFromCount(count int, key string) bool {
+	if count < 1 || count > 3 || countMotions[key] || key == "esc" {
+		return false
+	}
+	return m.applyWidthPreset(strconv.Itoa(count))
+}
+
+// presetContentWidth narrows the rendered pane to the active preset.
+func (m *Model) presetContentWidth(available int) int {
+	if m.widthPreset <= 0 || m.zenMode {
+		return available
+	}
+	width := m.widthPreset + m.contentVP.Style.GetHorizontalFrameSize()
+	return clamp(width, minContentWidth, max(available, minContentWidth))
+}
@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/linkgraph"
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// linkGraph returns the link graph of the vault, built on first use from
+// the markdown index and dropped whenever the tree changes.
+func (m *Model) linkGraph() *linkgraph.Graph {
+	if m.graph == nil {
+		m.graph = linkgraph.New(m.vaultRoot(), m.markdownIndex())
+	}
+	return m.graph
+}
+
+// resolveWikiLink finds the document a wikilink in the open document names.
+func (m *Model) resolveWikiLink(target string) (string, bool) {
+	return m.linkGraph().Resolve(path.Dir(m.activeWikiPath()), target)
+}
+
+// markPos is where a mark was in rendered output: its line and the display
+// column of the text following it.
+type markPos struct {
+	line, col int
+}
+
+// takeMarks removes every mark from rendered and reports where each one
+// was, in order.
+func takeMarks(rendered, mark string) (string, []markPos) {
+	if !strings.Contains(rendered, mark) {
+		return rendered, nil
+	}
+	var marks []markPos
+	for i, line := range strings.Split(rendered, "\n") {
+		for offset := 0; ; {
+			n := strings.Index(line[offset:], mark)
+			if n < 0 {
+				break
+			}
+			offset += n
+			marks = append(marks, markPos{line: i, col: ansi.StringWidth(line[:offset])})
+			offset += len(mark)
+		}
+	}
+	return strings.ReplaceAll(rendered, mark, ""), marks
+}
+
+// wikiLinkInView returns the first wikilink shown in the viewport.
+func (m *Model) wikiLinkInView() (markdown.WikiLink, bool) {
+	top := m.contentVP.YOffset
+	for i, pos := range m.wikiLinkPos {
+		if i >= len(m.wikiLinks) || pos.line >= top+m.contentVP.Height {
+			break
+		}
+		if pos.line >= top {
+			return m.wikiLinks[i], true
+		}
+	}
+	return markdown.WikiLink{}, false
+}
+
+// followWikiLink opens the document of the first visible wikilink and
+// scrolls to the heading it names.
+func (m *Model) followWikiLink() tea.Cmd {
+	link, ok := m.wikiLinkInView()
+	if !ok {
+		m.err = errors.New("表示範囲にリンクがありません")
+		return nil
+	}
+	return m.openWikiLink(link)
+}
+
+// openWikiLink opens the document of a wikilink and scrolls to the heading
+// it names.
+func (m *Model) openWikiLink(link markdown.WikiLink) tea.Cmd {
+	var cmd tea.Cmd
+	if link.Target != "" {
+		if link.Path == "" {
+			m.err = errors.New("リンク先のノートが見つかりません: " + link.Target)
+			return nil
+		}
+		var err error
+		if cmd, err = m.editFile(link.Path); err != nil {
+			m.err = err
+			return nil
+		}
+	}
+	if link.Heading != "" {
+		m.whenRendered(func() { m.scrollToWikiHeading(link.Heading) })
+	}
+	return cmd
+}
+
+// scrollToWikiHeading scrolls to the heading a wikilink names.
+func (m *Model) scrollToWikiHeading(heading string) {
+	key := matchKey(heading)
+	for i, h := range m.headings {
+		if matchKey(h.Text) == key {
+			m.recordHeadingVisit(i)
+			m.scrollToHeading(i)
+			return
+		}
+	}
+	m.err = errors.New("見出しが見つかりません: " + heading)
+}
+
+// wikiLinkPreview describes where a wikilink leads for the status bar.
+func (m *Model) wikiLinkPreview(link markdown.WikiLink) string {
+	dest := m.activeAbsPath
+	if link.Target != "" {
+		if link.Path == "" {
+			return "リンク先のノートが見つかりません: " + link.Target
+		}
+		dest = filepath.Join(m.vaultRoot(), filepath.FromSlash(link.Path))
+	}
+	if link.Heading != "" {
+		dest += "#" + link.Heading
+	}
+	return dest
+}
+
+// wikiLinkHintStatus points out the key following the visible wikilink.
+func (m *Model) wikiLinkHintStatus() string {
+	if _, ok := m.wikiLinkInView(); !ok {
+		return ""
+	}
+	return "enter: リンクを開く"
+}
+
+// loadEmbed reads the document an embed names, for markdown.Embeds.
+func (m *Model) loadEmbed(target string) (string, string, bool) {
+	rel, ok := m.resolveWikiLink(target)
+	if !ok {
+		return "", "", false
+	}
+	absPath := filepath.Join(m.vaultRoot(), filepath.FromSlash(rel))
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", "", false
+	}
+	content, err := markdown.Convert(absPath, data)
+	if err != nil {
+		return "", "", false
+	}
+	return rel, content, true
+}
+
+// activeWikiPath returns the open document's path relative to the vault
+// root, the key loadEmbed identifies it by.
+func (m *Model) activeWikiPath() string {
+	rel, err := filepath.Rel(m.vaultRoot(), m.activeAbsPath)
+	if err != nil || m.activeAbsPath == "" {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/kyaoi/mdview/internal/markdown"
+)
+
+// WrapMode selects the line breaking rules for rendered prose.
+type WrapMode string
+
+const (
+	// WrapAuto picks the rules from the script the document is written in.
+	WrapAuto WrapMode = "auto"
+	// WrapCJK breaks lines between any two characters, following the
+	// Japanese rules about punctuation that may not start or end a line.
+	WrapCJK WrapMode = "cjk"
+	// WrapLatin only breaks lines at spaces.
+	WrapLatin WrapMode = "latin"
+)
+
+// ParseWrapMode validates a wrap mode name, accepting "" as auto.
+func ParseWrapMode(name string) (WrapMode, error) {
+	switch mode := WrapMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case "":
+		return WrapAuto, nil
+	case WrapAuto, WrapCJK, WrapLatin:
+		return mode, nil
+	}
+	return "", fmt.Errorf("不明な折り返し方式 %q (auto, cjk, latin のいずれか)", name)
+}
+
+// wrapsAnywhere reports whether src is wrapped with the CJK rules under mode.
+func wrapsAnywhere(mode WrapMode, src string) bool {
+	switch mode {
+	case WrapCJK:
+		return true
+	case WrapLatin:
+		return false
+	}
+	return markdown.DetectScript(src) == markdown.ScriptCJK
+}
+
+const (
+	// noLineStart lists characters that must not begin a line (gyoto kinsoku).
+	noLineStart = "、。，．・：；？！ー）」』】〕〉》”’ぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮヵヶ々,.:;?!)]}"
+	// noLineEnd lists characters that must not end a line (gyomatsu kinsoku).
+	noLineEnd = "（「『【〔〈《“‘([{"
+)
+
+// wrapAnywhere breaks every rendered line wider than width. Glamour only
+// breaks at spaces, which leaves CJK paragraphs as one overlong line.
+// Continuation rows keep the indentation of the line they came from.
+func wrapAnywhere(rendered string, width int) string {
+	if width <= 0 {
+		return rendered
+	}
+	lines := strings.Split(rendered, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		out = append(out, wrapLineAnywhere(line, width)...)
+	}
+	return strings.Join(out, "\n")
+}
+
+func wrapLineAnywhere(line string, width int) []string {
+	plain := ansi.Strip(line)
+	if ansi.StringWidth(plain) <= width {
+		return []string{line}
+	}
+	content := strings.TrimRight(plain, " ")
+	end := ansi.StringWidth(content)
+	if end <= width {
+		// Only the padding overflows.
+		return []string{ansi.Truncate(line, width, "")}
+	}
+
+	runes := []rune(content)
+	cols := make([]int, len(runes)+1)
+	for i, r := range runes {
+		cols[i+1] = cols[i] + ansi.StringWidth(string(r))
+	}
+	indent := len(content) - len(strings.TrimLeft(content, " "))
+	indent = min(indent, width/2)
+	prefix := strings.Repeat(" ", indent)
+
+	var rows []string
+	start, limit := 0, width
+	for start < len(runes) {
+		if cols[len(runes)]-cols[start] <= limit {
+			rows = append(rows, ansi.Cut(line, cols[start], end))
+			break
+		}
+		stop := breakIndex(runes, cols, start, cols[start]+limit)
+		rows = append(rows, ansi.Cut(line, cols[start], cols[stop]))
+		start = stop
+		for start < len(runes) && runes[start] == ' ' {
+			start++
+		}
+		limit = width - indent
+	}
+	for i := 1; i < len(rows); i++ {
+		rows[i] = prefix + rows[i]
+	}
+	return rows
+}
+
+// breakIndex returns the rune index at which to break a row starting at
+// start so that it ends at or before column limit, moving the break earlier
+// when it would split forbidden punctuation from its neighbour.
+func breakIndex(runes []rune, cols []int, start, limit int) int {
+	stop := start
+	for stop < len(runes) && cols[stop+1] <= limit {
+		stop++
+	}
+	if stop == start {
+		// A single character wider than the row still has to go somewhere.
+		return start + 1
+	}
+	for candidate := stop; candidate > start+1; candidate-- {
+		if !strings.ContainsRune(noLineStart, runes[candidate]) &&
+			!strings.ContainsRune(noLineEnd, runes[candidate-1]) {
+			return candidate
+		}
+		if stop-candidate >= 2 {
+			// Give up rather than leave a visibly short row.
+			break
+		}
+	}
+	return stop
+}
@@ -0,0 +1,41 @@
+package ui
+
+import "github.com/charmbracelet/lipgloss"
+
+// defaultZenWidth is the reading width used when no --zen-width is given.
+const defaultZenWidth = 80
+
+var (
+	contentStyle    = lipgloss.NewStyle().Padding(0, 1)
+	zenContentStyle = lipgloss.NewStyle().Padding(1, 4)
+)
+
+// toggleZenMode hides the tree, borders and status chrome and centres the
+// content at the configured reading width.
+func (m *Model) toggleZenMode() {
+	m.zenMode = !m.zenMode
+	if m.zenMode {
+		m.blurTree()
+		m.contentVP.Style = zenContentStyle
+	} else {
+		m.contentVP.Style = contentStyle
+	}
+	ratio := m.scrollRatio()
+	m.resize(m.width, m.height)
+	m.setScrollRatio(ratio)
+}
+
+// treeShown reports whether the tree panel takes part in the layout.
+func (m *Model) treeShown() bool {
+	return m.treeVisible && !m.zenMode
+}
+
+// zenWidth returns the content width (including margins) used in zen mode.
+func (m *Model) zenWidth(available int) int {
+	width := m.opts.ZenWidth
+	if width <= 0 {
+		width = defaultZenWidth
+	}
+	width += m.contentVP.Style.GetHorizontalFrameSize()
+	return clamp(width, minContentWidth, max(available, minContentWidth))
+}